@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -15,16 +17,18 @@ import (
 	"github.com/envm-org/envm/internal/users"
 	authPkg "github.com/envm-org/envm/pkg/auth"
 	"github.com/envm-org/envm/pkg/email"
+	"github.com/envm-org/envm/pkg/encryption"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/httprate"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type application struct {
 	config Config
-	db     *pgx.Conn
+	db     *pgxpool.Pool
+	keys   encryption.KeyProvider
 }
 
 func (app *application) mount() http.Handler {
@@ -42,8 +46,8 @@ func (app *application) mount() http.Handler {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"}, // Adjust as needed
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "If-Match", "If-None-Match", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"ETag", "Link", "X-Omitted-Secrets", "X-Restricted-Variables"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -65,7 +69,7 @@ func (app *application) mount() http.Handler {
 	authorizer := auth.NewAuthorizer(q)
 
 	// Env
-	envService := env.NewService(q)
+	envService := env.NewService(q, app.db, app.keys, emailSender)
 	envHandler := env.NewHandler(envService, authorizer)
 
 	// Project
@@ -73,7 +77,7 @@ func (app *application) mount() http.Handler {
 	projectHandler := project.NewHandler(projectService, authorizer)
 
 	// Org
-	orgService := org.NewService(q, emailSender)
+	orgService := org.NewService(q, emailSender, envService)
 	orgHandler := org.NewHandler(orgService, authorizer)
 
 	// Auth
@@ -116,6 +120,71 @@ func (app *application) mount() http.Handler {
 			r.Put("/", envHandler.UpdateEnv)
 			r.Delete("/", envHandler.DeleteEnv)
 			r.Get("/list", envHandler.ListEnvs)
+			r.Get("/stale", envHandler.ListStaleSecrets)
+			r.Get("/ephemeral", envHandler.ListEphemeralEnvironments)
+
+			r.Route("/{id}/variables", func(r chi.Router) {
+				r.Get("/", envHandler.ListVariables)
+				r.Post("/batch", envHandler.ApplyBatch)
+				r.Get("/{key}", envHandler.GetVariable)
+				r.Put("/{key}", envHandler.SetVariable)
+				r.Delete("/{key}", envHandler.DeleteVariable)
+				r.Get("/{key}/versions", envHandler.ListVariableVersions)
+				r.Post("/{key}/rollback", envHandler.RollbackVariable)
+				r.Post("/{key}/reveal", envHandler.RevealVariable)
+				r.Post("/{key}/regenerate", envHandler.RegenerateVariable)
+				r.Put("/{key}/rotation", envHandler.SetRotation)
+				r.Delete("/{key}/rotation", envHandler.DeleteRotation)
+				r.Put("/{key}/metadata", envHandler.SetMetadata)
+			})
+			r.Get("/{id}/roles", envHandler.ListRoleAccess)
+			r.Put("/{id}/roles/{role}", envHandler.SetRoleAccess)
+			r.Delete("/{id}/roles/{role}", envHandler.ResetRoleAccess)
+			r.Get("/{id}/acls", envHandler.ListACLs)
+			r.Put("/{id}/acls", envHandler.SetACL)
+			r.Delete("/{id}/acls/{aclid}", envHandler.DeleteACL)
+			r.Post("/{id}/rollback", envHandler.RollbackEnvironment)
+			r.Post("/{id}/import", envHandler.ImportVariables)
+			r.Get("/{id}/export", envHandler.ExportVariables)
+			r.Get("/{id}/resolve", envHandler.ResolveVariables)
+			r.Put("/{id}/parent", envHandler.SetParent)
+			r.Post("/{id}/clone", envHandler.CloneEnvironment)
+			r.Get("/{id}/lock", envHandler.GetLock)
+			r.Post("/{id}/lock", envHandler.LockEnvironment)
+			r.Delete("/{id}/lock", envHandler.UnlockEnvironment)
+			r.Get("/{id}/protection", envHandler.GetProtection)
+			r.Put("/{id}/protection", envHandler.SetProtection)
+			r.Delete("/{id}/protection", envHandler.DeleteProtection)
+			r.Get("/{id}/diff", envHandler.DiffEnvironments)
+			r.Post("/{id}/promote", envHandler.PromoteVariables)
+			r.Get("/{id}/compliance", envHandler.CheckCompliance)
+
+			r.Route("/{id}/snapshots", func(r chi.Router) {
+				r.Get("/", envHandler.ListSnapshots)
+				r.Post("/", envHandler.CreateSnapshot)
+				r.Get("/{tag}", envHandler.GetSnapshot)
+				r.Get("/{tag}/diff", envHandler.DiffSnapshot)
+				r.Post("/{tag}/restore", envHandler.RestoreSnapshot)
+			})
+
+			r.Route("/{id}/change-requests", func(r chi.Router) {
+				r.Get("/", envHandler.ListChangeRequests)
+				r.Post("/", envHandler.CreateChangeRequest)
+				r.Get("/{crid}", envHandler.GetChangeRequest)
+				r.Post("/{crid}/approve", envHandler.ApproveChangeRequest)
+				r.Post("/{crid}/reject", envHandler.RejectChangeRequest)
+				r.Post("/{crid}/comments", envHandler.CommentChangeRequest)
+			})
+
+			r.Route("/{id}/databases", func(r chi.Router) {
+				r.Get("/", envHandler.ListConnections)
+				r.Post("/", envHandler.RegisterConnection)
+				r.Get("/{dbid}", envHandler.GetConnection)
+				r.Delete("/{dbid}", envHandler.DeleteConnection)
+				r.Post("/{dbid}/credentials", envHandler.IssueCredentials)
+				r.Get("/{dbid}/leases", envHandler.ListLeases)
+				r.Delete("/{dbid}/leases/{lid}", envHandler.RevokeLease)
+			})
 		})
 
 		r.Route("/project", func(r chi.Router) {
@@ -126,6 +195,9 @@ func (app *application) mount() http.Handler {
 			r.Post("/members", projectHandler.AddMember)
 			r.Delete("/members", projectHandler.RemoveMember)
 			r.Get("/members", projectHandler.ListMembers)
+			r.Get("/schema", projectHandler.GetSchema)
+			r.Put("/schema", projectHandler.SetSchema)
+			r.Delete("/schema", projectHandler.DeleteSchema)
 		})
 
 		r.Route("/org", func(r chi.Router) {
@@ -134,6 +206,8 @@ func (app *application) mount() http.Handler {
 			r.Put("/", orgHandler.UpdateOrg)
 			r.Delete("/", orgHandler.DeleteOrg)
 			r.Get("/list", orgHandler.ListOrgs)
+			r.Get("/search", orgHandler.SearchVariables)
+			r.Get("/hygiene", orgHandler.HygieneReport)
 
 			r.Post("/invite", orgHandler.InviteMember)
 			r.Post("/join", orgHandler.AcceptInvitation)
@@ -143,6 +217,118 @@ func (app *application) mount() http.Handler {
 	return r
 }
 
+// encryptLegacyVariables encrypts variable values that were stored before
+// envelope encryption was introduced.
+func (app *application) encryptLegacyVariables(ctx context.Context) error {
+	migrated, err := env.NewService(repo.New(app.db), app.db, app.keys, email.NewLogSender()).EncryptPlaintextVariables(ctx)
+	if err != nil {
+		return err
+	}
+	if migrated > 0 {
+		slog.Info("encrypted plaintext variables", "count", migrated)
+	}
+	return nil
+}
+
+// startWorkers starts the background workers. They run until ctx is
+// canceled.
+func (app *application) startWorkers(ctx context.Context) {
+	q := repo.New(app.db)
+	emailSender := email.NewLogSender()
+	envService := env.NewService(q, app.db, app.keys, emailSender)
+	orgService := org.NewService(q, emailSender, envService)
+
+	go app.notifyExpiringSecrets(ctx, envService)
+	go app.reapEphemeralEnvironments(ctx, envService)
+	go app.reapCredentialLeases(ctx, envService)
+	go app.sendHygieneDigests(ctx, orgService)
+}
+
+// notifyExpiringSecrets periodically emails project admins about secrets that
+// are due for rotation within the configured notice period.
+func (app *application) notifyExpiringSecrets(ctx context.Context, envService env.Service) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		notified, err := envService.NotifyExpiringSecrets(ctx, app.config.RotationNotice)
+		if err != nil {
+			slog.Error("failed to notify about expiring secrets", "error", err)
+		}
+		if notified > 0 {
+			slog.Info("notified about expiring secrets", "count", notified)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reapEphemeralEnvironments periodically deletes ephemeral environments whose
+// TTL has passed.
+func (app *application) reapEphemeralEnvironments(ctx context.Context, envService env.Service) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		reaped, err := envService.ReapEphemeralEnvironments(ctx)
+		if err != nil {
+			slog.Error("failed to reap ephemeral environments", "error", err)
+		}
+		if reaped > 0 {
+			slog.Info("reaped ephemeral environments", "count", reaped)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reapCredentialLeases periodically drops the database roles of expired
+// leases.
+func (app *application) reapCredentialLeases(ctx context.Context, envService env.Service) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		reaped, err := envService.ReapCredentialLeases(ctx)
+		if err != nil {
+			slog.Error("failed to reap credential leases", "error", err)
+		}
+		if reaped > 0 {
+			slog.Info("revoked expired credential leases", "count", reaped)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendHygieneDigests periodically emails organization owners and admins the
+// findings of their secret hygiene report. The first digest is sent one
+// interval after startup so that restarts do not resend it.
+func (app *application) sendHygieneDigests(ctx context.Context, orgService org.Service) {
+	ticker := time.NewTicker(app.config.HygieneDigest)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		notified, err := orgService.SendHygieneDigests(ctx, env.HygienePolicy{MaxAge: app.config.SecretMaxAge})
+		if err != nil {
+			slog.Error("failed to send hygiene digests", "error", err)
+		}
+		if notified > 0 {
+			slog.Info("sent hygiene digests", "count", notified)
+		}
+	}
+}
+
 func (app *application) run(h http.Handler) error {
 	server := &http.Server{
 		Addr:         app.config.Addr,
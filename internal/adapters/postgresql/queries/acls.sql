@@ -0,0 +1,18 @@
+-- name: ListVariableACLs :many
+SELECT * FROM variable_acls
+WHERE environment_id = $1
+ORDER BY pattern;
+
+-- name: UpsertVariableACL :one
+INSERT INTO variable_acls (environment_id, pattern, readers, writers, created_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (environment_id, pattern) DO UPDATE
+SET readers = EXCLUDED.readers,
+    writers = EXCLUDED.writers,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteVariableACL :one
+DELETE FROM variable_acls
+WHERE id = $1 AND environment_id = $2
+RETURNING *;
@@ -0,0 +1,69 @@
+-- name: GetEnvironmentProtection :one
+SELECT * FROM environment_protections
+WHERE environment_id = $1 LIMIT 1;
+
+-- name: UpsertEnvironmentProtection :one
+INSERT INTO environment_protections (environment_id, required_approvals)
+VALUES ($1, $2)
+ON CONFLICT (environment_id) DO UPDATE
+SET required_approvals = EXCLUDED.required_approvals, updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteEnvironmentProtection :exec
+DELETE FROM environment_protections
+WHERE environment_id = $1;
+
+-- name: CreateChangeRequest :one
+INSERT INTO change_requests (environment_id, author_id, message, required_approvals)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetChangeRequest :one
+SELECT * FROM change_requests
+WHERE id = $1 LIMIT 1;
+
+-- name: LockChangeRequest :one
+SELECT * FROM change_requests
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
+-- name: ListChangeRequests :many
+SELECT * FROM change_requests
+WHERE environment_id = sqlc.arg(environment_id)
+  AND (sqlc.narg(status)::varchar IS NULL OR status = sqlc.narg(status))
+ORDER BY created_at DESC;
+
+-- name: CloseChangeRequest :execrows
+UPDATE change_requests
+SET status = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'pending';
+
+-- name: CreateChangeRequestVariable :exec
+INSERT INTO change_request_variables (change_request_id, key, action, value, is_secret, encrypted, generator, base)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListChangeRequestVariables :many
+SELECT * FROM change_request_variables
+WHERE change_request_id = $1
+ORDER BY key;
+
+-- name: UpsertChangeRequestReview :exec
+INSERT INTO change_request_reviews (change_request_id, user_id, decision)
+VALUES ($1, $2, $3)
+ON CONFLICT (change_request_id, user_id) DO UPDATE
+SET decision = EXCLUDED.decision, created_at = CURRENT_TIMESTAMP;
+
+-- name: ListChangeRequestReviews :many
+SELECT * FROM change_request_reviews
+WHERE change_request_id = $1
+ORDER BY created_at;
+
+-- name: CreateChangeRequestComment :one
+INSERT INTO change_request_comments (change_request_id, author_id, body)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ListChangeRequestComments :many
+SELECT * FROM change_request_comments
+WHERE change_request_id = $1
+ORDER BY created_at;
@@ -0,0 +1,48 @@
+-- name: CreateDatabaseConnection :one
+INSERT INTO database_connections (environment_id, name, dsn, creation, revocation, default_ttl_seconds, max_ttl_seconds, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetDatabaseConnection :one
+SELECT * FROM database_connections
+WHERE id = $1 AND environment_id = $2 LIMIT 1;
+
+-- name: ListDatabaseConnections :many
+SELECT * FROM database_connections
+WHERE environment_id = $1
+ORDER BY name;
+
+-- name: DeleteDatabaseConnection :exec
+DELETE FROM database_connections
+WHERE id = $1;
+
+-- name: CreateCredentialLease :one
+INSERT INTO credential_leases (connection_id, username, expires_at, created_by)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetCredentialLease :one
+SELECT * FROM credential_leases
+WHERE id = $1 AND connection_id = $2 LIMIT 1;
+
+-- name: ListCredentialLeases :many
+SELECT * FROM credential_leases
+WHERE connection_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListActiveCredentialLeases :many
+SELECT * FROM credential_leases
+WHERE connection_id = $1 AND revoked_at IS NULL
+ORDER BY created_at;
+
+-- name: ListExpiredCredentialLeases :many
+SELECT l.*, c.environment_id
+FROM credential_leases l
+JOIN database_connections c ON c.id = l.connection_id
+WHERE l.revoked_at IS NULL AND l.expires_at <= $1
+ORDER BY l.expires_at;
+
+-- name: RevokeCredentialLease :exec
+UPDATE credential_leases
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1;
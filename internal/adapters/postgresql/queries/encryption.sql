@@ -0,0 +1,19 @@
+-- name: GetEnvironmentKey :one
+SELECT * FROM environment_keys
+WHERE environment_id = $1 LIMIT 1;
+
+-- name: CreateEnvironmentKey :one
+INSERT INTO environment_keys (environment_id, wrapped_key, master_key_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (environment_id) DO NOTHING
+RETURNING *;
+
+-- name: ListPlaintextVariables :many
+SELECT * FROM variables
+WHERE encrypted = FALSE
+ORDER BY environment_id, key;
+
+-- name: EncryptVariableValue :exec
+UPDATE variables
+SET value = $2, encrypted = TRUE
+WHERE id = $1 AND encrypted = FALSE;
@@ -0,0 +1,18 @@
+-- name: CreateEphemeralEnvironment :one
+INSERT INTO ephemeral_environments (environment_id, source_id, expires_at, created_by)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListEphemeralEnvironments :many
+SELECT e.*, x.source_id, x.expires_at, x.created_by
+FROM ephemeral_environments x
+JOIN environments e ON e.id = x.environment_id
+WHERE e.project_id = $1 AND x.expires_at > $2
+ORDER BY x.expires_at;
+
+-- name: ListExpiredEphemeralEnvironments :many
+SELECT e.*
+FROM ephemeral_environments x
+JOIN environments e ON e.id = x.environment_id
+WHERE x.expires_at <= $1
+ORDER BY x.expires_at;
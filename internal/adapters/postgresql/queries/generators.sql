@@ -0,0 +1,14 @@
+-- name: GetVariableGenerator :one
+SELECT * FROM variable_generators
+WHERE variable_id = $1 LIMIT 1;
+
+-- name: UpsertVariableGenerator :one
+INSERT INTO variable_generators (variable_id, spec)
+VALUES ($1, $2)
+ON CONFLICT (variable_id) DO UPDATE
+SET spec = EXCLUDED.spec, updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteVariableGenerator :exec
+DELETE FROM variable_generators
+WHERE variable_id = $1;
@@ -0,0 +1,24 @@
+-- name: SetEnvironmentParent :one
+UPDATE environments
+SET parent_id = $2, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: LockEnvironmentTree :exec
+-- Serializes changes to the inheritance tree of a project until the end of
+-- the transaction.
+SELECT pg_advisory_xact_lock(hashtextextended(@project_id::uuid::text, 0));
+
+-- name: CreateVariableTombstone :exec
+INSERT INTO variable_tombstones (environment_id, key)
+VALUES ($1, $2)
+ON CONFLICT (environment_id, key) DO NOTHING;
+
+-- name: DeleteVariableTombstone :execrows
+DELETE FROM variable_tombstones
+WHERE environment_id = $1 AND key = $2;
+
+-- name: ListVariableTombstones :many
+SELECT * FROM variable_tombstones
+WHERE environment_id = $1
+ORDER BY key;
@@ -0,0 +1,29 @@
+-- name: GetEnvironmentLock :one
+SELECT * FROM environment_locks
+WHERE environment_id = $1 LIMIT 1;
+
+-- name: UpsertEnvironmentLock :one
+INSERT INTO environment_locks (environment_id, locked_by, reason, locked_until)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (environment_id) DO UPDATE
+SET locked_by = EXCLUDED.locked_by, reason = EXCLUDED.reason,
+    locked_until = EXCLUDED.locked_until, created_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteEnvironmentLock :exec
+DELETE FROM environment_locks
+WHERE environment_id = $1;
+
+-- name: ShareEnvironmentLock :exec
+-- Keeps the environment from being locked or unlocked until the end of the
+-- transaction, so that a write can rely on GetEnvironmentLock.
+SELECT id FROM environments
+WHERE id = $1
+FOR KEY SHARE;
+
+-- name: ClaimEnvironmentLock :exec
+-- Waits for the writes that checked the lock of the environment and holds
+-- off new ones until the end of the transaction.
+SELECT id FROM environments
+WHERE id = $1
+FOR UPDATE;
@@ -0,0 +1,22 @@
+-- name: GetVariableMetadata :one
+SELECT * FROM variable_metadata
+WHERE variable_id = $1 LIMIT 1;
+
+-- name: ListVariableMetadata :many
+SELECT * FROM variable_metadata
+WHERE variable_id = ANY(@variable_ids::uuid[]);
+
+-- name: UpsertVariableMetadata :one
+INSERT INTO variable_metadata (variable_id, description, tags, owner, links)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (variable_id) DO UPDATE
+SET description = EXCLUDED.description,
+    tags = EXCLUDED.tags,
+    owner = EXCLUDED.owner,
+    links = EXCLUDED.links,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteVariableMetadata :exec
+DELETE FROM variable_metadata
+WHERE variable_id = $1;
@@ -94,8 +94,8 @@ ORDER BY name;
 
 -- name: UpdateEnvironment :one
 UPDATE environments
-SET name = $2, slug = $3, updated_at = CURRENT_TIMESTAMP
-WHERE id = $1
+SET name = @name, slug = @slug, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = @id AND (sqlc.narg('version')::int IS NULL OR version = sqlc.narg('version'))
 RETURNING *;
 
 -- name: DeleteEnvironment :exec
@@ -103,16 +103,36 @@ DELETE FROM environments
 WHERE id = $1;
 
 -- name: CreateVariable :one
-INSERT INTO variables (environment_id, key, value, is_secret)
-VALUES ($1, $2, $3, $4)
+INSERT INTO variables (environment_id, key, value, is_secret, encrypted)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
+-- name: GetVariable :one
+SELECT * FROM variables
+WHERE environment_id = $1 AND key = $2 LIMIT 1;
+
+-- name: LockVariable :one
+SELECT * FROM variables
+WHERE environment_id = $1 AND key = $2 LIMIT 1
+FOR UPDATE;
+
+-- name: LockVariables :many
+SELECT * FROM variables
+WHERE environment_id = $1
+ORDER BY key
+FOR UPDATE;
+
 -- name: UpdateVariable :one
 UPDATE variables
-SET value = $3, is_secret = $4, updated_at = CURRENT_TIMESTAMP
+SET value = $3, is_secret = $4, encrypted = $5, version = version + 1, updated_at = CURRENT_TIMESTAMP
 WHERE environment_id = $1 AND key = $2
 RETURNING *;
 
+-- name: TouchVariable :exec
+UPDATE variables
+SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
 -- name: DeleteVariable :exec
 DELETE FROM variables
 WHERE environment_id = $1 AND key = $2;
@@ -0,0 +1,7 @@
+-- name: GetEnvironmentBySlug :one
+SELECT * FROM environments
+WHERE project_id = $1 AND slug = $2 LIMIT 1;
+
+-- name: GetProjectBySlug :one
+SELECT * FROM projects
+WHERE organization_id = $1 AND slug = $2 LIMIT 1;
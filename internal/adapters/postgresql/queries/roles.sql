@@ -0,0 +1,21 @@
+-- name: GetEnvironmentRoleAccess :one
+SELECT * FROM environment_role_access
+WHERE environment_id = $1 AND role = $2 LIMIT 1;
+
+-- name: ListEnvironmentRoleAccess :many
+SELECT * FROM environment_role_access
+WHERE environment_id = $1
+ORDER BY role;
+
+-- name: UpsertEnvironmentRoleAccess :one
+INSERT INTO environment_role_access (environment_id, role, access, updated_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (environment_id, role) DO UPDATE
+SET access = EXCLUDED.access,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteEnvironmentRoleAccess :exec
+DELETE FROM environment_role_access
+WHERE environment_id = $1 AND role = $2;
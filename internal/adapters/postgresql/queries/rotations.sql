@@ -0,0 +1,81 @@
+-- name: GetVariableRotation :one
+SELECT * FROM variable_rotations
+WHERE variable_id = $1 LIMIT 1;
+
+-- name: UpsertVariableRotation :one
+INSERT INTO variable_rotations (variable_id, interval_seconds, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (variable_id) DO UPDATE
+SET interval_seconds = EXCLUDED.interval_seconds, expires_at = EXCLUDED.expires_at,
+    notified_at = NULL, updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteVariableRotation :exec
+DELETE FROM variable_rotations
+WHERE variable_id = $1;
+
+-- name: MarkVariableRotated :exec
+UPDATE variable_rotations
+SET rotated_at = CURRENT_TIMESTAMP, notified_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE variable_id = $1;
+
+-- name: MarkRotationNotified :exec
+UPDATE variable_rotations
+SET notified_at = CURRENT_TIMESTAMP
+WHERE variable_id = $1;
+
+-- name: ListStaleRotations :many
+SELECT r.variable_id, v.key, e.id AS environment_id, e.slug AS environment_slug,
+       r.interval_seconds, r.expires_at, r.rotated_at,
+       LEAST(r.expires_at, r.rotated_at + make_interval(secs => r.interval_seconds))::timestamptz AS due_at,
+       EXISTS (SELECT 1 FROM variable_generators g WHERE g.variable_id = r.variable_id) AS generated
+FROM variable_rotations r
+JOIN variables v ON v.id = r.variable_id
+JOIN environments e ON e.id = v.environment_id
+WHERE e.project_id = @project_id
+  AND LEAST(r.expires_at, r.rotated_at + make_interval(secs => r.interval_seconds)) <= @due_before::timestamptz
+ORDER BY due_at, e.slug, v.key;
+
+-- name: ListUnnotifiedRotations :many
+SELECT r.variable_id, v.key, e.slug AS environment_slug, e.project_id, r.updated_at,
+       LEAST(r.expires_at, r.rotated_at + make_interval(secs => r.interval_seconds))::timestamptz AS due_at
+FROM variable_rotations r
+JOIN variables v ON v.id = r.variable_id
+JOIN environments e ON e.id = v.environment_id
+WHERE r.notified_at IS NULL
+  AND LEAST(r.expires_at, r.rotated_at + make_interval(secs => r.interval_seconds)) <= @due_before::timestamptz
+ORDER BY e.project_id, due_at, e.slug, v.key;
+
+-- name: ListRotationNotices :many
+-- Returns who has been told about the current policy and rotation of the
+-- variables. Notices of an earlier cycle are ignored.
+SELECT n.* FROM rotation_notices n
+JOIN variable_rotations r ON r.variable_id = n.variable_id AND r.updated_at = n.cycle
+WHERE n.variable_id = ANY(@variable_ids::uuid[]);
+
+-- name: RecordRotationNotices :exec
+INSERT INTO rotation_notices (variable_id, email, cycle)
+SELECT unnest(@variable_ids::uuid[]), @email::varchar, unnest(@cycles::timestamptz[])
+ON CONFLICT (variable_id, email) DO UPDATE
+SET cycle = EXCLUDED.cycle;
+
+-- name: ListProjectAdminEmails :many
+SELECT u.email FROM users u
+JOIN project_members pm ON pm.user_id = u.id
+WHERE pm.project_id = $1 AND pm.role = 'admin'
+UNION
+SELECT u.email FROM users u
+JOIN organization_members om ON om.user_id = u.id
+JOIN projects p ON p.organization_id = om.organization_id
+WHERE p.id = $1 AND om.role IN ('owner', 'admin')
+ORDER BY email;
+
+-- name: ListOrganizationAdminEmails :many
+SELECT u.email FROM users u
+JOIN organization_members om ON om.user_id = u.id
+WHERE om.organization_id = $1 AND om.role IN ('owner', 'admin')
+ORDER BY u.email;
+
+-- name: ListVariableRotations :many
+SELECT * FROM variable_rotations
+WHERE variable_id = ANY(@variable_ids::uuid[]);
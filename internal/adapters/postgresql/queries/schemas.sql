@@ -0,0 +1,14 @@
+-- name: GetProjectSchema :one
+SELECT * FROM project_schemas
+WHERE project_id = $1 LIMIT 1;
+
+-- name: UpsertProjectSchema :one
+INSERT INTO project_schemas (project_id, definition)
+VALUES ($1, $2)
+ON CONFLICT (project_id) DO UPDATE
+SET definition = EXCLUDED.definition, updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteProjectSchema :exec
+DELETE FROM project_schemas
+WHERE project_id = $1;
@@ -0,0 +1,14 @@
+-- name: SearchVariables :many
+SELECT v.id, v.key, v.is_secret, v.updated_at,
+       e.project_id, e.id AS environment_id, e.slug AS environment_slug,
+       m.description, m.tags, m.owner
+FROM variables v
+JOIN environments e ON e.id = v.environment_id
+LEFT JOIN variable_metadata m ON m.variable_id = v.id
+WHERE e.id = ANY(@environment_ids::uuid[])
+  AND (v.key ILIKE @pattern
+       OR m.description ILIKE @pattern
+       OR m.owner ILIKE @pattern
+       OR EXISTS (SELECT 1 FROM unnest(m.tags) AS t(tag) WHERE t.tag ILIKE @pattern))
+ORDER BY v.key, e.project_id, e.slug
+LIMIT @max_results;
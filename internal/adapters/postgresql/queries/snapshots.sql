@@ -0,0 +1,22 @@
+-- name: CreateSnapshot :one
+INSERT INTO environment_snapshots (environment_id, tag, message, author_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: CreateSnapshotVariable :exec
+INSERT INTO snapshot_variables (snapshot_id, key, value, is_secret, encrypted)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetSnapshotByTag :one
+SELECT * FROM environment_snapshots
+WHERE environment_id = $1 AND tag = $2 LIMIT 1;
+
+-- name: ListSnapshots :many
+SELECT * FROM environment_snapshots
+WHERE environment_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListSnapshotVariables :many
+SELECT * FROM snapshot_variables
+WHERE snapshot_id = $1
+ORDER BY key;
@@ -0,0 +1,40 @@
+-- name: CreateVariableVersion :one
+INSERT INTO variable_versions (environment_id, key, version, action, value, is_secret, encrypted, author_id, message)
+VALUES (
+    $1, $2,
+    (SELECT COALESCE(MAX(version), 0) + 1 FROM variable_versions vv WHERE vv.environment_id = $1 AND vv.key = $2),
+    $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: GetVariableVersion :one
+SELECT * FROM variable_versions
+WHERE environment_id = $1 AND key = $2 AND version = $3 LIMIT 1;
+
+-- name: ListVariableVersions :many
+SELECT * FROM variable_versions
+WHERE environment_id = $1 AND key = $2
+ORDER BY version DESC;
+
+-- name: ListVariableVersionsAt :many
+SELECT DISTINCT ON (key) * FROM variable_versions
+WHERE environment_id = $1 AND created_at <= $2
+ORDER BY key, version DESC;
+
+-- name: ListValueChangeTimes :many
+-- Returns when each key of the environment was last written, which unlike
+-- variables.updated_at is not changed by edits of metadata or policies.
+SELECT key, MAX(created_at)::timestamptz AS changed_at
+FROM variable_versions
+WHERE environment_id = $1 AND action <> 'delete'
+GROUP BY key;
+
+-- name: ListPlaintextVariableVersions :many
+SELECT * FROM variable_versions
+WHERE encrypted = FALSE AND action <> 'delete'
+ORDER BY environment_id, key, version;
+
+-- name: EncryptVariableVersionValue :exec
+UPDATE variable_versions
+SET value = $2, encrypted = TRUE
+WHERE id = $1 AND encrypted = FALSE;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Existing rows keep encrypted = FALSE and are encrypted by the server on startup.
+ALTER TABLE variables ADD COLUMN encrypted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE environment_keys (
+    environment_id UUID PRIMARY KEY REFERENCES environments(id) ON DELETE CASCADE,
+    wrapped_key BYTEA NOT NULL,
+    master_key_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+-- Encrypted values can only be decrypted by the server, so there is no way back
+-- once a value has been encrypted.
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM variables WHERE encrypted) THEN
+        RAISE EXCEPTION 'variables are encrypted: this migration cannot be reverted without losing their values';
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+DROP TABLE IF EXISTS environment_keys;
+ALTER TABLE variables DROP COLUMN IF EXISTS encrypted;
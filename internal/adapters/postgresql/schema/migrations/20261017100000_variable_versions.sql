@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE variable_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL,
+    action VARCHAR(50) NOT NULL, -- create, update, delete, rollback
+    value TEXT NOT NULL,
+    is_secret BOOLEAN NOT NULL DEFAULT FALSE,
+    encrypted BOOLEAN NOT NULL DEFAULT FALSE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(environment_id, key, version)
+);
+
+CREATE INDEX idx_variable_versions_environment_id_created_at ON variable_versions(environment_id, created_at);
+
+-- Seed the history with the current state so that point-in-time reads work
+-- for keys that have not changed since.
+INSERT INTO variable_versions (environment_id, key, version, action, value, is_secret, encrypted, created_at)
+SELECT environment_id, key, 1, 'create', value, COALESCE(is_secret, FALSE), encrypted, COALESCE(updated_at, CURRENT_TIMESTAMP)
+FROM variables;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_variable_versions_environment_id_created_at;
+DROP TABLE IF EXISTS variable_versions;
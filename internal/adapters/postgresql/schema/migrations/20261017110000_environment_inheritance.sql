@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE environments ADD COLUMN parent_id UUID REFERENCES environments(id) ON DELETE SET NULL;
+ALTER TABLE environments ADD CONSTRAINT environments_parent_not_self CHECK (parent_id <> id);
+
+-- A tombstone hides a key inherited from a parent environment.
+CREATE TABLE variable_tombstones (
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (environment_id, key)
+);
+
+CREATE INDEX idx_environments_parent_id ON environments(parent_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_environments_parent_id;
+DROP TABLE IF EXISTS variable_tombstones;
+ALTER TABLE environments DROP CONSTRAINT IF EXISTS environments_parent_not_self;
+ALTER TABLE environments DROP COLUMN IF EXISTS parent_id;
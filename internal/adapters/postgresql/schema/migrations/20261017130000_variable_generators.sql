@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE variable_generators (
+    variable_id UUID PRIMARY KEY REFERENCES variables(id) ON DELETE CASCADE,
+    spec JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS variable_generators;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE variable_rotations (
+    variable_id UUID PRIMARY KEY REFERENCES variables(id) ON DELETE CASCADE,
+    interval_seconds INTEGER,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    rotated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    notified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT variable_rotations_policy CHECK (interval_seconds > 0 OR expires_at IS NOT NULL)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS variable_rotations;
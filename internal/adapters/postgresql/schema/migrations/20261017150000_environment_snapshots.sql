@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE environment_snapshots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    tag VARCHAR(255) NOT NULL,
+    message TEXT,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(environment_id, tag)
+);
+
+CREATE TABLE snapshot_variables (
+    snapshot_id UUID NOT NULL REFERENCES environment_snapshots(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    value TEXT NOT NULL,
+    is_secret BOOLEAN NOT NULL DEFAULT FALSE,
+    encrypted BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (snapshot_id, key)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS snapshot_variables;
+DROP TABLE IF EXISTS environment_snapshots;
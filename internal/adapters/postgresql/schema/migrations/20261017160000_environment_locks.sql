@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE environment_locks (
+    environment_id UUID PRIMARY KEY REFERENCES environments(id) ON DELETE CASCADE,
+    locked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS environment_locks;
@@ -0,0 +1,56 @@
+-- +goose Up
+CREATE TABLE environment_protections (
+    environment_id UUID PRIMARY KEY REFERENCES environments(id) ON DELETE CASCADE,
+    required_approvals INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT environment_protections_approvals CHECK (required_approvals > 0)
+);
+
+CREATE TABLE change_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    message TEXT,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending', -- pending, applied, rejected
+    required_approvals INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE change_request_variables (
+    change_request_id UUID NOT NULL REFERENCES change_requests(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    action VARCHAR(50) NOT NULL, -- set, delete, inherit
+    value TEXT NOT NULL DEFAULT '',
+    is_secret BOOLEAN NOT NULL DEFAULT FALSE,
+    encrypted BOOLEAN NOT NULL DEFAULT FALSE,
+    generator JSONB,
+    PRIMARY KEY (change_request_id, key)
+);
+
+CREATE TABLE change_request_reviews (
+    change_request_id UUID NOT NULL REFERENCES change_requests(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    decision VARCHAR(50) NOT NULL, -- approve, reject
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (change_request_id, user_id)
+);
+
+CREATE TABLE change_request_comments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    change_request_id UUID NOT NULL REFERENCES change_requests(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_change_requests_environment_id_status ON change_requests(environment_id, status);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_change_requests_environment_id_status;
+DROP TABLE IF EXISTS change_request_comments;
+DROP TABLE IF EXISTS change_request_reviews;
+DROP TABLE IF EXISTS change_request_variables;
+DROP TABLE IF EXISTS change_requests;
+DROP TABLE IF EXISTS environment_protections;
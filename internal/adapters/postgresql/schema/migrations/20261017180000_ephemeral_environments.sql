@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE ephemeral_environments (
+    environment_id UUID PRIMARY KEY REFERENCES environments(id) ON DELETE CASCADE,
+    source_id UUID REFERENCES environments(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_ephemeral_environments_expires_at ON ephemeral_environments(expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_ephemeral_environments_expires_at;
+DROP TABLE IF EXISTS ephemeral_environments;
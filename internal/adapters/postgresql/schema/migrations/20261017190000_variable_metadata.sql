@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE variable_metadata (
+    variable_id UUID PRIMARY KEY REFERENCES variables(id) ON DELETE CASCADE,
+    description TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    owner VARCHAR(255) NOT NULL DEFAULT '',
+    links TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_variable_metadata_tags ON variable_metadata USING GIN (tags);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_variable_metadata_tags;
+DROP TABLE IF EXISTS variable_metadata;
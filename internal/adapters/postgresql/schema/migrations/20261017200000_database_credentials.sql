@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE database_connections (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE RESTRICT,
+    name VARCHAR(255) NOT NULL,
+    dsn TEXT NOT NULL, -- sealed with the data key of the environment
+    creation TEXT NOT NULL,
+    revocation TEXT NOT NULL,
+    default_ttl_seconds INTEGER NOT NULL,
+    max_ttl_seconds INTEGER NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(environment_id, name),
+    CONSTRAINT database_connections_ttl CHECK (default_ttl_seconds > 0 AND default_ttl_seconds <= max_ttl_seconds)
+);
+
+CREATE TABLE credential_leases (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    connection_id UUID NOT NULL REFERENCES database_connections(id) ON DELETE CASCADE,
+    username VARCHAR(63) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_credential_leases_expires_at ON credential_leases(expires_at) WHERE revoked_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_credential_leases_expires_at;
+DROP TABLE IF EXISTS credential_leases;
+DROP TABLE IF EXISTS database_connections;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE variable_acls (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    pattern VARCHAR(255) NOT NULL, -- key or glob, such as STRIPE_*
+    readers TEXT[] NOT NULL DEFAULT '{}', -- user:<id> or role:<name>
+    writers TEXT[] NOT NULL DEFAULT '{}',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(environment_id, pattern)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS variable_acls;
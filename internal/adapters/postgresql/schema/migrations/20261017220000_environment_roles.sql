@@ -0,0 +1,21 @@
+-- +goose Up
+-- Project members used to be plain members with access to every environment.
+UPDATE project_members SET role = 'developer' WHERE role = 'member';
+ALTER TABLE project_members ALTER COLUMN role SET DEFAULT 'developer';
+
+-- Overrides the default access of a project role to one environment.
+CREATE TABLE environment_role_access (
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL,
+    access VARCHAR(10) NOT NULL, -- none, read, write
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (environment_id, role),
+    CONSTRAINT environment_role_access_level CHECK (access IN ('none', 'read', 'write'))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS environment_role_access;
+ALTER TABLE project_members ALTER COLUMN role SET DEFAULT 'member';
+UPDATE project_members SET role = 'member' WHERE role IN ('viewer', 'developer', 'maintainer');
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Counts the changes to a row so that clients can detect concurrent edits.
+ALTER TABLE environments ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE variables ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE variables DROP COLUMN IF EXISTS version;
+ALTER TABLE environments DROP COLUMN IF EXISTS version;
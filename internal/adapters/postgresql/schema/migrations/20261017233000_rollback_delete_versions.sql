@@ -0,0 +1,11 @@
+-- +goose Up
+-- Deletes made by a rollback or a snapshot restore were recorded with the
+-- rollback action. Written values are always encrypted, so the rows without a
+-- value are the deletes.
+UPDATE variable_versions
+SET action = 'delete'
+WHERE action = 'rollback' AND encrypted = FALSE AND value = '';
+
+-- +goose Down
+-- The repaired rows cannot be told apart from other deletes.
+SELECT 1;
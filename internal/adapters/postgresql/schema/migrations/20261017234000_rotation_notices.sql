@@ -0,0 +1,13 @@
+-- +goose Up
+-- Records which admins have been told that a variable is due for rotation.
+-- cycle is the updated_at of the policy at the time, so rotating the value or
+-- changing the policy starts over.
+CREATE TABLE rotation_notices (
+    variable_id UUID NOT NULL REFERENCES variable_rotations(variable_id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    cycle TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (variable_id, email)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS rotation_notices;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- The entity tag of the local variable each change was proposed against, so
+-- that a change request fails instead of overwriting a newer value. Requests
+-- created before are not checked.
+ALTER TABLE change_request_variables ADD COLUMN base TEXT;
+
+-- +goose Down
+ALTER TABLE change_request_variables DROP COLUMN IF EXISTS base;
@@ -53,18 +53,47 @@ CREATE TABLE projects (
 CREATE TABLE project_members (
     project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-    role VARCHAR(50) NOT NULL DEFAULT 'member',
+    role VARCHAR(50) NOT NULL DEFAULT 'developer', -- viewer, developer, maintainer, admin
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (project_id, user_id)
 );
+
+CREATE TABLE project_schemas (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    definition JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
 CREATE TABLE environments (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
     name VARCHAR(255) NOT NULL, -- dev, staging, prod
     slug VARCHAR(255) NOT NULL,
+    parent_id UUID REFERENCES environments(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1,
+    UNIQUE(project_id, slug),
+    CONSTRAINT environments_parent_not_self CHECK (parent_id <> id)
+);
+
+CREATE TABLE environment_role_access (
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL,
+    access VARCHAR(10) NOT NULL, -- none, read, write
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(project_id, slug)
+    PRIMARY KEY (environment_id, role),
+    CONSTRAINT environment_role_access_level CHECK (access IN ('none', 'read', 'write'))
+);
+
+CREATE TABLE ephemeral_environments (
+    environment_id UUID PRIMARY KEY REFERENCES environments(id) ON DELETE CASCADE,
+    source_id UUID REFERENCES environments(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE TABLE variables (
@@ -73,11 +102,189 @@ CREATE TABLE variables (
     key VARCHAR(255) NOT NULL,
     value TEXT NOT NULL,
     is_secret BOOLEAN DEFAULT FALSE,
+    encrypted BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1,
     UNIQUE(environment_id, key)
 );
 
+CREATE TABLE variable_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL,
+    action VARCHAR(50) NOT NULL, -- create, update, delete, rollback
+    value TEXT NOT NULL,
+    is_secret BOOLEAN NOT NULL DEFAULT FALSE,
+    encrypted BOOLEAN NOT NULL DEFAULT FALSE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(environment_id, key, version)
+);
+
+CREATE TABLE variable_tombstones (
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (environment_id, key)
+);
+
+CREATE TABLE variable_metadata (
+    variable_id UUID PRIMARY KEY REFERENCES variables(id) ON DELETE CASCADE,
+    description TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    owner VARCHAR(255) NOT NULL DEFAULT '',
+    links TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE variable_acls (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    pattern VARCHAR(255) NOT NULL, -- key or glob, such as STRIPE_*
+    readers TEXT[] NOT NULL DEFAULT '{}', -- user:<id> or role:<name>
+    writers TEXT[] NOT NULL DEFAULT '{}',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(environment_id, pattern)
+);
+
+CREATE TABLE variable_generators (
+    variable_id UUID PRIMARY KEY REFERENCES variables(id) ON DELETE CASCADE,
+    spec JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE variable_rotations (
+    variable_id UUID PRIMARY KEY REFERENCES variables(id) ON DELETE CASCADE,
+    interval_seconds INTEGER,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    rotated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    notified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT variable_rotations_policy CHECK (interval_seconds > 0 OR expires_at IS NOT NULL)
+);
+
+CREATE TABLE rotation_notices (
+    variable_id UUID NOT NULL REFERENCES variable_rotations(variable_id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    cycle TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (variable_id, email)
+);
+
+CREATE TABLE environment_snapshots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    tag VARCHAR(255) NOT NULL,
+    message TEXT,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(environment_id, tag)
+);
+
+CREATE TABLE snapshot_variables (
+    snapshot_id UUID NOT NULL REFERENCES environment_snapshots(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    value TEXT NOT NULL,
+    is_secret BOOLEAN NOT NULL DEFAULT FALSE,
+    encrypted BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (snapshot_id, key)
+);
+
+CREATE TABLE environment_locks (
+    environment_id UUID PRIMARY KEY REFERENCES environments(id) ON DELETE CASCADE,
+    locked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE environment_protections (
+    environment_id UUID PRIMARY KEY REFERENCES environments(id) ON DELETE CASCADE,
+    required_approvals INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT environment_protections_approvals CHECK (required_approvals > 0)
+);
+
+CREATE TABLE change_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    message TEXT,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending', -- pending, applied, rejected
+    required_approvals INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE change_request_variables (
+    change_request_id UUID NOT NULL REFERENCES change_requests(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    action VARCHAR(50) NOT NULL, -- set, delete, inherit
+    value TEXT NOT NULL DEFAULT '',
+    is_secret BOOLEAN NOT NULL DEFAULT FALSE,
+    encrypted BOOLEAN NOT NULL DEFAULT FALSE,
+    generator JSONB,
+    base TEXT, -- entity tag of the local variable when proposed, '' if there was none
+    PRIMARY KEY (change_request_id, key)
+);
+
+CREATE TABLE change_request_reviews (
+    change_request_id UUID NOT NULL REFERENCES change_requests(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    decision VARCHAR(50) NOT NULL, -- approve, reject
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (change_request_id, user_id)
+);
+
+CREATE TABLE change_request_comments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    change_request_id UUID NOT NULL REFERENCES change_requests(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE environment_keys (
+    environment_id UUID PRIMARY KEY REFERENCES environments(id) ON DELETE CASCADE,
+    wrapped_key BYTEA NOT NULL,
+    master_key_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE database_connections (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE RESTRICT,
+    name VARCHAR(255) NOT NULL,
+    dsn TEXT NOT NULL, -- sealed with the data key of the environment
+    creation TEXT NOT NULL,
+    revocation TEXT NOT NULL,
+    default_ttl_seconds INTEGER NOT NULL,
+    max_ttl_seconds INTEGER NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(environment_id, name),
+    CONSTRAINT database_connections_ttl CHECK (default_ttl_seconds > 0 AND default_ttl_seconds <= max_ttl_seconds)
+);
+
+CREATE TABLE credential_leases (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    connection_id UUID NOT NULL REFERENCES database_connections(id) ON DELETE CASCADE,
+    username VARCHAR(63) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE audit_logs (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID REFERENCES users(id) ON DELETE SET NULL,
@@ -110,3 +317,9 @@ CREATE INDEX idx_audit_logs_resource_id ON audit_logs(resource_id);
 CREATE INDEX idx_project_members_user_id ON project_members(user_id);
 CREATE INDEX idx_refresh_tokens_token ON refresh_tokens(token);
 CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX idx_variable_versions_environment_id_created_at ON variable_versions(environment_id, created_at);
+CREATE INDEX idx_environments_parent_id ON environments(parent_id);
+CREATE INDEX idx_change_requests_environment_id_status ON change_requests(environment_id, status);
+CREATE INDEX idx_ephemeral_environments_expires_at ON ephemeral_environments(expires_at);
+CREATE INDEX idx_variable_metadata_tags ON variable_metadata USING GIN (tags);
+CREATE INDEX idx_credential_leases_expires_at ON credential_leases(expires_at) WHERE revoked_at IS NULL;
@@ -19,13 +19,124 @@ type AuditLog struct {
 	CreatedAt      pgtype.Timestamptz `json:"created_at"`
 }
 
+type ChangeRequest struct {
+	ID                pgtype.UUID        `json:"id"`
+	EnvironmentID     pgtype.UUID        `json:"environment_id"`
+	AuthorID          pgtype.UUID        `json:"author_id"`
+	Message           pgtype.Text        `json:"message"`
+	Status            string             `json:"status"`
+	RequiredApprovals int32              `json:"required_approvals"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ChangeRequestComment struct {
+	ID              pgtype.UUID        `json:"id"`
+	ChangeRequestID pgtype.UUID        `json:"change_request_id"`
+	AuthorID        pgtype.UUID        `json:"author_id"`
+	Body            string             `json:"body"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+type ChangeRequestReview struct {
+	ChangeRequestID pgtype.UUID        `json:"change_request_id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Decision        string             `json:"decision"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+type ChangeRequestVariable struct {
+	ChangeRequestID pgtype.UUID `json:"change_request_id"`
+	Key             string      `json:"key"`
+	Action          string      `json:"action"`
+	Value           string      `json:"value"`
+	IsSecret        bool        `json:"is_secret"`
+	Encrypted       bool        `json:"encrypted"`
+	Generator       []byte      `json:"generator"`
+	Base            pgtype.Text `json:"base"`
+}
+
+type CredentialLease struct {
+	ID           pgtype.UUID        `json:"id"`
+	ConnectionID pgtype.UUID        `json:"connection_id"`
+	Username     string             `json:"username"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt    pgtype.Timestamptz `json:"revoked_at"`
+	CreatedBy    pgtype.UUID        `json:"created_by"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type DatabaseConnection struct {
+	ID                pgtype.UUID        `json:"id"`
+	EnvironmentID     pgtype.UUID        `json:"environment_id"`
+	Name              string             `json:"name"`
+	Dsn               string             `json:"dsn"`
+	Creation          string             `json:"creation"`
+	Revocation        string             `json:"revocation"`
+	DefaultTtlSeconds int32              `json:"default_ttl_seconds"`
+	MaxTtlSeconds     int32              `json:"max_ttl_seconds"`
+	CreatedBy         pgtype.UUID        `json:"created_by"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
 type Environment struct {
 	ID        pgtype.UUID        `json:"id"`
 	ProjectID pgtype.UUID        `json:"project_id"`
 	Name      string             `json:"name"`
 	Slug      string             `json:"slug"`
+	ParentID  pgtype.UUID        `json:"parent_id"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	Version   int32              `json:"version"`
+}
+
+type EnvironmentKey struct {
+	EnvironmentID pgtype.UUID        `json:"environment_id"`
+	WrappedKey    []byte             `json:"wrapped_key"`
+	MasterKeyID   string             `json:"master_key_id"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type EnvironmentLock struct {
+	EnvironmentID pgtype.UUID        `json:"environment_id"`
+	LockedBy      pgtype.UUID        `json:"locked_by"`
+	Reason        string             `json:"reason"`
+	LockedUntil   pgtype.Timestamptz `json:"locked_until"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type EnvironmentProtection struct {
+	EnvironmentID     pgtype.UUID        `json:"environment_id"`
+	RequiredApprovals int32              `json:"required_approvals"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
+type EnvironmentRoleAccess struct {
+	EnvironmentID pgtype.UUID        `json:"environment_id"`
+	Role          string             `json:"role"`
+	Access        string             `json:"access"`
+	UpdatedBy     pgtype.UUID        `json:"updated_by"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
+type EnvironmentSnapshot struct {
+	ID            pgtype.UUID        `json:"id"`
+	EnvironmentID pgtype.UUID        `json:"environment_id"`
+	Tag           string             `json:"tag"`
+	Message       pgtype.Text        `json:"message"`
+	AuthorID      pgtype.UUID        `json:"author_id"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type EphemeralEnvironment struct {
+	EnvironmentID pgtype.UUID        `json:"environment_id"`
+	SourceID      pgtype.UUID        `json:"source_id"`
+	ExpiresAt     pgtype.Timestamptz `json:"expires_at"`
+	CreatedBy     pgtype.UUID        `json:"created_by"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
 }
 
 type Organization struct {
@@ -71,6 +182,13 @@ type ProjectMember struct {
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
+type ProjectSchema struct {
+	ProjectID  pgtype.UUID        `json:"project_id"`
+	Definition []byte             `json:"definition"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
 type RefreshToken struct {
 	ID        pgtype.UUID        `json:"id"`
 	UserID    pgtype.UUID        `json:"user_id"`
@@ -81,6 +199,20 @@ type RefreshToken struct {
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
+type RotationNotice struct {
+	VariableID pgtype.UUID        `json:"variable_id"`
+	Email      string             `json:"email"`
+	Cycle      pgtype.Timestamptz `json:"cycle"`
+}
+
+type SnapshotVariable struct {
+	SnapshotID pgtype.UUID `json:"snapshot_id"`
+	Key        string      `json:"key"`
+	Value      string      `json:"value"`
+	IsSecret   bool        `json:"is_secret"`
+	Encrypted  bool        `json:"encrypted"`
+}
+
 type User struct {
 	ID                     pgtype.UUID        `json:"id"`
 	Email                  string             `json:"email"`
@@ -98,6 +230,66 @@ type Variable struct {
 	Key           string             `json:"key"`
 	Value         string             `json:"value"`
 	IsSecret      pgtype.Bool        `json:"is_secret"`
+	Encrypted     bool               `json:"encrypted"`
 	CreatedAt     pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	Version       int32              `json:"version"`
+}
+
+type VariableAcl struct {
+	ID            pgtype.UUID        `json:"id"`
+	EnvironmentID pgtype.UUID        `json:"environment_id"`
+	Pattern       string             `json:"pattern"`
+	Readers       []string           `json:"readers"`
+	Writers       []string           `json:"writers"`
+	CreatedBy     pgtype.UUID        `json:"created_by"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
+type VariableGenerator struct {
+	VariableID pgtype.UUID        `json:"variable_id"`
+	Spec       []byte             `json:"spec"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+type VariableMetadatum struct {
+	VariableID  pgtype.UUID        `json:"variable_id"`
+	Description string             `json:"description"`
+	Tags        []string           `json:"tags"`
+	Owner       string             `json:"owner"`
+	Links       []string           `json:"links"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type VariableRotation struct {
+	VariableID      pgtype.UUID        `json:"variable_id"`
+	IntervalSeconds pgtype.Int4        `json:"interval_seconds"`
+	ExpiresAt       pgtype.Timestamptz `json:"expires_at"`
+	RotatedAt       pgtype.Timestamptz `json:"rotated_at"`
+	NotifiedAt      pgtype.Timestamptz `json:"notified_at"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+type VariableTombstone struct {
+	EnvironmentID pgtype.UUID        `json:"environment_id"`
+	Key           string             `json:"key"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type VariableVersion struct {
+	ID            pgtype.UUID        `json:"id"`
+	EnvironmentID pgtype.UUID        `json:"environment_id"`
+	Key           string             `json:"key"`
+	Version       int32              `json:"version"`
+	Action        string             `json:"action"`
+	Value         string             `json:"value"`
+	IsSecret      bool               `json:"is_secret"`
+	Encrypted     bool               `json:"encrypted"`
+	AuthorID      pgtype.UUID        `json:"author_id"`
+	Message       pgtype.Text        `json:"message"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
 }
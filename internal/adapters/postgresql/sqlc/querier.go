@@ -13,51 +13,150 @@ import (
 type Querier interface {
 	AddOrganizationMember(ctx context.Context, arg AddOrganizationMemberParams) (OrganizationMember, error)
 	AddProjectMember(ctx context.Context, arg AddProjectMemberParams) (ProjectMember, error)
+	// Waits for the writes that checked the lock of the environment and holds
+	// off new ones until the end of the transaction.
+	ClaimEnvironmentLock(ctx context.Context, id pgtype.UUID) error
+	CloseChangeRequest(ctx context.Context, arg CloseChangeRequestParams) (int64, error)
 	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
+	CreateChangeRequest(ctx context.Context, arg CreateChangeRequestParams) (ChangeRequest, error)
+	CreateChangeRequestComment(ctx context.Context, arg CreateChangeRequestCommentParams) (ChangeRequestComment, error)
+	CreateChangeRequestVariable(ctx context.Context, arg CreateChangeRequestVariableParams) error
+	CreateCredentialLease(ctx context.Context, arg CreateCredentialLeaseParams) (CredentialLease, error)
+	CreateDatabaseConnection(ctx context.Context, arg CreateDatabaseConnectionParams) (DatabaseConnection, error)
 	CreateEnvironment(ctx context.Context, arg CreateEnvironmentParams) (Environment, error)
+	CreateEnvironmentKey(ctx context.Context, arg CreateEnvironmentKeyParams) (EnvironmentKey, error)
+	CreateEphemeralEnvironment(ctx context.Context, arg CreateEphemeralEnvironmentParams) (EphemeralEnvironment, error)
 	CreateInvitation(ctx context.Context, arg CreateInvitationParams) (OrganizationInvitation, error)
 	CreateOrganization(ctx context.Context, arg CreateOrganizationParams) (Organization, error)
 	CreateProject(ctx context.Context, arg CreateProjectParams) (Project, error)
 	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
+	CreateSnapshot(ctx context.Context, arg CreateSnapshotParams) (EnvironmentSnapshot, error)
+	CreateSnapshotVariable(ctx context.Context, arg CreateSnapshotVariableParams) error
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	CreateVariable(ctx context.Context, arg CreateVariableParams) (Variable, error)
+	CreateVariableTombstone(ctx context.Context, arg CreateVariableTombstoneParams) error
+	CreateVariableVersion(ctx context.Context, arg CreateVariableVersionParams) (VariableVersion, error)
+	DeleteDatabaseConnection(ctx context.Context, id pgtype.UUID) error
 	DeleteEnvironment(ctx context.Context, id pgtype.UUID) error
+	DeleteEnvironmentLock(ctx context.Context, environmentID pgtype.UUID) error
+	DeleteEnvironmentProtection(ctx context.Context, environmentID pgtype.UUID) error
+	DeleteEnvironmentRoleAccess(ctx context.Context, arg DeleteEnvironmentRoleAccessParams) error
 	DeleteInvitation(ctx context.Context, id pgtype.UUID) error
 	DeleteOrganization(ctx context.Context, id pgtype.UUID) error
 	DeleteProject(ctx context.Context, id pgtype.UUID) error
+	DeleteProjectSchema(ctx context.Context, projectID pgtype.UUID) error
 	DeleteRefreshToken(ctx context.Context, token string) error
 	DeleteUser(ctx context.Context, id pgtype.UUID) error
 	DeleteVariable(ctx context.Context, arg DeleteVariableParams) error
+	DeleteVariableACL(ctx context.Context, arg DeleteVariableACLParams) (VariableAcl, error)
+	DeleteVariableGenerator(ctx context.Context, variableID pgtype.UUID) error
+	DeleteVariableMetadata(ctx context.Context, variableID pgtype.UUID) error
+	DeleteVariableRotation(ctx context.Context, variableID pgtype.UUID) error
+	DeleteVariableTombstone(ctx context.Context, arg DeleteVariableTombstoneParams) (int64, error)
+	EncryptVariableValue(ctx context.Context, arg EncryptVariableValueParams) error
+	EncryptVariableVersionValue(ctx context.Context, arg EncryptVariableVersionValueParams) error
+	GetChangeRequest(ctx context.Context, id pgtype.UUID) (ChangeRequest, error)
+	GetCredentialLease(ctx context.Context, arg GetCredentialLeaseParams) (CredentialLease, error)
+	GetDatabaseConnection(ctx context.Context, arg GetDatabaseConnectionParams) (DatabaseConnection, error)
 	GetEnvironment(ctx context.Context, id pgtype.UUID) (Environment, error)
+	GetEnvironmentBySlug(ctx context.Context, arg GetEnvironmentBySlugParams) (Environment, error)
+	GetEnvironmentKey(ctx context.Context, environmentID pgtype.UUID) (EnvironmentKey, error)
+	GetEnvironmentLock(ctx context.Context, environmentID pgtype.UUID) (EnvironmentLock, error)
+	GetEnvironmentProtection(ctx context.Context, environmentID pgtype.UUID) (EnvironmentProtection, error)
+	GetEnvironmentRoleAccess(ctx context.Context, arg GetEnvironmentRoleAccessParams) (EnvironmentRoleAccess, error)
 	GetInvitationByToken(ctx context.Context, token string) (OrganizationInvitation, error)
 	GetOrganization(ctx context.Context, id pgtype.UUID) (Organization, error)
 	GetOrganizationMember(ctx context.Context, arg GetOrganizationMemberParams) (OrganizationMember, error)
 	GetProject(ctx context.Context, id pgtype.UUID) (Project, error)
+	GetProjectBySlug(ctx context.Context, arg GetProjectBySlugParams) (Project, error)
 	GetProjectMember(ctx context.Context, arg GetProjectMemberParams) (ProjectMember, error)
+	GetProjectSchema(ctx context.Context, projectID pgtype.UUID) (ProjectSchema, error)
 	GetRefreshToken(ctx context.Context, token string) (RefreshToken, error)
+	GetSnapshotByTag(ctx context.Context, arg GetSnapshotByTagParams) (EnvironmentSnapshot, error)
 	GetUser(ctx context.Context, id pgtype.UUID) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByResetToken(ctx context.Context, passwordResetToken pgtype.Text) (User, error)
+	GetVariable(ctx context.Context, arg GetVariableParams) (Variable, error)
+	GetVariableGenerator(ctx context.Context, variableID pgtype.UUID) (VariableGenerator, error)
+	GetVariableMetadata(ctx context.Context, variableID pgtype.UUID) (VariableMetadatum, error)
+	GetVariableRotation(ctx context.Context, variableID pgtype.UUID) (VariableRotation, error)
+	GetVariableVersion(ctx context.Context, arg GetVariableVersionParams) (VariableVersion, error)
+	ListActiveCredentialLeases(ctx context.Context, connectionID pgtype.UUID) ([]CredentialLease, error)
 	ListActiveRefreshTokensForUser(ctx context.Context, userID pgtype.UUID) ([]RefreshToken, error)
+	ListChangeRequestComments(ctx context.Context, changeRequestID pgtype.UUID) ([]ChangeRequestComment, error)
+	ListChangeRequestReviews(ctx context.Context, changeRequestID pgtype.UUID) ([]ChangeRequestReview, error)
+	ListChangeRequestVariables(ctx context.Context, changeRequestID pgtype.UUID) ([]ChangeRequestVariable, error)
+	ListChangeRequests(ctx context.Context, arg ListChangeRequestsParams) ([]ChangeRequest, error)
+	ListCredentialLeases(ctx context.Context, connectionID pgtype.UUID) ([]CredentialLease, error)
+	ListDatabaseConnections(ctx context.Context, environmentID pgtype.UUID) ([]DatabaseConnection, error)
+	ListEnvironmentRoleAccess(ctx context.Context, environmentID pgtype.UUID) ([]EnvironmentRoleAccess, error)
 	ListEnvironments(ctx context.Context, projectID pgtype.UUID) ([]Environment, error)
+	ListEphemeralEnvironments(ctx context.Context, arg ListEphemeralEnvironmentsParams) ([]ListEphemeralEnvironmentsRow, error)
+	ListExpiredCredentialLeases(ctx context.Context, expiresAt pgtype.Timestamptz) ([]ListExpiredCredentialLeasesRow, error)
+	ListExpiredEphemeralEnvironments(ctx context.Context, expiresAt pgtype.Timestamptz) ([]Environment, error)
 	ListInvitations(ctx context.Context, organizationID pgtype.UUID) ([]OrganizationInvitation, error)
+	ListOrganizationAdminEmails(ctx context.Context, organizationID pgtype.UUID) ([]string, error)
 	ListOrganizationMembers(ctx context.Context, organizationID pgtype.UUID) ([]ListOrganizationMembersRow, error)
 	ListOrganizations(ctx context.Context) ([]Organization, error)
+	ListPlaintextVariableVersions(ctx context.Context) ([]VariableVersion, error)
+	ListPlaintextVariables(ctx context.Context) ([]Variable, error)
+	ListProjectAdminEmails(ctx context.Context, projectID pgtype.UUID) ([]string, error)
 	ListProjectMembers(ctx context.Context, projectID pgtype.UUID) ([]ListProjectMembersRow, error)
 	ListProjects(ctx context.Context, organizationID pgtype.UUID) ([]Project, error)
 	ListProjectsForMember(ctx context.Context, arg ListProjectsForMemberParams) ([]Project, error)
+	// Returns who has been told about the current policy and rotation of the
+	// variables. Notices of an earlier cycle are ignored.
+	ListRotationNotices(ctx context.Context, variableIds []pgtype.UUID) ([]RotationNotice, error)
+	ListSnapshotVariables(ctx context.Context, snapshotID pgtype.UUID) ([]SnapshotVariable, error)
+	ListSnapshots(ctx context.Context, environmentID pgtype.UUID) ([]EnvironmentSnapshot, error)
+	ListStaleRotations(ctx context.Context, arg ListStaleRotationsParams) ([]ListStaleRotationsRow, error)
+	ListUnnotifiedRotations(ctx context.Context, dueBefore pgtype.Timestamptz) ([]ListUnnotifiedRotationsRow, error)
 	ListUsers(ctx context.Context) ([]User, error)
+	// Returns when each key of the environment was last written, which unlike
+	// variables.updated_at is not changed by edits of metadata or policies.
+	ListValueChangeTimes(ctx context.Context, environmentID pgtype.UUID) ([]ListValueChangeTimesRow, error)
+	ListVariableACLs(ctx context.Context, environmentID pgtype.UUID) ([]VariableAcl, error)
+	ListVariableMetadata(ctx context.Context, variableIds []pgtype.UUID) ([]VariableMetadatum, error)
+	ListVariableRotations(ctx context.Context, variableIds []pgtype.UUID) ([]VariableRotation, error)
+	ListVariableTombstones(ctx context.Context, environmentID pgtype.UUID) ([]VariableTombstone, error)
+	ListVariableVersions(ctx context.Context, arg ListVariableVersionsParams) ([]VariableVersion, error)
+	ListVariableVersionsAt(ctx context.Context, arg ListVariableVersionsAtParams) ([]VariableVersion, error)
 	ListVariables(ctx context.Context, environmentID pgtype.UUID) ([]Variable, error)
+	LockChangeRequest(ctx context.Context, id pgtype.UUID) (ChangeRequest, error)
+	// Serializes changes to the inheritance tree of a project until the end of
+	// the transaction.
+	LockEnvironmentTree(ctx context.Context, projectID pgtype.UUID) error
+	LockVariable(ctx context.Context, arg LockVariableParams) (Variable, error)
+	LockVariables(ctx context.Context, environmentID pgtype.UUID) ([]Variable, error)
+	MarkRotationNotified(ctx context.Context, variableID pgtype.UUID) error
+	MarkVariableRotated(ctx context.Context, variableID pgtype.UUID) error
+	RecordRotationNotices(ctx context.Context, arg RecordRotationNoticesParams) error
 	RemoveOrganizationMember(ctx context.Context, arg RemoveOrganizationMemberParams) error
 	RemoveProjectMember(ctx context.Context, arg RemoveProjectMemberParams) error
+	RevokeCredentialLease(ctx context.Context, id pgtype.UUID) error
 	RevokeRefreshToken(ctx context.Context, token string) error
+	SearchVariables(ctx context.Context, arg SearchVariablesParams) ([]SearchVariablesRow, error)
+	SetEnvironmentParent(ctx context.Context, arg SetEnvironmentParentParams) (Environment, error)
 	SetPasswordResetToken(ctx context.Context, arg SetPasswordResetTokenParams) error
+	// Keeps the environment from being locked or unlocked until the end of the
+	// transaction, so that a write can rely on GetEnvironmentLock.
+	ShareEnvironmentLock(ctx context.Context, id pgtype.UUID) error
+	TouchVariable(ctx context.Context, id pgtype.UUID) error
 	UpdateEnvironment(ctx context.Context, arg UpdateEnvironmentParams) (Environment, error)
 	UpdateOrganization(ctx context.Context, arg UpdateOrganizationParams) (Organization, error)
 	UpdatePassword(ctx context.Context, arg UpdatePasswordParams) error
 	UpdateProject(ctx context.Context, arg UpdateProjectParams) (Project, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateVariable(ctx context.Context, arg UpdateVariableParams) (Variable, error)
+	UpsertChangeRequestReview(ctx context.Context, arg UpsertChangeRequestReviewParams) error
+	UpsertEnvironmentLock(ctx context.Context, arg UpsertEnvironmentLockParams) (EnvironmentLock, error)
+	UpsertEnvironmentProtection(ctx context.Context, arg UpsertEnvironmentProtectionParams) (EnvironmentProtection, error)
+	UpsertEnvironmentRoleAccess(ctx context.Context, arg UpsertEnvironmentRoleAccessParams) (EnvironmentRoleAccess, error)
+	UpsertProjectSchema(ctx context.Context, arg UpsertProjectSchemaParams) (ProjectSchema, error)
+	UpsertVariableACL(ctx context.Context, arg UpsertVariableACLParams) (VariableAcl, error)
+	UpsertVariableGenerator(ctx context.Context, arg UpsertVariableGeneratorParams) (VariableGenerator, error)
+	UpsertVariableMetadata(ctx context.Context, arg UpsertVariableMetadataParams) (VariableMetadatum, error)
+	UpsertVariableRotation(ctx context.Context, arg UpsertVariableRotationParams) (VariableRotation, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -100,7 +100,7 @@ func (q *Queries) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams)
 const createEnvironment = `-- name: CreateEnvironment :one
 INSERT INTO environments (project_id, name, slug)
 VALUES ($1, $2, $3)
-RETURNING id, project_id, name, slug, created_at, updated_at
+RETURNING id, project_id, name, slug, parent_id, created_at, updated_at, version
 `
 
 type CreateEnvironmentParams struct {
@@ -117,8 +117,10 @@ func (q *Queries) CreateEnvironment(ctx context.Context, arg CreateEnvironmentPa
 		&i.ProjectID,
 		&i.Name,
 		&i.Slug,
+		&i.ParentID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -247,9 +249,9 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 }
 
 const createVariable = `-- name: CreateVariable :one
-INSERT INTO variables (environment_id, key, value, is_secret)
-VALUES ($1, $2, $3, $4)
-RETURNING id, environment_id, key, value, is_secret, created_at, updated_at
+INSERT INTO variables (environment_id, key, value, is_secret, encrypted)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, environment_id, key, value, is_secret, encrypted, created_at, updated_at, version
 `
 
 type CreateVariableParams struct {
@@ -257,6 +259,7 @@ type CreateVariableParams struct {
 	Key           string      `json:"key"`
 	Value         string      `json:"value"`
 	IsSecret      pgtype.Bool `json:"is_secret"`
+	Encrypted     bool        `json:"encrypted"`
 }
 
 func (q *Queries) CreateVariable(ctx context.Context, arg CreateVariableParams) (Variable, error) {
@@ -265,6 +268,7 @@ func (q *Queries) CreateVariable(ctx context.Context, arg CreateVariableParams)
 		arg.Key,
 		arg.Value,
 		arg.IsSecret,
+		arg.Encrypted,
 	)
 	var i Variable
 	err := row.Scan(
@@ -273,8 +277,10 @@ func (q *Queries) CreateVariable(ctx context.Context, arg CreateVariableParams)
 		&i.Key,
 		&i.Value,
 		&i.IsSecret,
+		&i.Encrypted,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -345,7 +351,7 @@ func (q *Queries) DeleteVariable(ctx context.Context, arg DeleteVariableParams)
 }
 
 const getEnvironment = `-- name: GetEnvironment :one
-SELECT id, project_id, name, slug, created_at, updated_at FROM environments
+SELECT id, project_id, name, slug, parent_id, created_at, updated_at, version FROM environments
 WHERE id = $1 LIMIT 1
 `
 
@@ -357,8 +363,10 @@ func (q *Queries) GetEnvironment(ctx context.Context, id pgtype.UUID) (Environme
 		&i.ProjectID,
 		&i.Name,
 		&i.Slug,
+		&i.ParentID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -531,8 +539,35 @@ func (q *Queries) GetUserByResetToken(ctx context.Context, passwordResetToken pg
 	return i, err
 }
 
+const getVariable = `-- name: GetVariable :one
+SELECT id, environment_id, key, value, is_secret, encrypted, created_at, updated_at, version FROM variables
+WHERE environment_id = $1 AND key = $2 LIMIT 1
+`
+
+type GetVariableParams struct {
+	EnvironmentID pgtype.UUID `json:"environment_id"`
+	Key           string      `json:"key"`
+}
+
+func (q *Queries) GetVariable(ctx context.Context, arg GetVariableParams) (Variable, error) {
+	row := q.db.QueryRow(ctx, getVariable, arg.EnvironmentID, arg.Key)
+	var i Variable
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Key,
+		&i.Value,
+		&i.IsSecret,
+		&i.Encrypted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
 const listEnvironments = `-- name: ListEnvironments :many
-SELECT id, project_id, name, slug, created_at, updated_at FROM environments
+SELECT id, project_id, name, slug, parent_id, created_at, updated_at, version FROM environments
 WHERE project_id = $1
 ORDER BY name
 `
@@ -551,8 +586,10 @@ func (q *Queries) ListEnvironments(ctx context.Context, projectID pgtype.UUID) (
 			&i.ProjectID,
 			&i.Name,
 			&i.Slug,
+			&i.ParentID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -825,7 +862,7 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 }
 
 const listVariables = `-- name: ListVariables :many
-SELECT id, environment_id, key, value, is_secret, created_at, updated_at FROM variables
+SELECT id, environment_id, key, value, is_secret, encrypted, created_at, updated_at, version FROM variables
 WHERE environment_id = $1
 ORDER BY key
 `
@@ -845,8 +882,75 @@ func (q *Queries) ListVariables(ctx context.Context, environmentID pgtype.UUID)
 			&i.Key,
 			&i.Value,
 			&i.IsSecret,
+			&i.Encrypted,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockVariable = `-- name: LockVariable :one
+SELECT id, environment_id, key, value, is_secret, encrypted, created_at, updated_at, version FROM variables
+WHERE environment_id = $1 AND key = $2 LIMIT 1
+FOR UPDATE
+`
+
+type LockVariableParams struct {
+	EnvironmentID pgtype.UUID `json:"environment_id"`
+	Key           string      `json:"key"`
+}
+
+func (q *Queries) LockVariable(ctx context.Context, arg LockVariableParams) (Variable, error) {
+	row := q.db.QueryRow(ctx, lockVariable, arg.EnvironmentID, arg.Key)
+	var i Variable
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Key,
+		&i.Value,
+		&i.IsSecret,
+		&i.Encrypted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const lockVariables = `-- name: LockVariables :many
+SELECT id, environment_id, key, value, is_secret, encrypted, created_at, updated_at, version FROM variables
+WHERE environment_id = $1
+ORDER BY key
+FOR UPDATE
+`
+
+func (q *Queries) LockVariables(ctx context.Context, environmentID pgtype.UUID) ([]Variable, error) {
+	rows, err := q.db.Query(ctx, lockVariables, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Variable
+	for rows.Next() {
+		var i Variable
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.Key,
+			&i.Value,
+			&i.IsSecret,
+			&i.Encrypted,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -905,29 +1009,48 @@ func (q *Queries) SetPasswordResetToken(ctx context.Context, arg SetPasswordRese
 	return err
 }
 
+const touchVariable = `-- name: TouchVariable :exec
+UPDATE variables
+SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (q *Queries) TouchVariable(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, touchVariable, id)
+	return err
+}
+
 const updateEnvironment = `-- name: UpdateEnvironment :one
 UPDATE environments
-SET name = $2, slug = $3, updated_at = CURRENT_TIMESTAMP
-WHERE id = $1
-RETURNING id, project_id, name, slug, created_at, updated_at
+SET name = $1, slug = $2, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $3 AND ($4::int IS NULL OR version = $4)
+RETURNING id, project_id, name, slug, parent_id, created_at, updated_at, version
 `
 
 type UpdateEnvironmentParams struct {
-	ID   pgtype.UUID `json:"id"`
-	Name string      `json:"name"`
-	Slug string      `json:"slug"`
+	Name    string      `json:"name"`
+	Slug    string      `json:"slug"`
+	ID      pgtype.UUID `json:"id"`
+	Version pgtype.Int4 `json:"version"`
 }
 
 func (q *Queries) UpdateEnvironment(ctx context.Context, arg UpdateEnvironmentParams) (Environment, error) {
-	row := q.db.QueryRow(ctx, updateEnvironment, arg.ID, arg.Name, arg.Slug)
+	row := q.db.QueryRow(ctx, updateEnvironment,
+		arg.Name,
+		arg.Slug,
+		arg.ID,
+		arg.Version,
+	)
 	var i Environment
 	err := row.Scan(
 		&i.ID,
 		&i.ProjectID,
 		&i.Name,
 		&i.Slug,
+		&i.ParentID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -1045,9 +1168,9 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 
 const updateVariable = `-- name: UpdateVariable :one
 UPDATE variables
-SET value = $3, is_secret = $4, updated_at = CURRENT_TIMESTAMP
+SET value = $3, is_secret = $4, encrypted = $5, updated_at = CURRENT_TIMESTAMP
 WHERE environment_id = $1 AND key = $2
-RETURNING id, environment_id, key, value, is_secret, created_at, updated_at
+RETURNING id, environment_id, key, value, is_secret, encrypted, created_at, updated_at, version
 `
 
 type UpdateVariableParams struct {
@@ -1055,6 +1178,7 @@ type UpdateVariableParams struct {
 	Key           string      `json:"key"`
 	Value         string      `json:"value"`
 	IsSecret      pgtype.Bool `json:"is_secret"`
+	Encrypted     bool        `json:"encrypted"`
 }
 
 func (q *Queries) UpdateVariable(ctx context.Context, arg UpdateVariableParams) (Variable, error) {
@@ -1063,6 +1187,7 @@ func (q *Queries) UpdateVariable(ctx context.Context, arg UpdateVariableParams)
 		arg.Key,
 		arg.Value,
 		arg.IsSecret,
+		arg.Encrypted,
 	)
 	var i Variable
 	err := row.Scan(
@@ -1071,8 +1196,10 @@ func (q *Queries) UpdateVariable(ctx context.Context, arg UpdateVariableParams)
 		&i.Key,
 		&i.Value,
 		&i.IsSecret,
+		&i.Encrypted,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
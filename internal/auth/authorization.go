@@ -16,10 +16,63 @@ const (
 	RoleOwner  Role = "owner"
 	RoleAdmin  Role = "admin"
 	RoleMember Role = "member"
+
+	RoleMaintainer Role = "maintainer"
+	RoleDeveloper  Role = "developer"
+	RoleViewer     Role = "viewer"
+)
+
+// ProjectRoles are the roles of project members, from least to most
+// privileged. Project admins have write access to every environment and
+// maintainers can also create, change and delete environments.
+var ProjectRoles = []Role{RoleViewer, RoleDeveloper, RoleMaintainer, RoleAdmin}
+
+// Access is what a user may do with an environment and its variables.
+type Access string
+
+const (
+	AccessNone  Access = "none"
+	AccessRead  Access = "read"
+	AccessWrite Access = "write"
 )
 
+var accessRank = map[Access]int{AccessNone: 0, AccessRead: 1, AccessWrite: 2}
+
+// Valid reports whether a is a known access level.
+func (a Access) Valid() bool {
+	_, ok := accessRank[a]
+	return ok
+}
+
+// Allows reports whether a includes required.
+func (a Access) Allows(required Access) bool {
+	return a.Valid() && accessRank[a] >= accessRank[required]
+}
+
+// DefaultAccess is the access of a project role to environments that do not
+// override it: viewers read, everyone else writes.
+func DefaultAccess(role Role) Access {
+	switch role {
+	case RoleAdmin, RoleMaintainer, RoleDeveloper:
+		return AccessWrite
+	case RoleViewer:
+		return AccessRead
+	}
+	return AccessNone
+}
+
 type Authorizer interface {
 	HasRole(ctx context.Context, userID, orgID pgtype.UUID, requiredRoles ...Role) error
+	HasProjectAccess(ctx context.Context, userID, projectID pgtype.UUID) error
+	CanRevealSecrets(ctx context.Context, userID, projectID pgtype.UUID) error
+	CanOverrideLock(ctx context.Context, userID, projectID pgtype.UUID) error
+	CanProtectEnvironment(ctx context.Context, userID, projectID pgtype.UUID) error
+	CanApproveChanges(ctx context.Context, userID, projectID pgtype.UUID) error
+	CanManageDatabases(ctx context.Context, userID, projectID pgtype.UUID) error
+	CanRestrictVariables(ctx context.Context, userID, projectID pgtype.UUID) error
+	CanManageEnvironments(ctx context.Context, userID, projectID pgtype.UUID) error
+	CanAssignRoles(ctx context.Context, userID, projectID pgtype.UUID) error
+	EnvironmentAccess(ctx context.Context, userID pgtype.UUID, env repo.Environment) (Access, error)
 }
 
 type authorizer struct {
@@ -54,3 +107,170 @@ func (a *authorizer) HasRole(ctx context.Context, userID, orgID pgtype.UUID, req
 
 	return fmt.Errorf("insufficient permissions: required %v, have %s", requiredRoles, member.Role)
 }
+
+// projectOrganization returns the id of the organization that owns the
+// project.
+func (a *authorizer) projectOrganization(ctx context.Context, projectID pgtype.UUID) (pgtype.UUID, error) {
+	project, err := a.repo.GetProject(ctx, projectID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return pgtype.UUID{}, fmt.Errorf("project not found")
+		}
+		return pgtype.UUID{}, fmt.Errorf("failed to fetch project: %w", err)
+	}
+	return project.OrganizationID, nil
+}
+
+// projectRole reports whether userID is an owner or admin of the project's
+// organization and, if not, returns their role in the project. It fails if
+// they are neither.
+func (a *authorizer) projectRole(ctx context.Context, userID, projectID pgtype.UUID) (orgAdmin bool, role Role, err error) {
+	orgID, err := a.projectOrganization(ctx, projectID)
+	if err != nil {
+		return false, "", err
+	}
+
+	if err := a.HasRole(ctx, userID, orgID, RoleOwner, RoleAdmin); err == nil {
+		return true, "", nil
+	}
+
+	member, err := a.repo.GetProjectMember(ctx, repo.GetProjectMemberParams{
+		ProjectID: projectID,
+		UserID:    userID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, "", fmt.Errorf("user is not a member of this project")
+		}
+		return false, "", fmt.Errorf("failed to check project membership: %w", err)
+	}
+	return false, Role(member.Role), nil
+}
+
+// HasProjectAccess succeeds for owners and admins of the project's organization
+// and for users that were added as members of the project itself.
+func (a *authorizer) HasProjectAccess(ctx context.Context, userID, projectID pgtype.UUID) error {
+	_, _, err := a.projectRole(ctx, userID, projectID)
+	return err
+}
+
+// CanRevealSecrets succeeds for owners and admins of the project's organization
+// and for admins of the project itself. Other members only see masked secrets.
+func (a *authorizer) CanRevealSecrets(ctx context.Context, userID, projectID pgtype.UUID) error {
+	orgAdmin, role, err := a.projectRole(ctx, userID, projectID)
+	if err != nil {
+		return err
+	}
+	if !orgAdmin && role != RoleAdmin {
+		return fmt.Errorf("insufficient permissions to reveal secrets")
+	}
+	return nil
+}
+
+// CanOverrideLock succeeds only for owners of the project's organization.
+func (a *authorizer) CanOverrideLock(ctx context.Context, userID, projectID pgtype.UUID) error {
+	orgID, err := a.projectOrganization(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	return a.HasRole(ctx, userID, orgID, RoleOwner)
+}
+
+// CanProtectEnvironment succeeds for owners and admins of the project's
+// organization. Project admins cannot lift the review requirement they are
+// subject to.
+func (a *authorizer) CanProtectEnvironment(ctx context.Context, userID, projectID pgtype.UUID) error {
+	orgID, err := a.projectOrganization(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	return a.HasRole(ctx, userID, orgID, RoleOwner, RoleAdmin)
+}
+
+// CanApproveChanges succeeds for owners and admins of the project's
+// organization and for admins of the project itself.
+func (a *authorizer) CanApproveChanges(ctx context.Context, userID, projectID pgtype.UUID) error {
+	orgAdmin, role, err := a.projectRole(ctx, userID, projectID)
+	if err != nil {
+		return err
+	}
+	if !orgAdmin && role != RoleAdmin {
+		return fmt.Errorf("insufficient permissions to review changes")
+	}
+	return nil
+}
+
+// CanManageDatabases succeeds for owners and admins of the project's
+// organization and for admins of the project itself. It guards the database
+// connections that dynamic credentials are issued from.
+func (a *authorizer) CanManageDatabases(ctx context.Context, userID, projectID pgtype.UUID) error {
+	orgAdmin, role, err := a.projectRole(ctx, userID, projectID)
+	if err != nil {
+		return err
+	}
+	if !orgAdmin && role != RoleAdmin {
+		return fmt.Errorf("insufficient permissions to manage database connections")
+	}
+	return nil
+}
+
+// CanRestrictVariables succeeds for owners and admins of the project's
+// organization. Project admins cannot change the access rules they are subject
+// to.
+func (a *authorizer) CanRestrictVariables(ctx context.Context, userID, projectID pgtype.UUID) error {
+	orgID, err := a.projectOrganization(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	return a.HasRole(ctx, userID, orgID, RoleOwner, RoleAdmin)
+}
+
+// CanManageEnvironments succeeds for owners and admins of the project's
+// organization and for admins and maintainers of the project itself. It guards
+// creating, changing and deleting environments.
+func (a *authorizer) CanManageEnvironments(ctx context.Context, userID, projectID pgtype.UUID) error {
+	orgAdmin, role, err := a.projectRole(ctx, userID, projectID)
+	if err != nil {
+		return err
+	}
+	if !orgAdmin && role != RoleAdmin && role != RoleMaintainer {
+		return fmt.Errorf("insufficient permissions to manage environments")
+	}
+	return nil
+}
+
+// CanAssignRoles succeeds for owners and admins of the project's organization,
+// who also add project members. It guards the access of project roles to
+// environments.
+func (a *authorizer) CanAssignRoles(ctx context.Context, userID, projectID pgtype.UUID) error {
+	orgID, err := a.projectOrganization(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	return a.HasRole(ctx, userID, orgID, RoleOwner, RoleAdmin)
+}
+
+// EnvironmentAccess returns what userID may do with env. Owners and admins of
+// the organization and project admins have write access. Other project members
+// get the default access of their role unless the environment overrides it.
+func (a *authorizer) EnvironmentAccess(ctx context.Context, userID pgtype.UUID, env repo.Environment) (Access, error) {
+	orgAdmin, role, err := a.projectRole(ctx, userID, env.ProjectID)
+	if err != nil {
+		return AccessNone, err
+	}
+	if orgAdmin || role == RoleAdmin {
+		return AccessWrite, nil
+	}
+
+	override, err := a.repo.GetEnvironmentRoleAccess(ctx, repo.GetEnvironmentRoleAccessParams{
+		EnvironmentID: env.ID,
+		Role:          string(role),
+	})
+	if err == pgx.ErrNoRows {
+		return DefaultAccess(role), nil
+	}
+	if err != nil {
+		return AccessNone, fmt.Errorf("failed to fetch environment access: %w", err)
+	}
+	return Access(override.Access), nil
+}
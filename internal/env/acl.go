@@ -0,0 +1,381 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+var (
+	ErrInvalidACL  = errors.New("invalid access rule")
+	ErrACLNotFound = errors.New("access rule not found")
+	ErrRestricted  = errors.New("access to this variable is restricted")
+)
+
+// Prefixes of the principals an access rule names.
+const (
+	principalUser = "user:"
+	principalRole = "role:"
+)
+
+// aclRoles are the role names a rule can grant access to. They are matched
+// against the caller's role in the organization and in the project.
+var aclRoles = []string{"owner", "admin", "member", "maintainer", "developer", "viewer"}
+
+// maxACLPrincipals limits the readers and writers of a single rule.
+const maxACLPrincipals = 64
+
+// ACL restricts the variables whose key matches Pattern, a key or a glob such
+// as STRIPE_*, to the principals it names. Readers may read matching
+// variables, writers may also change them. A principal is user:<id> or
+// role:<name>. Rules of ancestors apply to the variables an environment
+// inherits as well as to its own, so Source is the slug of the environment
+// that defines the rule and Inherited is set when that is an ancestor.
+type ACL struct {
+	ID        pgtype.UUID        `json:"id"`
+	Pattern   string             `json:"pattern"`
+	Readers   []string           `json:"readers"`
+	Writers   []string           `json:"writers"`
+	Source    string             `json:"source"`
+	Inherited bool               `json:"inherited,omitempty"`
+	CreatedBy pgtype.UUID        `json:"created_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type SetACLParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Pattern       string      `json:"pattern"`
+	Readers       []string    `json:"readers"`
+	Writers       []string    `json:"writers"`
+	AuthorID      pgtype.UUID `json:"-"`
+}
+
+// KeyAccess is what a single user may do with the variables of an
+// environment. The zero value allows everything.
+type KeyAccess struct {
+	rules  []ACL
+	userID pgtype.UUID
+	roles  []string
+	// hidden are the slugs of the ancestors whose variables the user may not
+	// read.
+	hidden []string
+}
+
+// HideLayers returns a copy of a under which the variables inherited from the
+// environments with the given slugs cannot be read.
+func (a KeyAccess) HideLayers(slugs []string) KeyAccess {
+	a.hidden = slices.Concat(a.hidden, slugs)
+	return a
+}
+
+// CanRead reports whether the user may read key: every rule matching it must
+// name the user as a reader or writer. Keys no rule matches are unrestricted.
+func (a KeyAccess) CanRead(key string) bool {
+	for _, rule := range a.rules {
+		if matchKey(rule.Pattern, key) && !a.named(rule.Readers) && !a.named(rule.Writers) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanWrite reports whether the user may change key: every rule matching it must
+// name the user as a writer.
+func (a KeyAccess) CanWrite(key string) bool {
+	for _, rule := range a.rules {
+		if matchKey(rule.Pattern, key) && !a.named(rule.Writers) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanReadVariable reports whether the user may read v: its key must be
+// readable and, if it is inherited, so must be the environment it comes from.
+func (a KeyAccess) CanReadVariable(v Variable) bool {
+	return a.CanRead(v.Key) && !(v.Inherited && slices.Contains(a.hidden, v.Source))
+}
+
+// Redact replaces the variables the user may not read with placeholders that
+// only keep the key and where it is defined.
+func (a KeyAccess) Redact(vars []Variable) []Variable {
+	if len(a.rules) == 0 && len(a.hidden) == 0 {
+		return vars
+	}
+	out := make([]Variable, 0, len(vars))
+	for _, v := range vars {
+		if !a.CanReadVariable(v) {
+			v = redacted(v)
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// checkKeys fails with ErrRestricted, naming the keys allowed rejects.
+func checkKeys(keys []string, allowed func(key string) bool) error {
+	var denied []string
+	for _, key := range keys {
+		if !allowed(key) {
+			denied = append(denied, key)
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("%w: %s", ErrRestricted, strings.Join(denied, ", "))
+	}
+	return nil
+}
+
+// changedKeys returns the keys of changes.
+func changedKeys(changes []change) []string {
+	keys := make([]string, len(changes))
+	for i, c := range changes {
+		keys[i] = c.Key
+	}
+	return keys
+}
+
+func (a KeyAccess) named(principals []string) bool {
+	for _, p := range principals {
+		if id, ok := strings.CutPrefix(p, principalUser); ok {
+			var uid pgtype.UUID
+			if uid.Scan(id) == nil && uid == a.userID {
+				return true
+			}
+		} else if role, ok := strings.CutPrefix(p, principalRole); ok && slices.Contains(a.roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func redacted(v Variable) Variable {
+	return Variable{
+		Key:        v.Key,
+		IsSecret:   v.IsSecret,
+		Restricted: true,
+		Source:     v.Source,
+		Inherited:  v.Inherited,
+		CreatedAt:  v.CreatedAt,
+		UpdatedAt:  v.UpdatedAt,
+	}
+}
+
+// matchKey reports whether key matches pattern. Patterns are validated when
+// stored, so a malformed one never matches.
+func matchKey(pattern, key string) bool {
+	ok, err := path.Match(pattern, key)
+	return err == nil && ok
+}
+
+// normalizePrincipals validates principals and returns them deduplicated, with
+// user ids in canonical form.
+func normalizePrincipals(field string, principals []string) ([]string, error) {
+	if len(principals) > maxACLPrincipals {
+		return nil, fmt.Errorf("%w: at most %d %s", ErrInvalidACL, maxACLPrincipals, field)
+	}
+	out := make([]string, 0, len(principals))
+	for _, p := range principals {
+		p = strings.TrimSpace(p)
+		if id, ok := strings.CutPrefix(p, principalUser); ok {
+			var uid pgtype.UUID
+			if err := uid.Scan(id); err != nil {
+				return nil, fmt.Errorf("%w: %s: invalid user id %q", ErrInvalidACL, field, id)
+			}
+			value, _ := uid.Value()
+			p = principalUser + value.(string)
+		} else if role, ok := strings.CutPrefix(p, principalRole); !ok || !slices.Contains(aclRoles, role) {
+			return nil, fmt.Errorf("%w: %s: %q is not user:<id> or role:<%s>", ErrInvalidACL, field, p, strings.Join(aclRoles, "|"))
+		}
+		if !slices.Contains(out, p) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func toACL(rule repo.VariableAcl, source repo.Environment, envID pgtype.UUID) ACL {
+	return ACL{
+		ID:        rule.ID,
+		Pattern:   rule.Pattern,
+		Readers:   rule.Readers,
+		Writers:   rule.Writers,
+		Source:    source.Slug,
+		Inherited: source.ID != envID,
+		CreatedBy: rule.CreatedBy,
+		CreatedAt: rule.CreatedAt,
+		UpdatedAt: rule.UpdatedAt,
+	}
+}
+
+// ListACLs returns the access rules that apply to the environment, its own
+// first and then those of its ancestors, nearest first.
+func (s *svc) ListACLs(ctx context.Context, envID pgtype.UUID) ([]ACL, error) {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	chain, err := s.chain(ctx, s.repo, env)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []ACL{}
+	for _, source := range chain {
+		rules, err := s.repo.ListVariableACLs(ctx, source.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list access rules: %w", err)
+		}
+		for _, rule := range rules {
+			out = append(out, toACL(rule, source, env.ID))
+		}
+	}
+	return out, nil
+}
+
+// SetACL creates the access rule for a pattern or replaces its principals.
+func (s *svc) SetACL(ctx context.Context, params SetACLParams) (ACL, error) {
+	params.Pattern = strings.TrimSpace(params.Pattern)
+	if params.Pattern == "" || len(params.Pattern) > 255 {
+		return ACL{}, fmt.Errorf("%w: pattern is required (max 255 characters)", ErrInvalidACL)
+	}
+	if _, err := path.Match(params.Pattern, ""); err != nil {
+		return ACL{}, fmt.Errorf("%w: malformed pattern %q", ErrInvalidACL, params.Pattern)
+	}
+	readers, err := normalizePrincipals("readers", params.Readers)
+	if err != nil {
+		return ACL{}, err
+	}
+	writers, err := normalizePrincipals("writers", params.Writers)
+	if err != nil {
+		return ACL{}, err
+	}
+	if len(readers) == 0 && len(writers) == 0 {
+		return ACL{}, fmt.Errorf("%w: name at least one reader or writer", ErrInvalidACL)
+	}
+
+	env, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return ACL{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	var stored repo.VariableAcl
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, env.ID); err != nil {
+			return err
+		}
+		var err error
+		stored, err = q.UpsertVariableACL(ctx, repo.UpsertVariableACLParams{
+			EnvironmentID: env.ID,
+			Pattern:       params.Pattern,
+			Readers:       readers,
+			Writers:       writers,
+			CreatedBy:     params.AuthorID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save access rule: %w", err)
+		}
+		return s.audit(ctx, q, env, params.AuthorID, auditACLSet, map[string]any{
+			"pattern": stored.Pattern,
+			"readers": stored.Readers,
+			"writers": stored.Writers,
+		})
+	})
+	if err != nil {
+		return ACL{}, err
+	}
+	return toACL(stored, env, env.ID), nil
+}
+
+// DeleteACL removes an access rule defined by the environment.
+func (s *svc) DeleteACL(ctx context.Context, envID, id, userID pgtype.UUID) error {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	return s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, env.ID); err != nil {
+			return err
+		}
+		rule, err := q.DeleteVariableACL(ctx, repo.DeleteVariableACLParams{
+			ID:            id,
+			EnvironmentID: env.ID,
+		})
+		if err == pgx.ErrNoRows {
+			return ErrACLNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete access rule: %w", err)
+		}
+		return s.audit(ctx, q, env, userID, auditACLDelete, map[string]any{
+			"pattern": rule.Pattern,
+		})
+	})
+}
+
+// KeyAccess returns what userID may do with the variables of the environment
+// under the access rules that apply to it.
+func (s *svc) KeyAccess(ctx context.Context, envID, userID pgtype.UUID) (KeyAccess, error) {
+	rules, err := s.ListACLs(ctx, envID)
+	if err != nil {
+		return KeyAccess{}, err
+	}
+	if len(rules) == 0 {
+		return KeyAccess{}, nil
+	}
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return KeyAccess{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	roles, err := s.roles(ctx, env, userID)
+	if err != nil {
+		return KeyAccess{}, err
+	}
+	return KeyAccess{rules: rules, userID: userID, roles: roles}, nil
+}
+
+// roles returns the role names userID holds for env: the role in the
+// organization, where an owner also counts as admin, and the role in the
+// project.
+func (s *svc) roles(ctx context.Context, env repo.Environment, userID pgtype.UUID) ([]string, error) {
+	project, err := s.repo.GetProject(ctx, env.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch project: %w", err)
+	}
+
+	var roles []string
+	member, err := s.repo.GetOrganizationMember(ctx, repo.GetOrganizationMemberParams{
+		OrganizationID: project.OrganizationID,
+		UserID:         userID,
+	})
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to check membership: %w", err)
+	}
+	if err == nil {
+		roles = append(roles, member.Role)
+		if member.Role == "owner" {
+			roles = append(roles, "admin")
+		}
+	}
+
+	projectMember, err := s.repo.GetProjectMember(ctx, repo.GetProjectMemberParams{
+		ProjectID: env.ProjectID,
+		UserID:    userID,
+	})
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to check project membership: %w", err)
+	}
+	if err == nil && !slices.Contains(roles, projectMember.Role) {
+		roles = append(roles, projectMember.Role)
+	}
+	return roles, nil
+}
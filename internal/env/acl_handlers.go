@@ -0,0 +1,174 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/internal/auth"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// aclID parses the aclid URL parameter. On failure the error response has
+// already been written and ok is false.
+func aclID(w http.ResponseWriter, r *http.Request) (id pgtype.UUID, ok bool) {
+	if err := id.Scan(chi.URLParam(r, "aclid")); err != nil {
+		http.Error(w, "invalid access rule id format", http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+// keyAccess returns what userID may do with the variables of env. On failure
+// the error response has already been written and ok is false.
+func (h *handler) keyAccess(w http.ResponseWriter, r *http.Request, env repo.Environment, userID pgtype.UUID) (access KeyAccess, ok bool) {
+	access, err := h.envKeyAccess(r.Context(), env, userID)
+	if err != nil {
+		writeVariableError(w, err)
+		return access, false
+	}
+	return access, true
+}
+
+// envKeyAccess returns what userID may do with the variables of env, hiding
+// those inherited from ancestors userID has no read access to.
+func (h *handler) envKeyAccess(ctx context.Context, env repo.Environment, userID pgtype.UUID) (KeyAccess, error) {
+	access, err := h.service.KeyAccess(ctx, env.ID, userID)
+	if err != nil {
+		return KeyAccess{}, err
+	}
+	if !env.ParentID.Valid {
+		return access, nil
+	}
+	ancestors, err := h.service.Ancestors(ctx, env.ID)
+	if err != nil {
+		return KeyAccess{}, err
+	}
+	var hidden []string
+	for _, a := range ancestors {
+		if h.checkEnvAccess(ctx, userID, a, auth.AccessRead) != nil {
+			hidden = append(hidden, a.Slug)
+		}
+	}
+	return access.HideLayers(hidden), nil
+}
+
+// checkKey checks that userID may read key in env or, if write is set, change
+// it. On failure the error response has already been written and false is
+// returned.
+func (h *handler) checkKey(w http.ResponseWriter, r *http.Request, env repo.Environment, userID pgtype.UUID, key string, write bool) bool {
+	access, ok := h.keyAccess(w, r, env, userID)
+	if !ok {
+		return false
+	}
+	if (write && !access.CanWrite(key)) || !access.CanRead(key) {
+		http.Error(w, ErrRestricted.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// checkKeys checks that userID may read every key in keys in env or, if write
+// is set, change it. On failure the error response has already been written
+// and false is returned.
+func (h *handler) checkKeys(w http.ResponseWriter, r *http.Request, env repo.Environment, userID pgtype.UUID, keys []string, write bool) bool {
+	access, ok := h.keyAccess(w, r, env, userID)
+	if !ok {
+		return false
+	}
+	allowed := access.CanRead
+	if write {
+		allowed = func(key string) bool { return access.CanRead(key) && access.CanWrite(key) }
+	}
+	if err := checkKeys(keys, allowed); err != nil {
+		writeVariableError(w, err)
+		return false
+	}
+	return true
+}
+
+// keysAccess applies the access rules of every environment read while
+// resolving or exporting variables to userID.
+func (h *handler) keysAccess(userID pgtype.UUID) KeyAccessFunc {
+	return func(ctx context.Context, env repo.Environment) (KeyAccess, error) {
+		return h.envKeyAccess(ctx, env, userID)
+	}
+}
+
+// ListACLs returns the access rules that apply to the environment, including
+// those inherited from its ancestors.
+func (h *handler) ListACLs(w http.ResponseWriter, r *http.Request) {
+	env, _, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	acls, err := h.service.ListACLs(r.Context(), env.ID)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, acls)
+}
+
+// SetACL creates or replaces the access rule for a key pattern. Only owners and
+// admins of the organization can change access rules.
+func (h *handler) SetACL(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanRestrictVariables(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var params SetACLParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.AuthorID = userID
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	acl, err := h.service.SetACL(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, acl)
+}
+
+func (h *handler) DeleteACL(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	id, ok := aclID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanRestrictVariables(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteACL(r.Context(), env.ID, id, userID); err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, map[string]string{"message": "access rule deleted"})
+}
@@ -0,0 +1,201 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+func TestMatchKey(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{pattern: "API_KEY", key: "API_KEY", want: true},
+		{pattern: "API_KEY", key: "API_KEYS", want: false},
+		{pattern: "API_KEY", key: "api_key", want: false},
+		{pattern: "STRIPE_*", key: "STRIPE_SECRET", want: true},
+		{pattern: "STRIPE_*", key: "STRIPE_", want: true},
+		{pattern: "STRIPE_*", key: "OLD_STRIPE_SECRET", want: false},
+		{pattern: "*_TOKEN", key: "GITHUB_TOKEN", want: true},
+		{pattern: "DB_?", key: "DB_1", want: true},
+		{pattern: "DB_?", key: "DB_10", want: false},
+		{pattern: "DB_[0-9]", key: "DB_7", want: true},
+		{pattern: "DB_[0-9]", key: "DB_X", want: false},
+		{pattern: "*", key: "ANYTHING", want: true},
+		{pattern: "DB_[", key: "DB_[", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.key, func(t *testing.T) {
+			if got := matchKey(tt.pattern, tt.key); got != tt.want {
+				t.Errorf("matchKey(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyAccess(t *testing.T) {
+	alice := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	bob := pgtype.UUID{Bytes: [16]byte{2}, Valid: true}
+	aliceID := "user:01000000-0000-0000-0000-000000000000"
+	rules := []ACL{
+		{Pattern: "STRIPE_*", Readers: []string{"role:developer"}, Writers: []string{"role:admin"}},
+		{Pattern: "STRIPE_WEBHOOK_*", Writers: []string{aliceID}},
+	}
+
+	tests := []struct {
+		name     string
+		access   KeyAccess
+		key      string
+		canRead  bool
+		canWrite bool
+	}{
+		{name: "unrestricted key", access: KeyAccess{rules: rules, userID: alice}, key: "PORT", canRead: true, canWrite: true},
+		{name: "no rules", access: KeyAccess{}, key: "STRIPE_SECRET", canRead: true, canWrite: true},
+		{name: "not named", access: KeyAccess{rules: rules, userID: alice}, key: "STRIPE_SECRET", canRead: false, canWrite: false},
+		{name: "reader role", access: KeyAccess{rules: rules, userID: alice, roles: []string{"developer"}}, key: "STRIPE_SECRET", canRead: true, canWrite: false},
+		{name: "writer role", access: KeyAccess{rules: rules, userID: alice, roles: []string{"admin"}}, key: "STRIPE_SECRET", canRead: true, canWrite: true},
+		{name: "every matching rule applies", access: KeyAccess{rules: rules, userID: bob, roles: []string{"admin"}}, key: "STRIPE_WEBHOOK_SECRET", canRead: false, canWrite: false},
+		{name: "named by every matching rule", access: KeyAccess{rules: rules, userID: alice, roles: []string{"developer"}}, key: "STRIPE_WEBHOOK_SECRET", canRead: true, canWrite: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.access.CanRead(tt.key); got != tt.canRead {
+				t.Errorf("CanRead(%q) = %v, want %v", tt.key, got, tt.canRead)
+			}
+			if got := tt.access.CanWrite(tt.key); got != tt.canWrite {
+				t.Errorf("CanWrite(%q) = %v, want %v", tt.key, got, tt.canWrite)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	access := KeyAccess{rules: []ACL{{Pattern: "SECRET_*", Readers: []string{"role:admin"}}}}
+	vars := []Variable{
+		{Key: "PORT", Value: "8080"},
+		{Key: "SECRET_TOKEN", Value: "s3cr3t", IsSecret: true, Source: "prod"},
+	}
+
+	got := access.Redact(vars)
+	if got[0].Value != "8080" || got[0].Restricted {
+		t.Errorf("Redact() changed readable variable: %+v", got[0])
+	}
+	if got[1].Value != "" || !got[1].Restricted || !got[1].IsSecret || got[1].Source != "prod" {
+		t.Errorf("Redact() = %+v, want a restricted placeholder", got[1])
+	}
+	if vars[1].Value != "s3cr3t" {
+		t.Error("Redact() modified its argument")
+	}
+}
+
+func TestRedactHiddenLayers(t *testing.T) {
+	access := KeyAccess{}.HideLayers([]string{"base"})
+	vars := []Variable{
+		{Key: "PORT", Value: "8080", Source: "base", Inherited: true},
+		{Key: "HOST", Value: "localhost", Source: "dev"},
+		{Key: "LOG_LEVEL", Value: "debug", Source: "shared", Inherited: true},
+	}
+
+	got := access.Redact(vars)
+	if !got[0].Restricted || got[0].Value != "" {
+		t.Errorf("Redact() of a variable from a hidden layer = %+v, want a restricted placeholder", got[0])
+	}
+	for _, v := range got[1:] {
+		if v.Restricted {
+			t.Errorf("Redact() restricted %s from a readable layer", v.Key)
+		}
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+	allowed := func(key string) bool { return key != "B" && key != "D" }
+
+	if err := checkKeys([]string{"A", "C"}, allowed); err != nil {
+		t.Errorf("checkKeys() error = %v, want nil", err)
+	}
+	err := checkKeys([]string{"A", "B", "C", "D"}, allowed)
+	if !errors.Is(err, ErrRestricted) {
+		t.Fatalf("checkKeys() error = %v, want %v", err, ErrRestricted)
+	}
+	if want := ErrRestricted.Error() + ": B, D"; err.Error() != want {
+		t.Errorf("checkKeys() error = %q, want %q", err, want)
+	}
+}
+
+// principal returns the principal naming userID in an access rule.
+func principal(userID pgtype.UUID) string {
+	value, _ := userID.Value()
+	return principalUser + value.(string)
+}
+
+func TestKeyAccessEnforced(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	alice := f.newUser(t, "alice")
+	if _, err := f.q.AddProjectMember(ctx, repo.AddProjectMemberParams{
+		ProjectID: f.project.ID,
+		UserID:    alice,
+		Role:      "developer",
+	}); err != nil {
+		t.Fatalf("failed to add project member: %v", err)
+	}
+	if _, err := f.s.SetACL(ctx, SetACLParams{
+		EnvironmentID: f.env.ID,
+		Pattern:       "STRIPE_*",
+		Readers:       []string{"role:developer"},
+		Writers:       []string{principal(f.user)},
+		AuthorID:      f.user,
+	}); err != nil {
+		t.Fatalf("SetACL() error = %v", err)
+	}
+
+	access, err := f.s.KeyAccess(ctx, f.env.ID, alice)
+	if err != nil {
+		t.Fatalf("KeyAccess() error = %v", err)
+	}
+	if !access.CanRead("STRIPE_SECRET") || access.CanWrite("STRIPE_SECRET") {
+		t.Errorf("developer access to STRIPE_SECRET = read %v, write %v, want read only", access.CanRead("STRIPE_SECRET"), access.CanWrite("STRIPE_SECRET"))
+	}
+
+	// A child environment inherits the rule.
+	child := f.newEnv(t, "preview")
+	if _, err := f.s.SetParent(ctx, child.ID, f.env.ID); err != nil {
+		t.Fatalf("SetParent() error = %v", err)
+	}
+	access, err = f.s.KeyAccess(ctx, child.ID, alice)
+	if err != nil {
+		t.Fatalf("KeyAccess() error = %v", err)
+	}
+	if access.CanWrite("STRIPE_SECRET") {
+		t.Error("CanWrite(STRIPE_SECRET) in a child environment = true, want false")
+	}
+}
+
+func TestApplyChangeRequestChecksAuthorAccess(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	alice := f.newUser(t, "alice")
+	cr := f.propose(t, 1, "STRIPE_SECRET", "sk_live")
+
+	// The author loses write access before the request is approved.
+	if _, err := f.s.SetACL(ctx, SetACLParams{
+		EnvironmentID: f.env.ID,
+		Pattern:       "STRIPE_*",
+		Writers:       []string{principal(alice)},
+		AuthorID:      f.user,
+	}); err != nil {
+		t.Fatalf("SetACL() error = %v", err)
+	}
+	if _, err := f.approve(cr, alice); !errors.Is(err, ErrRestricted) {
+		t.Fatalf("ApproveChangeRequest() error = %v, want %v", err, ErrRestricted)
+	}
+	if got := f.values(t, f.env.ID); len(got) != 0 {
+		t.Errorf("variables after the rejected approval = %v, want none", got)
+	}
+}
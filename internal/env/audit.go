@@ -0,0 +1,60 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+// Actions recorded in audit_logs by the env service.
+const (
+	auditACLDelete        = "acl.delete"
+	auditACLSet           = "acl.set"
+	auditChangeApply      = "env.change_apply"
+	auditChangeReject     = "env.change_reject"
+	auditCredentialIssue  = "credential.issue"
+	auditCredentialRevoke = "credential.revoke"
+	auditDatabaseCreate   = "database.create"
+	auditDatabaseDelete   = "database.delete"
+	auditExpire           = "env.expire"
+	auditLock             = "env.lock"
+	auditLockOverride     = "env.lock_override"
+	auditMetadataSet      = "metadata.set"
+	auditPromote          = "env.promote"
+	auditProtect          = "env.protect"
+	auditReveal           = "variable.reveal"
+	auditRoleAccess       = "env.role_access"
+	auditRotationDelete   = "rotation.delete"
+	auditRotationSet      = "rotation.set"
+	auditUnlock           = "env.unlock"
+	auditUnprotect        = "env.unprotect"
+)
+
+// audit records an action on env in audit_logs through q, so that it is only
+// kept if the surrounding transaction commits.
+func (s *svc) audit(ctx context.Context, q *repo.Queries, env repo.Environment, userID pgtype.UUID, action string, details any) error {
+	project, err := q.GetProject(ctx, env.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch project: %w", err)
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	if _, err := q.CreateAuditLog(ctx, repo.CreateAuditLogParams{
+		UserID:         userID,
+		OrganizationID: project.OrganizationID,
+		Action:         action,
+		ResourceType:   "environment",
+		ResourceID:     env.ID,
+		Details:        data,
+	}); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
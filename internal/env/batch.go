@@ -0,0 +1,209 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/pkg/secretgen"
+)
+
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// maxBatchOperations limits the operations of a single batch.
+const maxBatchOperations = 500
+
+// BatchParams lists the operations of a batch. Each one sets a key like
+// SetVariable, or deletes it like DeleteVariable when Delete is set; a key can
+// only appear once.
+type BatchParams struct {
+	EnvironmentID pgtype.UUID      `json:"-"`
+	Operations    []ProposedChange `json:"operations"`
+	AuthorID      pgtype.UUID      `json:"-"`
+	Message       string           `json:"message"`
+}
+
+// BatchResult is the outcome of one operation of a batch. Variable is the
+// variable an applied set produced, and Error why the operation was rejected.
+type BatchResult struct {
+	Index    int       `json:"index"`
+	Key      string    `json:"key"`
+	Action   string    `json:"action"`
+	Variable *Variable `json:"variable,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Batch is the outcome of a batch that was applied, one result per operation
+// in the order they were given.
+type Batch struct {
+	Results []BatchResult `json:"results"`
+}
+
+// BatchError is returned when an operation of a batch fails. Nothing was
+// applied; Results holds the error of every operation that failed.
+type BatchError struct {
+	Results []BatchResult
+}
+
+func (e *BatchError) Error() string {
+	failed := 0
+	for _, r := range e.Results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%s: %d of %d operations failed", ErrInvalidBatch, failed, len(e.Results))
+}
+
+func (e *BatchError) Unwrap() error {
+	return ErrInvalidBatch
+}
+
+func batchAction(op ProposedChange) string {
+	switch {
+	case op.Delete && op.Inherit:
+		return proposeInherit
+	case op.Delete:
+		return proposeDelete
+	default:
+		return proposeSet
+	}
+}
+
+// ApplyBatch validates every operation and then applies them all in a single
+// transaction. If any operation fails, nothing is applied and a *BatchError
+// reports the failures; schema violations of the batch as a whole are returned
+// as a *SchemaError.
+func (s *svc) ApplyBatch(ctx context.Context, params BatchParams) (Batch, error) {
+	if len(params.Operations) == 0 {
+		return Batch{}, fmt.Errorf("%w: no operations", ErrInvalidBatch)
+	}
+	if len(params.Operations) > maxBatchOperations {
+		return Batch{}, fmt.Errorf("%w: at most %d operations", ErrInvalidBatch, maxBatchOperations)
+	}
+	if err := s.checkUnprotected(ctx, params.EnvironmentID); err != nil {
+		return Batch{}, err
+	}
+
+	env, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	current, err := s.ListVariables(ctx, env.ID, false)
+	if err != nil {
+		return Batch{}, err
+	}
+	exists := make(map[string]bool, len(current))
+	for _, v := range current {
+		exists[v.Key] = true
+	}
+	inherited, err := s.inheritedKeys(ctx, env)
+	if err != nil {
+		return Batch{}, err
+	}
+
+	results := make([]BatchResult, len(params.Operations))
+	changes := make([]change, len(params.Operations))
+	generated := make([]secretgen.Result, len(params.Operations))
+	seen := make(map[string]bool, len(params.Operations))
+	var masked []string
+	failed := false
+	for i, op := range params.Operations {
+		results[i] = BatchResult{Index: i, Key: op.Key, Action: batchAction(op)}
+		changes[i] = change{
+			Key:      op.Key,
+			Value:    op.Value,
+			IsSecret: op.IsSecret,
+			Delete:   op.Delete,
+			AuthorID: params.AuthorID,
+			Message:  params.Message,
+		}
+
+		var err error
+		switch {
+		case validateKey(op.Key) != nil:
+			err = ErrInvalidKey
+		case seen[op.Key]:
+			err = fmt.Errorf("%w: %s is changed more than once", ErrInvalidBatch, op.Key)
+		case op.Inherit && !op.Delete:
+			err = fmt.Errorf("%w: inherit is only valid with delete", ErrInvalidBatch)
+		case op.Delete && (op.Value != "" || op.Generate != nil):
+			err = fmt.Errorf("%w: %s is deleted and set", ErrInvalidBatch, op.Key)
+		case op.Delete && !op.Inherit && !exists[op.Key]:
+			err = ErrVariableNotFound
+		case op.Generate != nil && op.Value != "":
+			err = fmt.Errorf("%w: value and generate are mutually exclusive", secretgen.ErrInvalidSpec)
+		case op.Generate != nil:
+			generated[i], err = generate(&changes[i], op.Generate)
+		}
+		seen[op.Key] = true
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		if op.Delete && !op.Inherit && inherited[op.Key] {
+			masked = append(masked, op.Key)
+		}
+	}
+	if failed {
+		return Batch{}, &BatchError{Results: results}
+	}
+	if err := s.checkChanges(ctx, env.ID, changes, masked); err != nil {
+		return Batch{}, err
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, env.ID)
+	if err != nil {
+		return Batch{}, err
+	}
+
+	vars := make([]repo.Variable, len(params.Operations))
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, params.EnvironmentID); err != nil {
+			return err
+		}
+		for i, op := range params.Operations {
+			var err error
+			if op.Delete {
+				err = s.deleteKey(ctx, q, DeleteVariableParams{
+					EnvironmentID: env.ID,
+					Key:           op.Key,
+					Inherit:       op.Inherit,
+					AuthorID:      params.AuthorID,
+					Message:       params.Message,
+				}, inherited[op.Key])
+			} else if vars[i], err = s.apply(ctx, q, env.ID, dataKey, changes[i]); err == nil {
+				err = saveGenerator(ctx, q, vars[i], op.Generate)
+			}
+			if errors.Is(err, ErrVariableNotFound) {
+				results[i].Error = err.Error()
+				return &BatchError{Results: results}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Batch{}, err
+	}
+
+	for i, op := range params.Operations {
+		if op.Delete {
+			continue
+		}
+		v, err := toVariable(dataKey, vars[i], false)
+		if err != nil {
+			return Batch{}, err
+		}
+		v.Generator = op.Generate
+		v.PublicKey = generated[i].PublicKey
+		results[i].Variable = &v
+	}
+	return Batch{Results: results}, nil
+}
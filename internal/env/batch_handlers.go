@@ -0,0 +1,64 @@
+package env
+
+import (
+	"encoding/json"
+	"net/http"
+
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+)
+
+// ApplyBatch applies a list of set and delete operations to the environment
+// in a single transaction. When any operation fails nothing is applied and
+// the response lists the failures. On a protected environment the operations
+// are proposed together as one change request instead.
+func (h *handler) ApplyBatch(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	var params BatchParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.AuthorID = userID
+
+	access, ok := h.keyAccess(w, r, env, userID)
+	if !ok {
+		return
+	}
+	results := make([]BatchResult, len(params.Operations))
+	restricted := false
+	for i, op := range params.Operations {
+		results[i] = BatchResult{Index: i, Key: op.Key, Action: batchAction(op)}
+		if !access.CanWrite(op.Key) {
+			results[i].Error = ErrRestricted.Error()
+			restricted = true
+		}
+	}
+	if restricted {
+		HTTPwriter.JSON(w, http.StatusForbidden, map[string]any{
+			"error":   ErrRestricted.Error(),
+			"results": results,
+		})
+		return
+	}
+
+	if h.proposeInstead(w, r, env, userID, params.Message, params.Operations...) {
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	batch, err := h.service.ApplyBatch(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, batch)
+}
@@ -0,0 +1,805 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/pkg/secretgen"
+)
+
+var (
+	ErrReviewRequired        = errors.New("environment is protected: changes require an approved change request")
+	ErrNotProtected          = errors.New("environment is not protected")
+	ErrInvalidProtection     = errors.New("invalid protection: required_approvals must be at least 1")
+	ErrInvalidChangeRequest  = errors.New("invalid change request")
+	ErrChangeRequestNotFound = errors.New("change request not found")
+	ErrChangeRequestClosed   = errors.New("change request is no longer pending")
+	ErrChangeRequestStale    = errors.New("change request no longer applies")
+	ErrSelfReview            = errors.New("the author of a change request cannot approve it")
+)
+
+// Change request statuses. A request stays pending until it has collected the
+// required approvals, when it is applied, or until it is rejected.
+const (
+	statusPending  = "pending"
+	statusApplied  = "applied"
+	statusRejected = "rejected"
+)
+
+// Proposed actions stored for each key of a change request.
+const (
+	proposeSet     = "set"
+	proposeDelete  = "delete"
+	proposeInherit = "inherit"
+)
+
+// Review decisions.
+const (
+	decisionApprove = "approve"
+	decisionReject  = "reject"
+)
+
+// Protection makes all variable changes of an environment go through change
+// requests that need RequiredApprovals approvals from users other than the
+// author.
+type Protection struct {
+	RequiredApprovals int32 `json:"required_approvals"`
+}
+
+// ProposedChange is one key of a change request. It sets the key like
+// SetVariable, or deletes it like DeleteVariable when Delete is set.
+type ProposedChange struct {
+	Key      string          `json:"key"`
+	Value    string          `json:"value"`
+	IsSecret bool            `json:"is_secret"`
+	Generate *secretgen.Spec `json:"generate,omitempty"`
+	Delete   bool            `json:"delete,omitempty"`
+	Inherit  bool            `json:"inherit,omitempty"`
+}
+
+type CreateChangeRequestParams struct {
+	EnvironmentID pgtype.UUID      `json:"-"`
+	AuthorID      pgtype.UUID      `json:"-"`
+	Message       string           `json:"message"`
+	Changes       []ProposedChange `json:"changes"`
+}
+
+// ReviewParams approves or rejects a change request, optionally with a
+// comment.
+type ReviewParams struct {
+	EnvironmentID   pgtype.UUID `json:"-"`
+	ChangeRequestID pgtype.UUID `json:"-"`
+	UserID          pgtype.UUID `json:"-"`
+	Comment         string      `json:"comment"`
+}
+
+type CommentParams struct {
+	EnvironmentID   pgtype.UUID `json:"-"`
+	ChangeRequestID pgtype.UUID `json:"-"`
+	AuthorID        pgtype.UUID `json:"-"`
+	Body            string      `json:"body"`
+}
+
+// ChangeRequest is a pending or closed set of changes to a protected
+// environment. Changes, Reviews and Comments are only included when a single
+// request is fetched.
+type ChangeRequest struct {
+	ID                pgtype.UUID        `json:"id"`
+	EnvironmentID     pgtype.UUID        `json:"environment_id"`
+	AuthorID          pgtype.UUID        `json:"author_id"`
+	Message           string             `json:"message,omitempty"`
+	Status            string             `json:"status"`
+	RequiredApprovals int32              `json:"required_approvals"`
+	Changes           []ProposedDiff     `json:"changes,omitempty"`
+	Reviews           []Review           `json:"reviews,omitempty"`
+	Comments          []Comment          `json:"comments,omitempty"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
+// ProposedDiff shows a proposed change against the current effective value of
+// the key. Current is nil for new keys and Proposed is nil when the key would
+// be removed. Secrets are masked the same way as in DiffEnvironments.
+type ProposedDiff struct {
+	Key      string     `json:"key"`
+	Action   string     `json:"action"`
+	Current  *DiffValue `json:"current,omitempty"`
+	Proposed *DiffValue `json:"proposed,omitempty"`
+}
+
+type Review struct {
+	UserID    pgtype.UUID        `json:"user_id"`
+	Decision  string             `json:"decision"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type Comment struct {
+	ID        pgtype.UUID        `json:"id"`
+	AuthorID  pgtype.UUID        `json:"author_id"`
+	Body      string             `json:"body"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+func toChangeRequest(cr repo.ChangeRequest) ChangeRequest {
+	return ChangeRequest{
+		ID:                cr.ID,
+		EnvironmentID:     cr.EnvironmentID,
+		AuthorID:          cr.AuthorID,
+		Message:           cr.Message.String,
+		Status:            cr.Status,
+		RequiredApprovals: cr.RequiredApprovals,
+		CreatedAt:         cr.CreatedAt,
+		UpdatedAt:         cr.UpdatedAt,
+	}
+}
+
+func toComment(c repo.ChangeRequestComment) Comment {
+	return Comment{
+		ID:        c.ID,
+		AuthorID:  c.AuthorID,
+		Body:      c.Body,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// GetProtection returns the protection of the environment, or nil.
+func (s *svc) GetProtection(ctx context.Context, envID pgtype.UUID) (*Protection, error) {
+	stored, err := s.repo.GetEnvironmentProtection(ctx, envID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch protection: %w", err)
+	}
+	return &Protection{RequiredApprovals: stored.RequiredApprovals}, nil
+}
+
+// checkUnprotected fails with ErrReviewRequired if variable changes of the
+// environment have to go through change requests.
+func (s *svc) checkUnprotected(ctx context.Context, envID pgtype.UUID) error {
+	protection, err := s.GetProtection(ctx, envID)
+	if err != nil {
+		return err
+	}
+	if protection != nil {
+		return ErrReviewRequired
+	}
+	return nil
+}
+
+// SetProtection protects the environment or changes the number of approvals
+// it requires. Pending change requests keep the number they were created with.
+func (s *svc) SetProtection(ctx context.Context, envID, userID pgtype.UUID, protection Protection) (Protection, error) {
+	if protection.RequiredApprovals < 1 {
+		return Protection{}, ErrInvalidProtection
+	}
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return Protection{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	var stored repo.EnvironmentProtection
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, envID); err != nil {
+			return err
+		}
+		var err error
+		stored, err = q.UpsertEnvironmentProtection(ctx, repo.UpsertEnvironmentProtectionParams{
+			EnvironmentID:     envID,
+			RequiredApprovals: protection.RequiredApprovals,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to protect environment: %w", err)
+		}
+		return s.audit(ctx, q, env, userID, auditProtect, protection)
+	})
+	if err != nil {
+		return Protection{}, err
+	}
+	return Protection{RequiredApprovals: stored.RequiredApprovals}, nil
+}
+
+// DeleteProtection allows direct changes to the environment again. Pending
+// change requests can still be reviewed.
+func (s *svc) DeleteProtection(ctx context.Context, envID, userID pgtype.UUID) error {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	protection, err := s.GetProtection(ctx, envID)
+	if err != nil {
+		return err
+	}
+	if protection == nil {
+		return ErrNotProtected
+	}
+
+	return s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, envID); err != nil {
+			return err
+		}
+		if err := q.DeleteEnvironmentProtection(ctx, envID); err != nil {
+			return fmt.Errorf("failed to unprotect environment: %w", err)
+		}
+		return s.audit(ctx, q, env, userID, auditUnprotect, protection)
+	})
+}
+
+// CreateChangeRequest records proposed changes to a protected environment.
+// Generated values are generated now so that reviewers approve the exact
+// value that will be written. The changes are checked against the project
+// schema now and again when they are applied.
+func (s *svc) CreateChangeRequest(ctx context.Context, params CreateChangeRequestParams) (ChangeRequest, error) {
+	if len(params.Changes) == 0 {
+		return ChangeRequest{}, fmt.Errorf("%w: no changes", ErrInvalidChangeRequest)
+	}
+	protection, err := s.GetProtection(ctx, params.EnvironmentID)
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	if protection == nil {
+		return ChangeRequest{}, ErrNotProtected
+	}
+	env, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return ChangeRequest{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	current, err := s.ListVariables(ctx, env.ID, false)
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	exists := make(map[string]bool, len(current))
+	for _, v := range current {
+		exists[v.Key] = true
+	}
+	// Each change is proposed against the current version of the local
+	// variable, or its absence, and goes stale when that changes.
+	local, err := s.repo.ListVariables(ctx, env.ID)
+	if err != nil {
+		return ChangeRequest{}, fmt.Errorf("failed to list variables: %w", err)
+	}
+	bases := make(map[string]string, len(local))
+	for _, v := range local {
+		bases[v.Key] = etag(v.ID, v.Version)
+	}
+
+	seen := make(map[string]bool, len(params.Changes))
+	for i := range params.Changes {
+		p := &params.Changes[i]
+		if err := validateKey(p.Key); err != nil {
+			return ChangeRequest{}, err
+		}
+		if seen[p.Key] {
+			return ChangeRequest{}, fmt.Errorf("%w: %s is changed more than once", ErrInvalidChangeRequest, p.Key)
+		}
+		seen[p.Key] = true
+		switch {
+		case p.Inherit && !p.Delete:
+			return ChangeRequest{}, fmt.Errorf("%w: inherit is only valid with delete", ErrInvalidChangeRequest)
+		case p.Delete && (p.Value != "" || p.Generate != nil):
+			return ChangeRequest{}, fmt.Errorf("%w: %s is deleted and set", ErrInvalidChangeRequest, p.Key)
+		case p.Delete && !p.Inherit && !exists[p.Key]:
+			return ChangeRequest{}, fmt.Errorf("%w: %s", ErrVariableNotFound, p.Key)
+		case p.Generate != nil && p.Value != "":
+			return ChangeRequest{}, fmt.Errorf("%w: value and generate are mutually exclusive", secretgen.ErrInvalidSpec)
+		}
+	}
+
+	var stored []repo.CreateChangeRequestVariableParams
+	var changes []change
+	for _, p := range params.Changes {
+		if p.Delete {
+			action := proposeDelete
+			if p.Inherit {
+				action = proposeInherit
+			}
+			stored = append(stored, repo.CreateChangeRequestVariableParams{
+				Key:    p.Key,
+				Action: action,
+				Base:   pgtype.Text{String: bases[p.Key], Valid: true},
+			})
+			continue
+		}
+		c := change{Key: p.Key, Value: p.Value, IsSecret: p.IsSecret}
+		var generator []byte
+		if p.Generate != nil {
+			if _, err := generate(&c, p.Generate); err != nil {
+				return ChangeRequest{}, err
+			}
+			if generator, err = json.Marshal(p.Generate); err != nil {
+				return ChangeRequest{}, err
+			}
+		}
+		changes = append(changes, c)
+		stored = append(stored, repo.CreateChangeRequestVariableParams{
+			Key:       p.Key,
+			Action:    proposeSet,
+			Value:     c.Value,
+			IsSecret:  c.IsSecret,
+			Generator: generator,
+			Base:      pgtype.Text{String: bases[p.Key], Valid: true},
+		})
+	}
+	inherited, err := s.inheritedKeys(ctx, env)
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	checks, masked := proposedChecks(stored, inherited)
+	if err := s.checkChanges(ctx, env.ID, checks, masked); err != nil {
+		return ChangeRequest{}, err
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, env.ID)
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	var cr repo.ChangeRequest
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		var err error
+		cr, err = q.CreateChangeRequest(ctx, repo.CreateChangeRequestParams{
+			EnvironmentID:     env.ID,
+			AuthorID:          params.AuthorID,
+			Message:           pgtype.Text{String: params.Message, Valid: params.Message != ""},
+			RequiredApprovals: protection.RequiredApprovals,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create change request: %w", err)
+		}
+		for _, v := range stored {
+			v.ChangeRequestID = cr.ID
+			if v.Action == proposeSet {
+				if v.Value, err = sealValue(dataKey, env.ID, v.Key, v.Value); err != nil {
+					return fmt.Errorf("failed to encrypt variable: %w", err)
+				}
+				v.Encrypted = true
+			}
+			if err := q.CreateChangeRequestVariable(ctx, v); err != nil {
+				return fmt.Errorf("failed to save proposed change: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	return s.GetChangeRequest(ctx, env.ID, cr.ID, false)
+}
+
+// proposedChecks turns proposed changes into the arguments of checkChanges.
+// Values of set actions must already be decrypted and inherited are the keys
+// provided by ancestors of the environment.
+func proposedChecks(proposed []repo.CreateChangeRequestVariableParams, inherited map[string]bool) ([]change, []string) {
+	var checks []change
+	var masked []string
+	for _, p := range proposed {
+		switch p.Action {
+		case proposeSet:
+			checks = append(checks, change{Key: p.Key, Value: p.Value, IsSecret: p.IsSecret})
+		case proposeDelete:
+			checks = append(checks, change{Key: p.Key, Delete: true})
+			if inherited[p.Key] {
+				masked = append(masked, p.Key)
+			}
+		case proposeInherit:
+			checks = append(checks, change{Key: p.Key, Delete: true})
+		}
+	}
+	return checks, masked
+}
+
+// changeRequest returns the change request if it belongs to the environment.
+func (s *svc) changeRequest(ctx context.Context, envID, id pgtype.UUID) (repo.ChangeRequest, error) {
+	cr, err := s.repo.GetChangeRequest(ctx, id)
+	if err == pgx.ErrNoRows || (err == nil && cr.EnvironmentID != envID) {
+		return repo.ChangeRequest{}, ErrChangeRequestNotFound
+	}
+	if err != nil {
+		return repo.ChangeRequest{}, fmt.Errorf("failed to fetch change request: %w", err)
+	}
+	return cr, nil
+}
+
+// proposed returns the proposed changes of a change request with the values
+// of set actions decrypted.
+func (s *svc) proposed(ctx context.Context, q *repo.Queries, cr repo.ChangeRequest) ([]repo.CreateChangeRequestVariableParams, error) {
+	vars, err := q.ListChangeRequestVariables(ctx, cr.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proposed changes: %w", err)
+	}
+	dataKey, err := s.keys.dataKey(ctx, q, cr.EnvironmentID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo.CreateChangeRequestVariableParams, 0, len(vars))
+	for _, v := range vars {
+		p := repo.CreateChangeRequestVariableParams{
+			ChangeRequestID: v.ChangeRequestID,
+			Key:             v.Key,
+			Action:          v.Action,
+			IsSecret:        v.IsSecret,
+			Generator:       v.Generator,
+			Base:            v.Base,
+		}
+		if v.Action == proposeSet {
+			if p.Value, err = openValue(dataKey, cr.EnvironmentID, v.Key, v.Value, v.Encrypted); err != nil {
+				return nil, fmt.Errorf("failed to decrypt variable %s: %w", v.Key, err)
+			}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (s *svc) ListChangeRequests(ctx context.Context, envID pgtype.UUID, status string) ([]ChangeRequest, error) {
+	switch status {
+	case "", statusPending, statusApplied, statusRejected:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidChangeRequest, status)
+	}
+	crs, err := s.repo.ListChangeRequests(ctx, repo.ListChangeRequestsParams{
+		EnvironmentID: envID,
+		Status:        pgtype.Text{String: status, Valid: status != ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list change requests: %w", err)
+	}
+	out := make([]ChangeRequest, 0, len(crs))
+	for _, cr := range crs {
+		out = append(out, toChangeRequest(cr))
+	}
+	return out, nil
+}
+
+// GetChangeRequest returns a change request with its diff against the current
+// variables of the environment, its reviews and its comments. Secret values
+// are masked unless reveal is set.
+func (s *svc) GetChangeRequest(ctx context.Context, envID, id pgtype.UUID, reveal bool) (ChangeRequest, error) {
+	cr, err := s.changeRequest(ctx, envID, id)
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return ChangeRequest{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	proposed, err := s.proposed(ctx, s.repo, cr)
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	current, err := s.ListVariables(ctx, envID, true)
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	byKey := make(map[string]Variable, len(current))
+	for _, v := range current {
+		byKey[v.Key] = v
+	}
+	// Deleting a local value with inherit brings back the value of the parent.
+	var parent map[string]Variable
+	if env.ParentID.Valid {
+		vars, err := s.ListVariables(ctx, env.ParentID, true)
+		if err != nil {
+			return ChangeRequest{}, err
+		}
+		parent = make(map[string]Variable, len(vars))
+		for _, v := range vars {
+			parent[v.Key] = v
+		}
+	}
+
+	view, err := diffView(reveal)
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	out := toChangeRequest(cr)
+	out.Changes = make([]ProposedDiff, 0, len(proposed))
+	for _, p := range proposed {
+		d := ProposedDiff{Key: p.Key, Action: p.Action}
+		if v, ok := byKey[p.Key]; ok {
+			d.Current = view(v)
+		}
+		switch p.Action {
+		case proposeSet:
+			d.Proposed = view(Variable{Key: p.Key, Value: p.Value, IsSecret: p.IsSecret})
+		case proposeInherit:
+			if v, ok := parent[p.Key]; ok {
+				d.Proposed = view(v)
+			}
+		}
+		out.Changes = append(out.Changes, d)
+	}
+
+	reviews, err := s.repo.ListChangeRequestReviews(ctx, cr.ID)
+	if err != nil {
+		return ChangeRequest{}, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	out.Reviews = make([]Review, 0, len(reviews))
+	for _, r := range reviews {
+		out.Reviews = append(out.Reviews, Review{UserID: r.UserID, Decision: r.Decision, CreatedAt: r.CreatedAt})
+	}
+	comments, err := s.repo.ListChangeRequestComments(ctx, cr.ID)
+	if err != nil {
+		return ChangeRequest{}, fmt.Errorf("failed to list comments: %w", err)
+	}
+	out.Comments = make([]Comment, 0, len(comments))
+	for _, c := range comments {
+		out.Comments = append(out.Comments, toComment(c))
+	}
+	return out, nil
+}
+
+// revealed returns the secret keys of the change request shown in plaintext.
+func (cr ChangeRequest) revealed() []string {
+	var keys []string
+	for _, d := range cr.Changes {
+		if (d.Current != nil && d.Current.IsSecret && !d.Current.Masked && !d.Current.Restricted) ||
+			(d.Proposed != nil && d.Proposed.IsSecret && !d.Proposed.Masked && !d.Proposed.Restricted) {
+			keys = append(keys, d.Key)
+		}
+	}
+	return keys
+}
+
+// redact replaces the current and proposed values of keys the caller may not
+// read with placeholders.
+func (cr ChangeRequest) redact(access KeyAccess) {
+	for i, d := range cr.Changes {
+		if access.CanRead(d.Key) {
+			continue
+		}
+		if d.Current != nil {
+			cr.Changes[i].Current = &DiffValue{IsSecret: d.Current.IsSecret, Restricted: true}
+		}
+		if d.Proposed != nil {
+			cr.Changes[i].Proposed = &DiffValue{IsSecret: d.Proposed.IsSecret, Restricted: true}
+		}
+	}
+}
+
+// review records the decision of params.UserID and the optional comment
+// through q.
+func review(ctx context.Context, q *repo.Queries, params ReviewParams, decision string) error {
+	if err := q.UpsertChangeRequestReview(ctx, repo.UpsertChangeRequestReviewParams{
+		ChangeRequestID: params.ChangeRequestID,
+		UserID:          params.UserID,
+		Decision:        decision,
+	}); err != nil {
+		return fmt.Errorf("failed to record review: %w", err)
+	}
+	if comment := strings.TrimSpace(params.Comment); comment != "" {
+		if _, err := q.CreateChangeRequestComment(ctx, repo.CreateChangeRequestCommentParams{
+			ChangeRequestID: params.ChangeRequestID,
+			AuthorID:        params.UserID,
+			Body:            comment,
+		}); err != nil {
+			return fmt.Errorf("failed to save comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// ApproveChangeRequest records an approval. The approval that reaches the
+// required number applies the changes in the same transaction, so it fails
+// without being recorded if the changes cannot be applied, for example
+// because the environment is locked or a key was changed since the request
+// was created. The changes are recorded in the version history under the
+// author of the request.
+func (s *svc) ApproveChangeRequest(ctx context.Context, params ReviewParams) (ChangeRequest, error) {
+	cr, err := s.changeRequest(ctx, params.EnvironmentID, params.ChangeRequestID)
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	if cr.AuthorID == params.UserID {
+		return ChangeRequest{}, ErrSelfReview
+	}
+	env, err := s.repo.GetEnvironment(ctx, cr.EnvironmentID)
+	if err != nil {
+		return ChangeRequest{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		// Approvals of the same request are serialized, so that exactly one
+		// of them counts the required number and applies the changes.
+		cr, err := q.LockChangeRequest(ctx, cr.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch change request: %w", err)
+		}
+		if cr.Status != statusPending {
+			return ErrChangeRequestClosed
+		}
+		if err := review(ctx, q, params, decisionApprove); err != nil {
+			return err
+		}
+		reviews, err := q.ListChangeRequestReviews(ctx, cr.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list reviews: %w", err)
+		}
+		var approvers []pgtype.UUID
+		for _, r := range reviews {
+			if r.Decision == decisionApprove && r.UserID != cr.AuthorID {
+				approvers = append(approvers, r.UserID)
+			}
+		}
+		if int32(len(approvers)) < cr.RequiredApprovals {
+			return nil
+		}
+		return s.applyChangeRequest(ctx, q, env, cr, params.UserID, approvers)
+	})
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	return s.GetChangeRequest(ctx, params.EnvironmentID, cr.ID, false)
+}
+
+// applyChangeRequest writes the changes of an approved change request and
+// closes it through q, which must hold the lock of the request. It fails with
+// ErrRestricted if the author can no longer change one of the keys.
+func (s *svc) applyChangeRequest(ctx context.Context, q *repo.Queries, env repo.Environment, cr repo.ChangeRequest, userID pgtype.UUID, approvers []pgtype.UUID) error {
+	if err := s.checkUnlocked(ctx, q, env.ID); err != nil {
+		return err
+	}
+	proposed, err := s.proposed(ctx, q, cr)
+	if err != nil {
+		return err
+	}
+	// The author may have lost write access to some keys since proposing.
+	access, err := s.KeyAccess(ctx, env.ID, cr.AuthorID)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, len(proposed))
+	for i, p := range proposed {
+		keys[i] = p.Key
+	}
+	allowed := func(key string) bool { return access.CanRead(key) && access.CanWrite(key) }
+	if err := checkKeys(keys, allowed); err != nil {
+		return err
+	}
+	inherited, err := s.inheritedKeys(ctx, env)
+	if err != nil {
+		return err
+	}
+	checks, masked := proposedChecks(proposed, inherited)
+	if err := s.checkChanges(ctx, env.ID, checks, masked); err != nil {
+		return err
+	}
+	dataKey, err := s.keys.dataKey(ctx, q, env.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range proposed {
+		if err := s.applyProposed(ctx, q, cr, dataKey, p, inherited[p.Key]); err != nil {
+			return err
+		}
+	}
+	closed, err := q.CloseChangeRequest(ctx, repo.CloseChangeRequestParams{ID: cr.ID, Status: statusApplied})
+	if err != nil {
+		return fmt.Errorf("failed to close change request: %w", err)
+	}
+	if closed == 0 {
+		return ErrChangeRequestClosed
+	}
+	return s.audit(ctx, q, env, userID, auditChangeApply, map[string]any{
+		"change_request_id": cr.ID,
+		"author_id":         cr.AuthorID,
+		"approvers":         approvers,
+	})
+}
+
+// applyProposed writes one proposed change through q. It fails with
+// ErrChangeRequestStale if the local variable was changed since the change was
+// proposed; changes proposed before bases were recorded are applied as is.
+func (s *svc) applyProposed(ctx context.Context, q *repo.Queries, cr repo.ChangeRequest, dataKey []byte, p repo.CreateChangeRequestVariableParams, inherited bool) error {
+	if p.Base.Valid {
+		current, err := q.LockVariable(ctx, repo.LockVariableParams{
+			EnvironmentID: cr.EnvironmentID,
+			Key:           p.Key,
+		})
+		var tag string
+		switch {
+		case err == nil:
+			tag = etag(current.ID, current.Version)
+		case err != pgx.ErrNoRows:
+			return fmt.Errorf("failed to fetch variable: %w", err)
+		}
+		if tag != p.Base.String {
+			return fmt.Errorf("%w: %s was changed since the change request was created", ErrChangeRequestStale, p.Key)
+		}
+	}
+
+	if p.Action != proposeSet {
+		err := s.deleteKey(ctx, q, DeleteVariableParams{
+			EnvironmentID: cr.EnvironmentID,
+			Key:           p.Key,
+			Inherit:       p.Action == proposeInherit,
+			AuthorID:      cr.AuthorID,
+			Message:       cr.Message.String,
+		}, inherited)
+		if errors.Is(err, ErrVariableNotFound) {
+			return fmt.Errorf("%w: %s was removed since the change request was created", ErrChangeRequestStale, p.Key)
+		}
+		return err
+	}
+
+	v, err := s.apply(ctx, q, cr.EnvironmentID, dataKey, change{
+		Key:      p.Key,
+		Value:    p.Value,
+		IsSecret: p.IsSecret,
+		AuthorID: cr.AuthorID,
+		Message:  cr.Message.String,
+	})
+	if err != nil {
+		return err
+	}
+	var spec *secretgen.Spec
+	if p.Generator != nil {
+		spec = new(secretgen.Spec)
+		if err := json.Unmarshal(p.Generator, spec); err != nil {
+			return fmt.Errorf("failed to decode generator: %w", err)
+		}
+	}
+	return saveGenerator(ctx, q, v, spec)
+}
+
+// RejectChangeRequest closes a pending change request without applying it.
+func (s *svc) RejectChangeRequest(ctx context.Context, params ReviewParams) (ChangeRequest, error) {
+	cr, err := s.changeRequest(ctx, params.EnvironmentID, params.ChangeRequestID)
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	env, err := s.repo.GetEnvironment(ctx, cr.EnvironmentID)
+	if err != nil {
+		return ChangeRequest{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		closed, err := q.CloseChangeRequest(ctx, repo.CloseChangeRequestParams{ID: cr.ID, Status: statusRejected})
+		if err != nil {
+			return fmt.Errorf("failed to close change request: %w", err)
+		}
+		if closed == 0 {
+			return ErrChangeRequestClosed
+		}
+		if err := review(ctx, q, params, decisionReject); err != nil {
+			return err
+		}
+		return s.audit(ctx, q, env, params.UserID, auditChangeReject, map[string]any{
+			"change_request_id": cr.ID,
+			"author_id":         cr.AuthorID,
+		})
+	})
+	if err != nil {
+		return ChangeRequest{}, err
+	}
+	return s.GetChangeRequest(ctx, params.EnvironmentID, cr.ID, false)
+}
+
+// CommentChangeRequest adds a comment to a change request. Closed requests
+// can still be commented on.
+func (s *svc) CommentChangeRequest(ctx context.Context, params CommentParams) (Comment, error) {
+	params.Body = strings.TrimSpace(params.Body)
+	if params.Body == "" {
+		return Comment{}, fmt.Errorf("%w: comment body is empty", ErrInvalidChangeRequest)
+	}
+	cr, err := s.changeRequest(ctx, params.EnvironmentID, params.ChangeRequestID)
+	if err != nil {
+		return Comment{}, err
+	}
+	c, err := s.repo.CreateChangeRequestComment(ctx, repo.CreateChangeRequestCommentParams{
+		ChangeRequestID: cr.ID,
+		AuthorID:        params.AuthorID,
+		Body:            params.Body,
+	})
+	if err != nil {
+		return Comment{}, fmt.Errorf("failed to save comment: %w", err)
+	}
+	return toComment(c), nil
+}
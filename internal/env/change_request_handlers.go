@@ -0,0 +1,284 @@
+package env
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// proposeInstead turns a direct edit of a protected environment into a change
+// request with the proposed changes and responds with 202 Accepted. It reports
+// whether it handled the request.
+func (h *handler) proposeInstead(w http.ResponseWriter, r *http.Request, env repo.Environment, userID pgtype.UUID, message string, proposed ...ProposedChange) bool {
+	protection, err := h.service.GetProtection(r.Context(), env.ID)
+	if err != nil {
+		writeVariableError(w, err)
+		return true
+	}
+	if protection == nil {
+		return false
+	}
+
+	cr, err := h.service.CreateChangeRequest(r.Context(), CreateChangeRequestParams{
+		EnvironmentID: env.ID,
+		AuthorID:      userID,
+		Message:       message,
+		Changes:       proposed,
+	})
+	if err != nil {
+		writeVariableError(w, err)
+		return true
+	}
+	HTTPwriter.JSON(w, http.StatusAccepted, cr)
+	return true
+}
+
+func (h *handler) GetProtection(w http.ResponseWriter, r *http.Request) {
+	env, _, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	protection, err := h.service.GetProtection(r.Context(), env.ID)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	if protection == nil {
+		http.Error(w, ErrNotProtected.Error(), http.StatusNotFound)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, protection)
+}
+
+// SetProtection protects the environment. Only owners and admins of the
+// organization can change protections.
+func (h *handler) SetProtection(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanProtectEnvironment(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var params Protection
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	protection, err := h.service.SetProtection(r.Context(), env.ID, userID, params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, protection)
+}
+
+func (h *handler) DeleteProtection(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanProtectEnvironment(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteProtection(r.Context(), env.ID, userID); err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, map[string]string{"message": "environment unprotected"})
+}
+
+// changeRequestID parses the crid URL parameter. On failure the error
+// response has already been written and ok is false.
+func changeRequestID(w http.ResponseWriter, r *http.Request) (id pgtype.UUID, ok bool) {
+	if err := id.Scan(chi.URLParam(r, "crid")); err != nil {
+		http.Error(w, "invalid change request id format", http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+func (h *handler) CreateChangeRequest(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	var params CreateChangeRequestParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.AuthorID = userID
+
+	keys := make([]string, len(params.Changes))
+	for i, c := range params.Changes {
+		keys[i] = c.Key
+	}
+	if !h.checkKeys(w, r, env, userID, keys, true) {
+		return
+	}
+
+	cr, err := h.service.CreateChangeRequest(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, cr)
+}
+
+// ListChangeRequests lists the change requests of the environment, newest
+// first, optionally filtered by the status query parameter.
+func (h *handler) ListChangeRequests(w http.ResponseWriter, r *http.Request) {
+	env, _, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	crs, err := h.service.ListChangeRequests(r.Context(), env.ID, r.URL.Query().Get("status"))
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, crs)
+}
+
+// GetChangeRequest returns a change request with its diff, reviews and
+// comments. Secrets are masked unless reveal=true is given together with a
+// reason, and values of keys the caller may not read are always redacted.
+func (h *handler) GetChangeRequest(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	id, ok := changeRequestID(w, r)
+	if !ok {
+		return
+	}
+
+	reveal, reason, ok := h.revealRequested(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	access, ok := h.keyAccess(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	cr, err := h.service.GetChangeRequest(r.Context(), env.ID, id, reveal)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	cr.redact(access)
+	if reveal && !h.auditReveal(w, r, env, userID, reason, cr.revealed()) {
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, cr)
+}
+
+// reviewParams checks that the user may review changes of the environment
+// and decodes the optional body of a review.
+func (h *handler) reviewParams(w http.ResponseWriter, r *http.Request) (params ReviewParams, ok bool) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return params, false
+	}
+	id, ok := changeRequestID(w, r)
+	if !ok {
+		return params, false
+	}
+	if err := h.authorizer.CanApproveChanges(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return params, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil && err != io.EOF {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return params, false
+	}
+	params.EnvironmentID = env.ID
+	params.ChangeRequestID = id
+	params.UserID = userID
+	return params, true
+}
+
+// ApproveChangeRequest approves a change request. The changes are applied by
+// the approval that reaches the required number.
+func (h *handler) ApproveChangeRequest(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.reviewParams(w, r)
+	if !ok {
+		return
+	}
+
+	cr, err := h.service.ApproveChangeRequest(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, cr)
+}
+
+func (h *handler) RejectChangeRequest(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.reviewParams(w, r)
+	if !ok {
+		return
+	}
+
+	cr, err := h.service.RejectChangeRequest(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, cr)
+}
+
+func (h *handler) CommentChangeRequest(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	id, ok := changeRequestID(w, r)
+	if !ok {
+		return
+	}
+
+	var params CommentParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.ChangeRequestID = id
+	params.AuthorID = userID
+
+	comment, err := h.service.CommentChangeRequest(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, comment)
+}
@@ -0,0 +1,117 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// propose protects the environment of the fixture and proposes to set key to
+// value as the fixture user.
+func (f *fixture) propose(t *testing.T, approvals int32, key, value string) ChangeRequest {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := f.s.SetProtection(ctx, f.env.ID, f.user, Protection{RequiredApprovals: approvals}); err != nil {
+		t.Fatalf("SetProtection() error = %v", err)
+	}
+	cr, err := f.s.CreateChangeRequest(ctx, CreateChangeRequestParams{
+		EnvironmentID: f.env.ID,
+		AuthorID:      f.user,
+		Changes:       []ProposedChange{{Key: key, Value: value}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChangeRequest() error = %v", err)
+	}
+	return cr
+}
+
+func (f *fixture) approve(cr ChangeRequest, userID pgtype.UUID) (ChangeRequest, error) {
+	return f.s.ApproveChangeRequest(context.Background(), ReviewParams{
+		EnvironmentID:   f.env.ID,
+		ChangeRequestID: cr.ID,
+		UserID:          userID,
+	})
+}
+
+func TestApproveChangeRequest(t *testing.T) {
+	f := newFixture(t)
+	alice, bob := f.newUser(t, "alice"), f.newUser(t, "bob")
+	cr := f.propose(t, 2, "PORT", "8080")
+
+	if _, err := f.approve(cr, f.user); !errors.Is(err, ErrSelfReview) {
+		t.Errorf("ApproveChangeRequest() by the author error = %v, want %v", err, ErrSelfReview)
+	}
+	// Approving twice counts once.
+	for range 2 {
+		got, err := f.approve(cr, alice)
+		if err != nil {
+			t.Fatalf("ApproveChangeRequest() error = %v", err)
+		}
+		if got.Status != statusPending {
+			t.Fatalf("status after one approval = %q, want %q", got.Status, statusPending)
+		}
+	}
+	got, err := f.approve(cr, bob)
+	if err != nil {
+		t.Fatalf("ApproveChangeRequest() error = %v", err)
+	}
+	if got.Status != statusApplied {
+		t.Errorf("status after two approvals = %q, want %q", got.Status, statusApplied)
+	}
+	if got, want := f.values(t, f.env.ID), map[string]string{"PORT": "8080"}; !maps.Equal(got, want) {
+		t.Errorf("variables after approval = %v, want %v", got, want)
+	}
+	if _, err := f.approve(cr, alice); !errors.Is(err, ErrChangeRequestClosed) {
+		t.Errorf("ApproveChangeRequest() of an applied request error = %v, want %v", err, ErrChangeRequestClosed)
+	}
+}
+
+func TestApproveChangeRequestConcurrently(t *testing.T) {
+	f := newFixture(t)
+	reviewers := []pgtype.UUID{f.newUser(t, "alice"), f.newUser(t, "bob")}
+	cr := f.propose(t, 2, "PORT", "8080")
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(reviewers))
+	for i, reviewer := range reviewers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = f.approve(cr, reviewer)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("ApproveChangeRequest() error = %v", err)
+		}
+	}
+	got, err := f.s.GetChangeRequest(context.Background(), f.env.ID, cr.ID, false)
+	if err != nil {
+		t.Fatalf("GetChangeRequest() error = %v", err)
+	}
+	if got.Status != statusApplied {
+		t.Errorf("status after concurrent approvals = %q, want %q", got.Status, statusApplied)
+	}
+}
+
+func TestApproveStaleChangeRequest(t *testing.T) {
+	f := newFixture(t)
+	reviewer := f.newUser(t, "reviewer")
+	first := f.propose(t, 1, "PORT", "8080")
+	second := f.propose(t, 1, "PORT", "9090")
+
+	if _, err := f.approve(first, reviewer); err != nil {
+		t.Fatalf("ApproveChangeRequest() error = %v", err)
+	}
+	if _, err := f.approve(second, reviewer); !errors.Is(err, ErrChangeRequestStale) {
+		t.Errorf("ApproveChangeRequest() of a stale request error = %v, want %v", err, ErrChangeRequestStale)
+	}
+	if got, want := f.values(t, f.env.ID), map[string]string{"PORT": "8080"}; !maps.Equal(got, want) {
+		t.Errorf("variables = %v, want %v", got, want)
+	}
+}
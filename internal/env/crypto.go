@@ -0,0 +1,165 @@
+package env
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/pkg/encryption"
+)
+
+// keyring hands out the per-environment data keys used to encrypt variable
+// values. Data keys are stored wrapped by the master key and kept unwrapped in
+// memory after first use.
+type keyring struct {
+	provider encryption.KeyProvider
+
+	mu   sync.Mutex
+	keys map[[16]byte][]byte
+}
+
+func newKeyring(provider encryption.KeyProvider) *keyring {
+	return &keyring{
+		provider: provider,
+		keys:     make(map[[16]byte][]byte),
+	}
+}
+
+// dataKey returns the data key of the environment, creating it on first use.
+func (k *keyring) dataKey(ctx context.Context, q *repo.Queries, envID pgtype.UUID) ([]byte, error) {
+	k.mu.Lock()
+	key, ok := k.keys[envID.Bytes]
+	k.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	stored, err := q.GetEnvironmentKey(ctx, envID)
+	if err == pgx.ErrNoRows {
+		stored, err = k.createDataKey(ctx, q, envID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment key: %w", err)
+	}
+
+	if stored.MasterKeyID != k.provider.ID() {
+		return nil, fmt.Errorf("environment key was wrapped by master key %s, have %s", stored.MasterKeyID, k.provider.ID())
+	}
+	key, err = k.provider.UnwrapKey(ctx, stored.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap environment key: %w", err)
+	}
+
+	k.mu.Lock()
+	k.keys[envID.Bytes] = key
+	k.mu.Unlock()
+	return key, nil
+}
+
+func (k *keyring) createDataKey(ctx context.Context, q *repo.Queries, envID pgtype.UUID) (repo.EnvironmentKey, error) {
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		return repo.EnvironmentKey{}, err
+	}
+	wrapped, err := k.provider.WrapKey(ctx, key)
+	if err != nil {
+		return repo.EnvironmentKey{}, fmt.Errorf("failed to wrap environment key: %w", err)
+	}
+
+	stored, err := q.CreateEnvironmentKey(ctx, repo.CreateEnvironmentKeyParams{
+		EnvironmentID: envID,
+		WrappedKey:    wrapped,
+		MasterKeyID:   k.provider.ID(),
+	})
+	if err == pgx.ErrNoRows {
+		// Another request created the key first.
+		return q.GetEnvironmentKey(ctx, envID)
+	}
+	return stored, err
+}
+
+// valueAD is the additional data that binds a sealed value to the environment
+// and the name it is stored under, so that a value copied to another row fails
+// to decrypt. name is the variable key, or another name that cannot be a key.
+func valueAD(envID pgtype.UUID, name string) []byte {
+	return append(envID.Bytes[:], name...)
+}
+
+func sealValue(key []byte, envID pgtype.UUID, name, plaintext string) (string, error) {
+	sealed, err := encryption.Seal(key, []byte(plaintext), valueAD(envID, name))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openValue decrypts a value that sealValue stored under name in the
+// environment. Rows written before encryption was introduced are returned as
+// is until they have been migrated.
+func openValue(key []byte, envID pgtype.UUID, name, value string, encrypted bool) (string, error) {
+	if !encrypted {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", encryption.ErrDecrypt
+	}
+	plaintext, err := encryption.Open(key, sealed, valueAD(envID, name))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// EncryptPlaintextVariables encrypts every variable and variable version that is
+// still stored in plain text and returns the number of migrated rows.
+func (s *svc) EncryptPlaintextVariables(ctx context.Context) (int, error) {
+	vars, err := s.repo.ListPlaintextVariables(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list plaintext variables: %w", err)
+	}
+
+	for i, v := range vars {
+		key, err := s.keys.dataKey(ctx, s.repo, v.EnvironmentID)
+		if err != nil {
+			return i, err
+		}
+		sealed, err := sealValue(key, v.EnvironmentID, v.Key, v.Value)
+		if err != nil {
+			return i, err
+		}
+		if err := s.repo.EncryptVariableValue(ctx, repo.EncryptVariableValueParams{
+			ID:    v.ID,
+			Value: sealed,
+		}); err != nil {
+			return i, fmt.Errorf("failed to encrypt variable %s: %w", v.Key, err)
+		}
+	}
+
+	versions, err := s.repo.ListPlaintextVariableVersions(ctx)
+	if err != nil {
+		return len(vars), fmt.Errorf("failed to list plaintext variable versions: %w", err)
+	}
+	for i, v := range versions {
+		key, err := s.keys.dataKey(ctx, s.repo, v.EnvironmentID)
+		if err != nil {
+			return len(vars) + i, err
+		}
+		sealed, err := sealValue(key, v.EnvironmentID, v.Key, v.Value)
+		if err != nil {
+			return len(vars) + i, err
+		}
+		if err := s.repo.EncryptVariableVersionValue(ctx, repo.EncryptVariableVersionValueParams{
+			ID:    v.ID,
+			Value: sealed,
+		}); err != nil {
+			return len(vars) + i, fmt.Errorf("failed to encrypt version %d of %s: %w", v.Version, v.Key, err)
+		}
+	}
+	return len(vars) + len(versions), nil
+}
@@ -0,0 +1,47 @@
+package env
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/envm-org/envm/pkg/encryption"
+)
+
+func TestSealValue(t *testing.T) {
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	env := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	other := pgtype.UUID{Bytes: [16]byte{2}, Valid: true}
+
+	sealed, err := sealValue(key, env, "API_KEY", "s3cr3t")
+	if err != nil {
+		t.Fatalf("sealValue() error = %v", err)
+	}
+	if got, err := openValue(key, env, "API_KEY", sealed, true); err != nil || got != "s3cr3t" {
+		t.Errorf("openValue() = %q, %v, want %q", got, err, "s3cr3t")
+	}
+
+	tests := []struct {
+		name  string
+		envID pgtype.UUID
+		key   string
+	}{
+		{name: "other key", envID: env, key: "DB_PASSWORD"},
+		{name: "other environment", envID: other, key: "API_KEY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := openValue(key, tt.envID, tt.key, sealed, true); !errors.Is(err, encryption.ErrDecrypt) {
+				t.Errorf("openValue() error = %v, want %v", err, encryption.ErrDecrypt)
+			}
+		})
+	}
+
+	if got, err := openValue(key, env, "API_KEY", "plain", false); err != nil || got != "plain" {
+		t.Errorf("openValue() of a plaintext row = %q, %v, want %q", got, err, "plain")
+	}
+}
@@ -0,0 +1,804 @@
+package env
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/pkg/secretgen"
+)
+
+var (
+	ErrInvalidConnection  = errors.New("invalid database connection")
+	ErrConnectionNotFound = errors.New("database connection not found")
+	ErrConnectionExists   = errors.New("a database connection with this name already exists in the environment")
+	ErrInvalidLease       = errors.New("invalid lease")
+	ErrLeaseNotFound      = errors.New("lease not found")
+	ErrLeaseRevoked       = errors.New("lease has already been revoked")
+	ErrTargetDatabase     = errors.New("target database error")
+)
+
+// Bounds of the TTL of a lease.
+const (
+	minLeaseTTL        = time.Minute
+	maxLeaseTTL        = 30 * 24 * time.Hour
+	defaultLeaseTTL    = time.Hour
+	defaultMaxLeaseTTL = 24 * time.Hour
+)
+
+// targetTimeout bounds every operation on a target database.
+const targetTimeout = 30 * time.Second
+
+// Templates of the statements run against a target database. {{name}},
+// {{password}} and {{expiration}} are replaced by the generated role name and
+// password and the end of the lease. The role is created with VALID UNTIL so
+// that its password stops working even if it is never revoked.
+const (
+	defaultCreation   = `CREATE ROLE "{{name}}" WITH LOGIN PASSWORD '{{password}}' VALID UNTIL '{{expiration}}';`
+	defaultRevocation = `DROP OWNED BY "{{name}}"; DROP ROLE "{{name}}";`
+)
+
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// Statuses of a lease.
+const (
+	leaseActive  = "active"
+	leaseExpired = "expired"
+	leaseRevoked = "revoked"
+)
+
+// DatabaseConnection is a Postgres server that envm issues short-lived roles
+// on. The DSN holds the credentials of a role allowed to create roles and is
+// stored encrypted; only its host and database are ever returned.
+type DatabaseConnection struct {
+	ID            pgtype.UUID `json:"id"`
+	EnvironmentID pgtype.UUID `json:"environment_id"`
+	Name          string      `json:"name"`
+	Target        string      `json:"target"`
+	Creation      string      `json:"creation"`
+	Revocation    string      `json:"revocation"`
+	DefaultTTL    string      `json:"default_ttl"`
+	MaxTTL        string      `json:"max_ttl"`
+	CreatedBy     pgtype.UUID `json:"created_by"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// RegisterConnectionParams registers a connection. DSN must be a
+// postgres:// URL with a TCP host and a password, and must not point at the
+// database of envm itself. Creation and Revocation default to creating a login
+// role and dropping it with everything it owns; Creation must set the
+// expiration of the role so that it cannot outlive its lease. DefaultTTL and
+// MaxTTL default to 1h and 24h.
+type RegisterConnectionParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Name          string      `json:"name"`
+	DSN           string      `json:"dsn"`
+	Creation      string      `json:"creation"`
+	Revocation    string      `json:"revocation"`
+	DefaultTTL    string      `json:"default_ttl"`
+	MaxTTL        string      `json:"max_ttl"`
+	AuthorID      pgtype.UUID `json:"-"`
+}
+
+// Lease is a role issued on a target database. The role is dropped when the
+// lease expires or is revoked.
+type Lease struct {
+	ID           pgtype.UUID `json:"id"`
+	ConnectionID pgtype.UUID `json:"connection_id"`
+	Username     string      `json:"username"`
+	Status       string      `json:"status"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+	RevokedAt    *time.Time  `json:"revoked_at,omitempty"`
+	CreatedBy    pgtype.UUID `json:"created_by"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// Credentials are returned once, when a lease is issued. The password is not
+// stored by envm.
+type Credentials struct {
+	Lease
+	Password string `json:"password"`
+	DSN      string `json:"dsn"`
+}
+
+// IssueParams requests credentials from a connection. TTL defaults to the
+// default TTL of the connection and cannot exceed its maximum.
+type IssueParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	ConnectionID  pgtype.UUID `json:"-"`
+	TTL           string      `json:"ttl"`
+	UserID        pgtype.UUID `json:"-"`
+}
+
+// checkTemplate checks that a template only uses known placeholders and uses
+// each of required.
+func checkTemplate(name, tmpl string, required ...string) error {
+	used := make(map[string]bool)
+	for _, m := range templatePlaceholder.FindAllStringSubmatch(tmpl, -1) {
+		switch m[1] {
+		case "name", "password", "expiration":
+			used[m[1]] = true
+		default:
+			return fmt.Errorf("%w: unknown placeholder {{%s}} in %s", ErrInvalidConnection, m[1], name)
+		}
+	}
+	for _, r := range required {
+		if !used[r] {
+			return fmt.Errorf("%w: %s must use {{%s}}", ErrInvalidConnection, name, r)
+		}
+	}
+	return nil
+}
+
+// renderTemplate fills in a template. Names and passwords are generated from
+// characters that need no quoting, so they are safe to substitute into SQL.
+func renderTemplate(tmpl, name, password string, expiration time.Time) string {
+	return templatePlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		switch templatePlaceholder.FindStringSubmatch(m)[1] {
+		case "name":
+			return name
+		case "password":
+			return password
+		default:
+			return expiration.UTC().Format(time.RFC3339)
+		}
+	})
+}
+
+func parseTTL(raw string, fallback time.Duration) (time.Duration, error) {
+	if raw == "" {
+		return fallback, nil
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl < minLeaseTTL || ttl > maxLeaseTTL {
+		return 0, fmt.Errorf("must be a duration between %s and %s", minLeaseTTL, maxLeaseTTL)
+	}
+	return ttl, nil
+}
+
+// dsnOverrides are connection parameters that would make envm connect to
+// another host than the one in the URL, or authenticate with files of the
+// server.
+var dsnOverrides = []string{"host", "hostaddr", "passfile", "service", "servicefile", "sslcert", "sslkey"}
+
+// parseDSN checks that dsn is a postgres URL naming a TCP host and carrying a
+// password, so that connecting with it never falls back to the sockets,
+// password file or environment of the server.
+func parseDSN(dsn string) (*url.URL, *pgconn.Config, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") || u.Hostname() == "" {
+		return nil, nil, fmt.Errorf("%w: dsn must be a postgres:// URL", ErrInvalidConnection)
+	}
+	if _, ok := u.User.Password(); !ok {
+		return nil, nil, fmt.Errorf("%w: dsn must include a password", ErrInvalidConnection)
+	}
+	query := u.Query()
+	for _, param := range dsnOverrides {
+		if query.Has(param) {
+			return nil, nil, fmt.Errorf("%w: dsn must not set %s", ErrInvalidConnection, param)
+		}
+	}
+	config, err := pgconn.ParseConfig(dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidConnection, err)
+	}
+	if config.Password == "" {
+		return nil, nil, fmt.Errorf("%w: dsn must include a password", ErrInvalidConnection)
+	}
+	hosts := []string{config.Host}
+	for _, fallback := range config.Fallbacks {
+		hosts = append(hosts, fallback.Host)
+	}
+	for _, host := range hosts {
+		if strings.HasPrefix(host, "/") {
+			return nil, nil, fmt.Errorf("%w: dsn must not use a unix socket", ErrInvalidConnection)
+		}
+	}
+	return u, config, nil
+}
+
+// lookupHost resolves host to its addresses. A unix socket has none.
+func lookupHost(ctx context.Context, host string) ([]net.IP, error) {
+	if strings.HasPrefix(host, "/") {
+		return nil, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
+
+// isLocal reports whether ip is an address of the machine envm runs on.
+func isLocal(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if n, ok := addr.(*net.IPNet); ok && n.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTarget resolves the hosts of config and fails if any of their
+// addresses is the database server of envm itself, whose roles must never be
+// managed through a connection. It returns the addresses of each host.
+func (s *svc) resolveTarget(ctx context.Context, config *pgconn.Config) (map[string][]string, error) {
+	ownConfig := s.db.Config().ConnConfig
+	own, err := lookupHost(ctx, ownConfig.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the envm database server: %w", err)
+	}
+	// A server reached over a unix socket or a local address is the machine
+	// envm runs on.
+	ownLocal := strings.HasPrefix(ownConfig.Host, "/")
+	for _, ip := range own {
+		ownLocal = ownLocal || isLocal(ip)
+	}
+
+	targets := []*pgconn.FallbackConfig{{Host: config.Host, Port: config.Port}}
+	targets = append(targets, config.Fallbacks...)
+	resolved := make(map[string][]string, len(targets))
+	for _, t := range targets {
+		ips, err := lookupHost(ctx, t.Host)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to resolve %s: %v", ErrInvalidConnection, t.Host, err)
+		}
+		for _, ip := range ips {
+			if t.Port != ownConfig.Port {
+				continue
+			}
+			if (ownLocal && isLocal(ip)) || slices.ContainsFunc(own, ip.Equal) {
+				return nil, fmt.Errorf("%w: dsn must not point at the envm database server", ErrInvalidConnection)
+			}
+		}
+		for _, ip := range ips {
+			resolved[t.Host] = append(resolved[t.Host], ip.String())
+		}
+	}
+	return resolved, nil
+}
+
+// connectTarget connects to a target database after checking it with
+// resolveTarget. It connects to the addresses that were checked, so a name
+// that resolves differently by then cannot point it at the envm database
+// server. The caller closes the connection.
+func (s *svc) connectTarget(ctx context.Context, dsn string) (*pgx.Conn, error) {
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConnection, err)
+	}
+	resolved, err := s.resolveTarget(ctx, &config.Config)
+	if err != nil {
+		return nil, err
+	}
+	config.LookupFunc = func(ctx context.Context, host string) ([]string, error) {
+		if addrs, ok := resolved[host]; ok {
+			return addrs, nil
+		}
+		return nil, fmt.Errorf("host %s was not checked", host)
+	}
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to connect: %v", ErrTargetDatabase, err)
+	}
+	return conn, nil
+}
+
+func toLease(l repo.CredentialLease) Lease {
+	out := Lease{
+		ID:           l.ID,
+		ConnectionID: l.ConnectionID,
+		Username:     l.Username,
+		Status:       leaseActive,
+		ExpiresAt:    l.ExpiresAt.Time,
+		CreatedBy:    l.CreatedBy,
+		CreatedAt:    l.CreatedAt.Time,
+	}
+	if l.RevokedAt.Valid {
+		revoked := l.RevokedAt.Time
+		out.RevokedAt = &revoked
+		out.Status = leaseRevoked
+	} else if !out.ExpiresAt.After(time.Now()) {
+		out.Status = leaseExpired
+	}
+	return out
+}
+
+// connection returns the connection of the environment.
+func (s *svc) connection(ctx context.Context, envID, id pgtype.UUID) (repo.DatabaseConnection, error) {
+	conn, err := s.repo.GetDatabaseConnection(ctx, repo.GetDatabaseConnectionParams{
+		ID:            id,
+		EnvironmentID: envID,
+	})
+	if err == pgx.ErrNoRows {
+		return repo.DatabaseConnection{}, ErrConnectionNotFound
+	}
+	if err != nil {
+		return repo.DatabaseConnection{}, fmt.Errorf("failed to fetch database connection: %w", err)
+	}
+	return conn, nil
+}
+
+// dsnName is the name a connection DSN is sealed under. Variable keys cannot
+// contain a colon, so it never matches the key of a variable.
+func dsnName(connection string) string {
+	return "database:" + connection
+}
+
+// connectionDSN decrypts the DSN of the connection.
+func (s *svc) connectionDSN(ctx context.Context, conn repo.DatabaseConnection) (string, error) {
+	dataKey, err := s.keys.dataKey(ctx, s.repo, conn.EnvironmentID)
+	if err != nil {
+		return "", err
+	}
+	return openValue(dataKey, conn.EnvironmentID, dsnName(conn.Name), conn.Dsn, true)
+}
+
+func (s *svc) toConnection(ctx context.Context, conn repo.DatabaseConnection) (DatabaseConnection, error) {
+	dsn, err := s.connectionDSN(ctx, conn)
+	if err != nil {
+		return DatabaseConnection{}, err
+	}
+	u, _, err := parseDSN(dsn)
+	if err != nil {
+		return DatabaseConnection{}, err
+	}
+	return DatabaseConnection{
+		ID:            conn.ID,
+		EnvironmentID: conn.EnvironmentID,
+		Name:          conn.Name,
+		Target:        u.Host + u.Path,
+		Creation:      conn.Creation,
+		Revocation:    conn.Revocation,
+		DefaultTTL:    (time.Duration(conn.DefaultTtlSeconds) * time.Second).String(),
+		MaxTTL:        (time.Duration(conn.MaxTtlSeconds) * time.Second).String(),
+		CreatedBy:     conn.CreatedBy,
+		CreatedAt:     conn.CreatedAt.Time,
+	}, nil
+}
+
+// RegisterConnection stores a connection after checking that the server can
+// be reached with it.
+func (s *svc) RegisterConnection(ctx context.Context, params RegisterConnectionParams) (DatabaseConnection, error) {
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		return DatabaseConnection{}, fmt.Errorf("%w: name is required", ErrInvalidConnection)
+	}
+	if _, _, err := parseDSN(params.DSN); err != nil {
+		return DatabaseConnection{}, err
+	}
+	if strings.TrimSpace(params.Creation) == "" {
+		params.Creation = defaultCreation
+	}
+	if strings.TrimSpace(params.Revocation) == "" {
+		params.Revocation = defaultRevocation
+	}
+	if err := checkTemplate("creation", params.Creation, "name", "password", "expiration"); err != nil {
+		return DatabaseConnection{}, err
+	}
+	if err := checkTemplate("revocation", params.Revocation, "name"); err != nil {
+		return DatabaseConnection{}, err
+	}
+	defaultTTL, err := parseTTL(params.DefaultTTL, defaultLeaseTTL)
+	if err != nil {
+		return DatabaseConnection{}, fmt.Errorf("%w: default_ttl %v", ErrInvalidConnection, err)
+	}
+	maxTTL, err := parseTTL(params.MaxTTL, max(defaultMaxLeaseTTL, defaultTTL))
+	if err != nil {
+		return DatabaseConnection{}, fmt.Errorf("%w: max_ttl %v", ErrInvalidConnection, err)
+	}
+	if defaultTTL > maxTTL {
+		return DatabaseConnection{}, fmt.Errorf("%w: default_ttl exceeds max_ttl", ErrInvalidConnection)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, targetTimeout)
+	defer cancel()
+	target, err := s.connectTarget(ctx, params.DSN)
+	if err != nil {
+		return DatabaseConnection{}, err
+	}
+	target.Close(ctx)
+
+	env, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return DatabaseConnection{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	var stored repo.DatabaseConnection
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, env.ID); err != nil {
+			return err
+		}
+		dataKey, err := s.keys.dataKey(ctx, q, env.ID)
+		if err != nil {
+			return err
+		}
+		sealed, err := sealValue(dataKey, env.ID, dsnName(params.Name), params.DSN)
+		if err != nil {
+			return err
+		}
+		stored, err = q.CreateDatabaseConnection(ctx, repo.CreateDatabaseConnectionParams{
+			EnvironmentID:     env.ID,
+			Name:              params.Name,
+			Dsn:               sealed,
+			Creation:          params.Creation,
+			Revocation:        params.Revocation,
+			DefaultTtlSeconds: int32(defaultTTL / time.Second),
+			MaxTtlSeconds:     int32(maxTTL / time.Second),
+			CreatedBy:         params.AuthorID,
+		})
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrConnectionExists
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create database connection: %w", err)
+		}
+		return s.audit(ctx, q, env, params.AuthorID, auditDatabaseCreate, map[string]any{
+			"connection": stored.Name,
+		})
+	})
+	if err != nil {
+		return DatabaseConnection{}, err
+	}
+	return s.toConnection(ctx, stored)
+}
+
+func (s *svc) ListConnections(ctx context.Context, envID pgtype.UUID) ([]DatabaseConnection, error) {
+	stored, err := s.repo.ListDatabaseConnections(ctx, envID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list database connections: %w", err)
+	}
+	out := make([]DatabaseConnection, 0, len(stored))
+	for _, conn := range stored {
+		c, err := s.toConnection(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (s *svc) GetConnection(ctx context.Context, envID, id pgtype.UUID) (DatabaseConnection, error) {
+	conn, err := s.connection(ctx, envID, id)
+	if err != nil {
+		return DatabaseConnection{}, err
+	}
+	return s.toConnection(ctx, conn)
+}
+
+// DeleteConnection revokes the active leases of the connection and deletes
+// it. If a role cannot be dropped the connection is kept, so that deleting it
+// can be retried.
+func (s *svc) DeleteConnection(ctx context.Context, envID, id, userID pgtype.UUID) error {
+	conn, err := s.connection(ctx, envID, id)
+	if err != nil {
+		return err
+	}
+	// Checked before the credentials are revoked as well as when deleting.
+	if err := s.checkUnlocked(ctx, s.repo, envID); err != nil {
+		return err
+	}
+	if err := s.revokeLeases(ctx, conn, userID, "connection deleted"); err != nil {
+		return err
+	}
+
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	return s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, env.ID); err != nil {
+			return err
+		}
+		if err := q.DeleteDatabaseConnection(ctx, conn.ID); err != nil {
+			return fmt.Errorf("failed to delete database connection: %w", err)
+		}
+		return s.audit(ctx, q, env, userID, auditDatabaseDelete, map[string]any{
+			"connection": conn.Name,
+		})
+	})
+}
+
+// IssueCredentials creates a role on the target database and records a lease
+// for it.
+func (s *svc) IssueCredentials(ctx context.Context, params IssueParams) (Credentials, error) {
+	conn, err := s.connection(ctx, params.EnvironmentID, params.ConnectionID)
+	if err != nil {
+		return Credentials{}, err
+	}
+	ttl, err := parseTTL(params.TTL, time.Duration(conn.DefaultTtlSeconds)*time.Second)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("%w: ttl %v", ErrInvalidLease, err)
+	}
+	if maxTTL := time.Duration(conn.MaxTtlSeconds) * time.Second; ttl > maxTTL {
+		return Credentials{}, fmt.Errorf("%w: ttl exceeds the maximum of %s", ErrInvalidLease, maxTTL)
+	}
+	dsn, err := s.connectionDSN(ctx, conn)
+	if err != nil {
+		return Credentials{}, err
+	}
+	u, _, err := parseDSN(dsn)
+	if err != nil {
+		return Credentials{}, err
+	}
+	env, err := s.repo.GetEnvironment(ctx, conn.EnvironmentID)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return Credentials{}, err
+	}
+	username := "envm_" + hex.EncodeToString(suffix)
+	password, err := secretgen.Generate(secretgen.Spec{
+		Type:    secretgen.TypePassword,
+		Length:  32,
+		Classes: []string{secretgen.ClassLower, secretgen.ClassUpper, secretgen.ClassDigits},
+	})
+	if err != nil {
+		return Credentials{}, err
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	if err := s.execTarget(ctx, dsn, renderTemplate(conn.Creation, username, password.Value, expiresAt)); err != nil {
+		return Credentials{}, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	var lease repo.CredentialLease
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		var err error
+		lease, err = q.CreateCredentialLease(ctx, repo.CreateCredentialLeaseParams{
+			ConnectionID: conn.ID,
+			Username:     username,
+			ExpiresAt:    pgtype.Timestamptz{Time: expiresAt, Valid: true},
+			CreatedBy:    params.UserID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record lease: %w", err)
+		}
+		return s.audit(ctx, q, env, params.UserID, auditCredentialIssue, map[string]any{
+			"connection": conn.Name,
+			"lease":      lease.ID,
+			"username":   username,
+			"expires_at": expiresAt,
+		})
+	})
+	if err != nil {
+		// Without a lease nothing would ever drop the role.
+		if dropErr := s.dropRole(ctx, conn, username); dropErr != nil {
+			return Credentials{}, errors.Join(err, dropErr)
+		}
+		return Credentials{}, err
+	}
+
+	u.User = url.UserPassword(username, password.Value)
+	return Credentials{
+		Lease:    toLease(lease),
+		Password: password.Value,
+		DSN:      u.String(),
+	}, nil
+}
+
+// ListLeases returns the leases of the connection, newest first.
+func (s *svc) ListLeases(ctx context.Context, envID, connectionID pgtype.UUID) ([]Lease, error) {
+	conn, err := s.connection(ctx, envID, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.repo.ListCredentialLeases(ctx, conn.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %w", err)
+	}
+	out := make([]Lease, 0, len(stored))
+	for _, l := range stored {
+		out = append(out, toLease(l))
+	}
+	return out, nil
+}
+
+func (s *svc) lease(ctx context.Context, conn repo.DatabaseConnection, id pgtype.UUID) (repo.CredentialLease, error) {
+	lease, err := s.repo.GetCredentialLease(ctx, repo.GetCredentialLeaseParams{
+		ID:           id,
+		ConnectionID: conn.ID,
+	})
+	if err == pgx.ErrNoRows {
+		return repo.CredentialLease{}, ErrLeaseNotFound
+	}
+	if err != nil {
+		return repo.CredentialLease{}, fmt.Errorf("failed to fetch lease: %w", err)
+	}
+	return lease, nil
+}
+
+func (s *svc) GetLease(ctx context.Context, envID, connectionID, id pgtype.UUID) (Lease, error) {
+	conn, err := s.connection(ctx, envID, connectionID)
+	if err != nil {
+		return Lease{}, err
+	}
+	lease, err := s.lease(ctx, conn, id)
+	if err != nil {
+		return Lease{}, err
+	}
+	return toLease(lease), nil
+}
+
+// RevokeLease drops the role of the lease before it expires.
+func (s *svc) RevokeLease(ctx context.Context, envID, connectionID, id, userID pgtype.UUID) error {
+	conn, err := s.connection(ctx, envID, connectionID)
+	if err != nil {
+		return err
+	}
+	lease, err := s.lease(ctx, conn, id)
+	if err != nil {
+		return err
+	}
+	if lease.RevokedAt.Valid {
+		return ErrLeaseRevoked
+	}
+	return s.revoke(ctx, conn, lease, userID, "revoked")
+}
+
+// ReapCredentialLeases drops the roles of expired leases and returns how many
+// were revoked. A target database that cannot be reached does not keep the
+// leases of other connections from being revoked; its leases are retried on
+// the next run.
+func (s *svc) ReapCredentialLeases(ctx context.Context) (int, error) {
+	expired, err := s.repo.ListExpiredCredentialLeases(ctx, pgtype.Timestamptz{Time: time.Now(), Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired leases: %w", err)
+	}
+
+	reaped := 0
+	var errs []error
+	for _, row := range expired {
+		conn, err := s.connection(ctx, row.EnvironmentID, row.ConnectionID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		lease := repo.CredentialLease{
+			ID:           row.ID,
+			ConnectionID: row.ConnectionID,
+			Username:     row.Username,
+			ExpiresAt:    row.ExpiresAt,
+			RevokedAt:    row.RevokedAt,
+			CreatedBy:    row.CreatedBy,
+			CreatedAt:    row.CreatedAt,
+		}
+		if err := s.revoke(ctx, conn, lease, pgtype.UUID{}, "expired"); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reaped++
+	}
+	return reaped, errors.Join(errs...)
+}
+
+// revokeLeases revokes every lease of the connection that is not revoked yet,
+// including expired ones the reaper has not reached.
+func (s *svc) revokeLeases(ctx context.Context, conn repo.DatabaseConnection, userID pgtype.UUID, reason string) error {
+	leases, err := s.repo.ListActiveCredentialLeases(ctx, conn.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list leases: %w", err)
+	}
+	for _, lease := range leases {
+		if err := s.revoke(ctx, conn, lease, userID, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteConnections revokes the leases of every database connection of the
+// environment and deletes the connections. Connections keep their environment
+// from being deleted so that no role issued for it outlives it; this must run
+// first.
+func (s *svc) deleteConnections(ctx context.Context, envID pgtype.UUID, reason string) error {
+	conns, err := s.repo.ListDatabaseConnections(ctx, envID)
+	if err != nil {
+		return fmt.Errorf("failed to list database connections: %w", err)
+	}
+	for _, conn := range conns {
+		if err := s.revokeLeases(ctx, conn, pgtype.UUID{}, reason); err != nil {
+			return err
+		}
+		if err := s.repo.DeleteDatabaseConnection(ctx, conn.ID); err != nil {
+			return fmt.Errorf("failed to delete database connection: %w", err)
+		}
+	}
+	return nil
+}
+
+// revoke drops the role of the lease and marks it revoked.
+func (s *svc) revoke(ctx context.Context, conn repo.DatabaseConnection, lease repo.CredentialLease, userID pgtype.UUID, reason string) error {
+	if err := s.dropRole(ctx, conn, lease.Username); err != nil {
+		return fmt.Errorf("failed to drop role %s: %w", lease.Username, err)
+	}
+	env, err := s.repo.GetEnvironment(ctx, conn.EnvironmentID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	return s.withTx(ctx, func(q *repo.Queries) error {
+		if err := q.RevokeCredentialLease(ctx, lease.ID); err != nil {
+			return fmt.Errorf("failed to revoke lease: %w", err)
+		}
+		return s.audit(ctx, q, env, userID, auditCredentialRevoke, map[string]any{
+			"connection": conn.Name,
+			"lease":      lease.ID,
+			"username":   lease.Username,
+			"reason":     reason,
+		})
+	})
+}
+
+// dropRole runs the revocation statements of the connection for the role. A
+// role that no longer exists is not an error.
+func (s *svc) dropRole(ctx context.Context, conn repo.DatabaseConnection, username string) error {
+	dsn, err := s.connectionDSN(ctx, conn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, targetTimeout)
+	defer cancel()
+	target, err := s.connectTarget(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer target.Close(ctx)
+
+	var exists bool
+	if err := target.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)", username).Scan(&exists); err != nil {
+		return fmt.Errorf("%w: %v", ErrTargetDatabase, err)
+	}
+	if !exists {
+		return nil
+	}
+	if _, err := target.Exec(ctx, renderTemplate(conn.Revocation, username, "", time.Time{})); err != nil {
+		return fmt.Errorf("%w: %v", ErrTargetDatabase, err)
+	}
+	return nil
+}
+
+// execTarget runs statements on a target database. Without arguments they
+// are sent as one simple query, so they run in a single transaction.
+func (s *svc) execTarget(ctx context.Context, dsn, statements string) error {
+	ctx, cancel := context.WithTimeout(ctx, targetTimeout)
+	defer cancel()
+	target, err := s.connectTarget(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer target.Close(ctx)
+
+	if _, err := target.Exec(ctx, statements); err != nil {
+		return fmt.Errorf("%w: %v", ErrTargetDatabase, err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package env
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// connectionID parses the dbid URL parameter. On failure the error response
+// has already been written and ok is false.
+func connectionID(w http.ResponseWriter, r *http.Request) (id pgtype.UUID, ok bool) {
+	if err := id.Scan(chi.URLParam(r, "dbid")); err != nil {
+		http.Error(w, "invalid connection id format", http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+// leaseID parses the lid URL parameter. On failure the error response has
+// already been written and ok is false.
+func leaseID(w http.ResponseWriter, r *http.Request) (id pgtype.UUID, ok bool) {
+	if err := id.Scan(chi.URLParam(r, "lid")); err != nil {
+		http.Error(w, "invalid lease id format", http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+func (h *handler) ListConnections(w http.ResponseWriter, r *http.Request) {
+	env, _, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	conns, err := h.service.ListConnections(r.Context(), env.ID)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, conns)
+}
+
+// RegisterConnection registers a database that credentials can be issued on.
+// Only admins of the project and of its organization can register connections.
+func (h *handler) RegisterConnection(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanManageDatabases(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var params RegisterConnectionParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.AuthorID = userID
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	conn, err := h.service.RegisterConnection(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, conn)
+}
+
+func (h *handler) GetConnection(w http.ResponseWriter, r *http.Request) {
+	env, _, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	id, ok := connectionID(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := h.service.GetConnection(r.Context(), env.ID, id)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, conn)
+}
+
+// DeleteConnection revokes every active lease of the connection and deletes
+// it.
+func (h *handler) DeleteConnection(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	id, ok := connectionID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanManageDatabases(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteConnection(r.Context(), env.ID, id, userID); err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, map[string]string{"message": "database connection deleted"})
+}
+
+// IssueCredentials creates a short-lived role and returns its credentials.
+// Anyone with access to the project can request credentials; what the role
+// may do is decided by the creation template of the connection.
+func (h *handler) IssueCredentials(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	id, ok := connectionID(w, r)
+	if !ok {
+		return
+	}
+
+	var params IssueParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil && err != io.EOF {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.ConnectionID = id
+	params.UserID = userID
+
+	creds, err := h.service.IssueCredentials(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	HTTPwriter.JSON(w, http.StatusOK, creds)
+}
+
+func (h *handler) ListLeases(w http.ResponseWriter, r *http.Request) {
+	env, _, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	id, ok := connectionID(w, r)
+	if !ok {
+		return
+	}
+
+	leases, err := h.service.ListLeases(r.Context(), env.ID, id)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, leases)
+}
+
+// RevokeLease drops the role of a lease. Users can revoke their own leases,
+// admins any lease.
+func (h *handler) RevokeLease(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	connID, ok := connectionID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := leaseID(w, r)
+	if !ok {
+		return
+	}
+
+	lease, err := h.service.GetLease(r.Context(), env.ID, connID, id)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	if lease.CreatedBy != userID {
+		if err := h.authorizer.CanManageDatabases(r.Context(), userID, env.ProjectID); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := h.service.RevokeLease(r.Context(), env.ID, connID, id, userID); err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, map[string]string{"message": "lease revoked"})
+}
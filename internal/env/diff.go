@@ -0,0 +1,164 @@
+package env
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DiffSide is one of the two states being compared: the current variables of
+// an environment or, when At is set, its variables at that time, or when Tag
+// is set, one of its snapshots.
+type DiffSide struct {
+	EnvironmentID pgtype.UUID
+	At            *time.Time
+	Tag           string
+}
+
+type DiffParams struct {
+	Left   DiffSide
+	Right  DiffSide
+	Reveal bool
+}
+
+// DiffValue is a value on one side of a diff. Masked secrets carry a hash
+// instead of the value. Hashes are keyed per diff, so equal hashes within one
+// response mean equal values but they cannot be compared across responses.
+// Restricted values, which the caller may not read, carry neither.
+type DiffValue struct {
+	Value      string `json:"value,omitempty"`
+	IsSecret   bool   `json:"is_secret"`
+	Masked     bool   `json:"masked,omitempty"`
+	Restricted bool   `json:"restricted,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+}
+
+type DiffEntry struct {
+	Key   string     `json:"key"`
+	Left  *DiffValue `json:"left,omitempty"`
+	Right *DiffValue `json:"right,omitempty"`
+}
+
+type Diff struct {
+	OnlyLeft  []DiffEntry `json:"only_left"`
+	OnlyRight []DiffEntry `json:"only_right"`
+	Changed   []DiffEntry `json:"changed"`
+	Unchanged []DiffEntry `json:"unchanged"`
+}
+
+// DiffEnvironments compares two environments, or one environment at two points
+// in time. A key is changed when its value or secret flag differs.
+func (s *svc) DiffEnvironments(ctx context.Context, params DiffParams) (Diff, error) {
+	left, err := s.diffState(ctx, params.Left)
+	if err != nil {
+		return Diff{}, err
+	}
+	right, err := s.diffState(ctx, params.Right)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	view, err := diffView(params.Reveal)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	diff := Diff{
+		OnlyLeft:  []DiffEntry{},
+		OnlyRight: []DiffEntry{},
+		Changed:   []DiffEntry{},
+		Unchanged: []DiffEntry{},
+	}
+	rightByKey := make(map[string]Variable, len(right))
+	for _, v := range right {
+		rightByKey[v.Key] = v
+	}
+	leftKeys := make(map[string]bool, len(left))
+	for _, l := range left {
+		leftKeys[l.Key] = true
+		r, ok := rightByKey[l.Key]
+		switch {
+		case !ok:
+			diff.OnlyLeft = append(diff.OnlyLeft, DiffEntry{Key: l.Key, Left: view(l)})
+		case l.Value != r.Value || l.IsSecret != r.IsSecret:
+			diff.Changed = append(diff.Changed, DiffEntry{Key: l.Key, Left: view(l), Right: view(r)})
+		default:
+			diff.Unchanged = append(diff.Unchanged, DiffEntry{Key: l.Key, Left: view(l), Right: view(r)})
+		}
+	}
+	for _, r := range right {
+		if !leftKeys[r.Key] {
+			diff.OnlyRight = append(diff.OnlyRight, DiffEntry{Key: r.Key, Right: view(r)})
+		}
+	}
+	return diff, nil
+}
+
+// diffView returns the function that turns the values of one diff into
+// DiffValues. Unless reveal is set, secrets are replaced by a hash keyed for
+// this diff only.
+func diffView(reveal bool) (func(v Variable) *DiffValue, error) {
+	hashKey := make([]byte, 32)
+	if _, err := rand.Read(hashKey); err != nil {
+		return nil, err
+	}
+	return func(v Variable) *DiffValue {
+		out := &DiffValue{IsSecret: v.IsSecret}
+		if v.IsSecret && !reveal {
+			mac := hmac.New(sha256.New, hashKey)
+			mac.Write([]byte(v.Value))
+			out.Masked = true
+			out.Hash = hex.EncodeToString(mac.Sum(nil)[:16])
+		} else {
+			out.Value = v.Value
+		}
+		return out
+	}, nil
+}
+
+// revealed returns the secret keys shown in plaintext on each side.
+func (d Diff) revealed() (left, right []string) {
+	for _, entries := range [][]DiffEntry{d.OnlyLeft, d.OnlyRight, d.Changed, d.Unchanged} {
+		for _, e := range entries {
+			if e.Left != nil && e.Left.IsSecret && !e.Left.Masked && !e.Left.Restricted {
+				left = append(left, e.Key)
+			}
+			if e.Right != nil && e.Right.IsSecret && !e.Right.Masked && !e.Right.Restricted {
+				right = append(right, e.Key)
+			}
+		}
+	}
+	return left, right
+}
+
+// redact replaces the values of keys the caller may not read with
+// placeholders, checking each side against its own access rules.
+func (d Diff) redact(left, right KeyAccess) {
+	for _, entries := range [][]DiffEntry{d.OnlyLeft, d.OnlyRight, d.Changed, d.Unchanged} {
+		for i, e := range entries {
+			if e.Left != nil && !left.CanRead(e.Key) {
+				entries[i].Left = &DiffValue{IsSecret: e.Left.IsSecret, Restricted: true}
+			}
+			if e.Right != nil && !right.CanRead(e.Key) {
+				entries[i].Right = &DiffValue{IsSecret: e.Right.IsSecret, Restricted: true}
+			}
+		}
+	}
+}
+
+// diffState returns the decrypted variables of one side of a diff.
+func (s *svc) diffState(ctx context.Context, side DiffSide) ([]Variable, error) {
+	if side.Tag != "" {
+		snapshot, err := s.GetSnapshot(ctx, side.EnvironmentID, side.Tag, true)
+		return snapshot.Variables, err
+	}
+	if side.At != nil {
+		return s.ListVariablesAt(ctx, side.EnvironmentID, *side.At, true)
+	}
+	return s.ListVariables(ctx, side.EnvironmentID, true)
+}
@@ -0,0 +1,111 @@
+package env
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/envm-org/envm/internal/auth"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DiffEnvironments compares the environment in the URL (left) with the one
+// given by the with query parameter (right, defaults to the same
+// environment). at and with_at select points in time for either side, which
+// is not possible for an environment that inherits.
+func (h *handler) DiffEnvironments(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	reveal, reason, ok := h.revealRequested(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	rightEnv := env
+	params := DiffParams{
+		Left:   DiffSide{EnvironmentID: env.ID},
+		Right:  DiffSide{EnvironmentID: env.ID},
+		Reveal: reveal,
+	}
+
+	if with := query.Get("with"); with != "" {
+		var otherID pgtype.UUID
+		if err := otherID.Scan(with); err != nil {
+			http.Error(w, "invalid with format", http.StatusBadRequest)
+			return
+		}
+		other, err := h.service.GetEnv(r.Context(), otherID)
+		if err != nil {
+			http.Error(w, "environment not found", http.StatusNotFound)
+			return
+		}
+		if err := h.checkEnvAccess(r.Context(), userID, other, auth.AccessRead); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if reveal {
+			if err := h.authorizer.CanRevealSecrets(r.Context(), userID, other.ProjectID); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		params.Right = DiffSide{EnvironmentID: other.ID}
+		rightEnv = other
+	}
+
+	for name, side := range map[string]*DiffSide{"at": &params.Left, "with_at": &params.Right} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "invalid "+name+" format: use RFC 3339", http.StatusBadRequest)
+			return
+		}
+		side.At = &at
+	}
+
+	if params.Left.EnvironmentID == params.Right.EnvironmentID && params.Left.At == nil && params.Right.At == nil {
+		http.Error(w, "nothing to compare: set with, at or with_at", http.StatusBadRequest)
+		return
+	}
+
+	leftAccess, ok := h.keyAccess(w, r, env, userID)
+	if !ok {
+		return
+	}
+	rightAccess, ok := h.keyAccess(w, r, rightEnv, userID)
+	if !ok {
+		return
+	}
+
+	diff, err := h.service.DiffEnvironments(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	diff.redact(leftAccess, rightAccess)
+	if reveal {
+		left, right := diff.revealed()
+		if rightEnv.ID == env.ID {
+			left, right = mergeKeys(left, right), nil
+		}
+		if !h.auditReveal(w, r, env, userID, reason, left) || !h.auditReveal(w, r, rightEnv, userID, reason, right) {
+			return
+		}
+	}
+	HTTPwriter.JSON(w, http.StatusOK, diff)
+}
+
+// mergeKeys returns the sorted union of two key lists.
+func mergeKeys(a, b []string) []string {
+	keys := append(slices.Clone(a), b...)
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
@@ -0,0 +1,285 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+var (
+	ErrInvalidClone      = errors.New("invalid clone")
+	ErrEnvironmentExists = errors.New("an environment with this slug already exists in the project")
+)
+
+// Bounds of the TTL of an ephemeral environment.
+const (
+	minEphemeralTTL = time.Minute
+	maxEphemeralTTL = 30 * 24 * time.Hour
+)
+
+// Override sets a key in a cloned environment. An overridden key stays secret
+// if it is secret in the source.
+type Override struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	IsSecret bool   `json:"is_secret"`
+}
+
+// CloneParams creates an ephemeral environment in the project of the source
+// with a copy of its effective variables and the overrides applied. The clone
+// is deleted once TTL, a duration such as 72h, has passed.
+type CloneParams struct {
+	SourceID  pgtype.UUID `json:"-"`
+	Name      string      `json:"name"`
+	Slug      string      `json:"slug"`
+	TTL       string      `json:"ttl"`
+	Overrides []Override  `json:"overrides"`
+	AuthorID  pgtype.UUID `json:"-"`
+}
+
+// EphemeralEnvironment is an environment that is deleted automatically at
+// ExpiresAt.
+type EphemeralEnvironment struct {
+	repo.Environment
+	SourceID  pgtype.UUID `json:"source_id"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	CreatedBy pgtype.UUID `json:"created_by"`
+}
+
+// CloneEnvironment creates an ephemeral environment from the source. The copy
+// does not inherit from the source, so later changes to the source are not
+// reflected in it. The access rules that apply to the source are copied along
+// with the variables. All variables are written in one transaction and recorded
+// as created in the version history of the clone.
+func (s *svc) CloneEnvironment(ctx context.Context, params CloneParams) (EphemeralEnvironment, error) {
+	params.Name = strings.TrimSpace(params.Name)
+	params.Slug = strings.TrimSpace(params.Slug)
+	if params.Name == "" || params.Slug == "" {
+		return EphemeralEnvironment{}, fmt.Errorf("%w: name and slug are required", ErrInvalidClone)
+	}
+	ttl, err := time.ParseDuration(params.TTL)
+	if err != nil || ttl < minEphemeralTTL || ttl > maxEphemeralTTL {
+		return EphemeralEnvironment{}, fmt.Errorf("%w: ttl must be a duration between %s and %s", ErrInvalidClone, minEphemeralTTL, maxEphemeralTTL)
+	}
+
+	source, err := s.repo.GetEnvironment(ctx, params.SourceID)
+	if err != nil {
+		return EphemeralEnvironment{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	vars, err := s.ListVariables(ctx, source.ID, true)
+	if err != nil {
+		return EphemeralEnvironment{}, err
+	}
+	acls, err := s.ListACLs(ctx, source.ID)
+	if err != nil {
+		return EphemeralEnvironment{}, err
+	}
+
+	message := "cloned from " + source.Slug
+	changes := make([]change, 0, len(vars)+len(params.Overrides))
+	index := make(map[string]int, len(vars))
+	for _, v := range vars {
+		index[v.Key] = len(changes)
+		changes = append(changes, change{
+			Key:      v.Key,
+			Value:    v.Value,
+			IsSecret: v.IsSecret,
+			AuthorID: params.AuthorID,
+			Message:  message,
+		})
+	}
+	var overrides []change
+	for _, o := range params.Overrides {
+		if err := validateKey(o.Key); err != nil {
+			return EphemeralEnvironment{}, err
+		}
+		c := change{Key: o.Key, Value: o.Value, IsSecret: o.IsSecret, AuthorID: params.AuthorID, Message: message}
+		if i, ok := index[o.Key]; ok {
+			c.IsSecret = c.IsSecret || changes[i].IsSecret
+			changes[i] = c
+		} else {
+			index[o.Key] = len(changes)
+			changes = append(changes, c)
+		}
+		overrides = append(overrides, c)
+	}
+	// The clone starts out with the effective set of the source, so the
+	// overrides are checked as if they were written to the source.
+	if err := s.checkChanges(ctx, source.ID, overrides, nil); err != nil {
+		return EphemeralEnvironment{}, err
+	}
+
+	var out EphemeralEnvironment
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		env, err := q.CreateEnvironment(ctx, repo.CreateEnvironmentParams{
+			ProjectID: source.ProjectID,
+			Name:      params.Name,
+			Slug:      params.Slug,
+		})
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrEnvironmentExists
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create environment: %w", err)
+		}
+		ephemeral, err := q.CreateEphemeralEnvironment(ctx, repo.CreateEphemeralEnvironmentParams{
+			EnvironmentID: env.ID,
+			SourceID:      source.ID,
+			ExpiresAt:     pgtype.Timestamptz{Time: time.Now().Add(ttl), Valid: true},
+			CreatedBy:     params.AuthorID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record ephemeral environment: %w", err)
+		}
+
+		dataKey, err := s.keys.dataKey(ctx, q, env.ID)
+		if err != nil {
+			return err
+		}
+		for _, c := range changes {
+			if _, err := s.apply(ctx, q, env.ID, dataKey, c); err != nil {
+				return err
+			}
+		}
+		for _, rule := range cloneACLs(acls) {
+			if _, err := q.UpsertVariableACL(ctx, repo.UpsertVariableACLParams{
+				EnvironmentID: env.ID,
+				Pattern:       rule.Pattern,
+				Readers:       rule.Readers,
+				Writers:       rule.Writers,
+				CreatedBy:     params.AuthorID,
+			}); err != nil {
+				return fmt.Errorf("failed to copy access rule: %w", err)
+			}
+		}
+		out = EphemeralEnvironment{
+			Environment: env,
+			SourceID:    ephemeral.SourceID,
+			ExpiresAt:   ephemeral.ExpiresAt.Time,
+			CreatedBy:   ephemeral.CreatedBy,
+		}
+		return nil
+	})
+	if err != nil {
+		return EphemeralEnvironment{}, err
+	}
+	return out, nil
+}
+
+// ListEphemeralEnvironments returns the ephemeral environments of the project
+// that have not expired yet, the soonest to expire first.
+func (s *svc) ListEphemeralEnvironments(ctx context.Context, projectID pgtype.UUID) ([]EphemeralEnvironment, error) {
+	rows, err := s.repo.ListEphemeralEnvironments(ctx, repo.ListEphemeralEnvironmentsParams{
+		ProjectID: projectID,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ephemeral environments: %w", err)
+	}
+	out := make([]EphemeralEnvironment, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, EphemeralEnvironment{
+			Environment: repo.Environment{
+				ID:        row.ID,
+				ProjectID: row.ProjectID,
+				Name:      row.Name,
+				Slug:      row.Slug,
+				ParentID:  row.ParentID,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+				Version:   row.Version,
+			},
+			SourceID:  row.SourceID,
+			ExpiresAt: row.ExpiresAt.Time,
+			CreatedBy: row.CreatedBy,
+		})
+	}
+	return out, nil
+}
+
+// ReapEphemeralEnvironments deletes the ephemeral environments that have
+// expired and returns how many were deleted. Locked environments are kept
+// until they are unlocked. An environment that cannot be deleted does not stop
+// the others; the errors are joined.
+func (s *svc) ReapEphemeralEnvironments(ctx context.Context) (int, error) {
+	expired, err := s.repo.ListExpiredEphemeralEnvironments(ctx, pgtype.Timestamptz{Time: time.Now(), Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired environments: %w", err)
+	}
+
+	reaped := 0
+	var errs []error
+	for _, env := range expired {
+		lock, err := s.GetLock(ctx, env.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if lock != nil {
+			continue
+		}
+		if err := s.deleteConnections(ctx, env.ID, "environment expired"); err != nil {
+			errs = append(errs, fmt.Errorf("environment %s: %w", env.Slug, err))
+			continue
+		}
+		err = s.withTx(ctx, func(q *repo.Queries) error {
+			if err := s.audit(ctx, q, env, pgtype.UUID{}, auditExpire, map[string]any{
+				"slug": env.Slug,
+			}); err != nil {
+				return err
+			}
+			if err := q.DeleteEnvironment(ctx, env.ID); err != nil {
+				return fmt.Errorf("failed to delete environment: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("environment %s: %w", env.Slug, err))
+			continue
+		}
+		reaped++
+	}
+	return reaped, errors.Join(errs...)
+}
+
+// cloneACLs merges the access rules that apply to a source environment into
+// rules for its clone. Rules for the same pattern all have to allow access, so
+// they are merged into one that names only the principals common to all.
+func cloneACLs(acls []ACL) []ACL {
+	var out []ACL
+	index := make(map[string]int)
+	for _, rule := range acls {
+		i, ok := index[rule.Pattern]
+		if !ok {
+			index[rule.Pattern] = len(out)
+			out = append(out, rule)
+			continue
+		}
+		// Writers may also read, so readers are those that can read under both
+		// rules without being able to write under both.
+		readers := intersect(append(slices.Clone(out[i].Readers), out[i].Writers...), append(slices.Clone(rule.Readers), rule.Writers...))
+		out[i].Writers = intersect(out[i].Writers, rule.Writers)
+		out[i].Readers = slices.DeleteFunc(readers, func(p string) bool { return slices.Contains(out[i].Writers, p) })
+	}
+	return out
+}
+
+// intersect returns the elements of a that are also in b, without duplicates.
+func intersect(a, b []string) []string {
+	out := []string{}
+	for _, v := range a {
+		if slices.Contains(b, v) && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package env
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/envm-org/envm/internal/auth"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CloneEnvironment creates an ephemeral environment from the environment in
+// the URL, for example for the preview deployment of a pull request. The
+// clone creates an environment holding the values of the source, so the
+// caller must manage the environments of the project or have write access to
+// the source.
+func (h *handler) CloneEnvironment(w http.ResponseWriter, r *http.Request) {
+	source, userID, ok := h.authorizeEnvAccess(w, r, auth.AccessRead)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanManageEnvironments(r.Context(), userID, source.ProjectID); err != nil {
+		if err := h.checkEnvAccess(r.Context(), userID, source, auth.AccessWrite); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	var params CloneParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.SourceID = source.ID
+	params.AuthorID = userID
+
+	env, err := h.service.CloneEnvironment(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, env)
+}
+
+// ListEphemeralEnvironments lists the active ephemeral environments of the
+// project given by the project_id query parameter.
+func (h *handler) ListEphemeralEnvironments(w http.ResponseWriter, r *http.Request) {
+	var projectID pgtype.UUID
+	if err := projectID.Scan(r.URL.Query().Get("project_id")); err != nil {
+		http.Error(w, "invalid project_id format", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.HasProjectAccess(r.Context(), userID, projectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	envs, err := h.service.ListEphemeralEnvironments(r.Context(), projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Leave out the environments the caller's role has no access to.
+	visible := make([]EphemeralEnvironment, 0, len(envs))
+	for _, env := range envs {
+		if err := h.checkEnvAccess(r.Context(), userID, env.Environment, auth.AccessRead); err == nil {
+			visible = append(visible, env)
+		}
+	}
+	HTTPwriter.JSON(w, http.StatusOK, visible)
+}
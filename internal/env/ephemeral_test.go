@@ -0,0 +1,89 @@
+package env
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCloneACLs(t *testing.T) {
+	tests := []struct {
+		name string
+		acls []ACL
+		want []ACL
+	}{
+		{
+			name: "no rules",
+			acls: nil,
+			want: nil,
+		},
+		{
+			name: "distinct patterns are kept in order",
+			acls: []ACL{
+				{Pattern: "STRIPE_*", Readers: []string{"role:developer"}, Writers: []string{"role:admin"}},
+				{Pattern: "DB_*", Writers: []string{"role:maintainer"}},
+			},
+			want: []ACL{
+				{Pattern: "STRIPE_*", Readers: []string{"role:developer"}, Writers: []string{"role:admin"}},
+				{Pattern: "DB_*", Writers: []string{"role:maintainer"}},
+			},
+		},
+		{
+			name: "same pattern keeps common principals",
+			acls: []ACL{
+				{Pattern: "STRIPE_*", Readers: []string{"role:developer", "role:viewer"}, Writers: []string{"role:admin"}},
+				{Pattern: "STRIPE_*", Readers: []string{"role:developer"}, Writers: []string{"role:admin", "role:owner"}},
+			},
+			want: []ACL{
+				{Pattern: "STRIPE_*", Readers: []string{"role:developer"}, Writers: []string{"role:admin"}},
+			},
+		},
+		{
+			name: "writer of one rule and reader of the other only reads",
+			acls: []ACL{
+				{Pattern: "STRIPE_*", Readers: []string{"role:developer"}, Writers: []string{"user:a", "role:admin"}},
+				{Pattern: "STRIPE_*", Readers: []string{"user:a"}, Writers: []string{"role:admin"}},
+			},
+			want: []ACL{
+				{Pattern: "STRIPE_*", Readers: []string{"user:a"}, Writers: []string{"role:admin"}},
+			},
+		},
+		{
+			name: "nobody in common",
+			acls: []ACL{
+				{Pattern: "KEY", Readers: []string{"role:developer"}},
+				{Pattern: "KEY", Writers: []string{"role:admin"}},
+				{Pattern: "OTHER", Readers: []string{"role:viewer"}},
+			},
+			want: []ACL{
+				{Pattern: "KEY", Readers: []string{}, Writers: []string{}},
+				{Pattern: "OTHER", Readers: []string{"role:viewer"}},
+			},
+		},
+		{
+			name: "three rules for one pattern",
+			acls: []ACL{
+				{Pattern: "KEY", Writers: []string{"role:admin", "role:owner", "user:a"}},
+				{Pattern: "KEY", Writers: []string{"role:admin", "role:owner"}, Readers: []string{"user:a"}},
+				{Pattern: "KEY", Writers: []string{"role:owner"}, Readers: []string{"role:admin", "user:a"}},
+			},
+			want: []ACL{
+				{Pattern: "KEY", Readers: []string{"user:a", "role:admin"}, Writers: []string{"role:owner"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cloneACLs(tt.acls)
+			if len(got) != len(tt.want) {
+				t.Fatalf("cloneACLs() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Pattern != tt.want[i].Pattern ||
+					!slices.Equal(got[i].Readers, tt.want[i].Readers) ||
+					!slices.Equal(got[i].Writers, tt.want[i].Writers) {
+					t.Errorf("cloneACLs()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
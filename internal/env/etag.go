@@ -0,0 +1,152 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+var ErrPreconditionFailed = errors.New("precondition failed: the resource has been modified")
+
+// etag is the entity tag of a version of the environment or variable with the
+// given id. Including the id makes the tag change when a variable is deleted
+// and created again, or when the value an environment inherits is replaced by
+// one of its own.
+func etag(id pgtype.UUID, version int32) string {
+	return fmt.Sprintf(`"%x.%d"`, id.Bytes, version)
+}
+
+// EnvironmentETag returns the entity tag of the current version of env.
+func EnvironmentETag(env repo.Environment) string {
+	return etag(env.ID, env.Version)
+}
+
+// revealedETag returns the entity tag of the revealed representation of the
+// version tagged tag. It differs from the masked one so that caches never
+// serve one in place of the other.
+func revealedETag(tag string) string {
+	if tag == "" {
+		return ""
+	}
+	return strings.TrimSuffix(tag, `"`) + `;revealed"`
+}
+
+// matchETag reports whether an If-Match header names tag, or the tag of its
+// revealed representation. Matching is strong, so weak tags never match, and *
+// matches any tag of an existing resource.
+func matchETag(header, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || t == tag || t == revealedETag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// noneMatch reports whether an If-None-Match header names tag. Matching is
+// weak, as for every conditional GET.
+func noneMatch(header, tag string) bool {
+	if header == "" || tag == "" {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the ETag header of the response to tag. If the client
+// already holds that version it writes 304 Not Modified and returns true.
+func notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	w.Header().Set("ETag", tag)
+	if noneMatch(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// writeTaggedJSON writes data like HTTPwriter.JSON with a weak entity tag
+// derived from the body, so that clients polling a list get 304 Not Modified
+// while nothing in it changes.
+func writeTaggedJSON(w http.ResponseWriter, r *http.Request, data any) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body.Bytes())
+	if notModified(w, r, fmt.Sprintf(`W/"%x"`, sum[:16])) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+// CheckETag fails with ErrPreconditionFailed unless ifMatch, an If-Match
+// header, names the current version of key in env. Writes check it again in
+// their transaction; this is for writes that are proposed as change requests
+// instead.
+func (s *svc) CheckETag(ctx context.Context, envID pgtype.UUID, key, ifMatch string) error {
+	return s.checkETag(ctx, s.repo, envID, key, ifMatch)
+}
+
+// checkETag fails with ErrPreconditionFailed unless ifMatch, an If-Match
+// header, names the current version of key in env. It must run inside the
+// transaction of the write, which keeps a local variable locked until the
+// write commits so that a concurrent change cannot slip in after the check.
+func (s *svc) checkETag(ctx context.Context, q *repo.Queries, envID pgtype.UUID, key, ifMatch string) error {
+	if ifMatch == "" {
+		return nil
+	}
+
+	var current string
+	v, err := q.LockVariable(ctx, repo.LockVariableParams{
+		EnvironmentID: envID,
+		Key:           key,
+	})
+	switch {
+	case err == nil:
+		current = etag(v.ID, v.Version)
+	case err != pgx.ErrNoRows:
+		return fmt.Errorf("failed to fetch variable: %w", err)
+	default:
+		env, err := s.repo.GetEnvironment(ctx, envID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch environment: %w", err)
+		}
+		vars, err := s.effective(ctx, env)
+		if err != nil {
+			return err
+		}
+		for _, l := range vars {
+			if l.v.Key == key {
+				current = etag(l.v.ID, l.v.Version)
+			}
+		}
+	}
+
+	if !matchETag(ifMatch, current) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestETag(t *testing.T) {
+	id := pgtype.UUID{Bytes: [16]byte{0xab, 0xcd}, Valid: true}
+	other := pgtype.UUID{Bytes: [16]byte{0xef}, Valid: true}
+
+	if got, want := etag(id, 3), `"abcd0000000000000000000000000000.3"`; got != want {
+		t.Errorf("etag() = %s, want %s", got, want)
+	}
+	if etag(id, 3) == etag(id, 4) {
+		t.Error("etag() does not change with the version")
+	}
+	if etag(id, 3) == etag(other, 3) {
+		t.Error("etag() does not change with the id")
+	}
+	if got, want := revealedETag(`"abc.3"`), `"abc.3;revealed"`; got != want {
+		t.Errorf("revealedETag() = %s, want %s", got, want)
+	}
+	if got := revealedETag(""); got != "" {
+		t.Errorf("revealedETag(\"\") = %s, want none", got)
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		tag    string
+		want   bool
+	}{
+		{name: "same tag", header: `"a.1"`, tag: `"a.1"`, want: true},
+		{name: "other version", header: `"a.1"`, tag: `"a.2"`, want: false},
+		{name: "one of several", header: `"a.0", "a.1"`, tag: `"a.1"`, want: true},
+		{name: "none of several", header: `"a.0","b.1"`, tag: `"a.1"`, want: false},
+		{name: "wildcard", header: `*`, tag: `"a.1"`, want: true},
+		{name: "wildcard without resource", header: `*`, tag: "", want: false},
+		{name: "weak tags never match", header: `W/"a.1"`, tag: `"a.1"`, want: false},
+		{name: "revealed representation", header: `"a.1;revealed"`, tag: `"a.1"`, want: true},
+		{name: "revealed representation of other version", header: `"a.0;revealed"`, tag: `"a.1"`, want: false},
+		{name: "unquoted", header: `a.1`, tag: `"a.1"`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchETag(tt.header, tt.tag); got != tt.want {
+				t.Errorf("matchETag(%q, %q) = %v, want %v", tt.header, tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoneMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		tag    string
+		want   bool
+	}{
+		{name: "no header", header: "", tag: `"a.1"`, want: false},
+		{name: "no tag", header: `"a.1"`, tag: "", want: false},
+		{name: "same tag", header: `"a.1"`, tag: `"a.1"`, want: true},
+		{name: "other version", header: `"a.1"`, tag: `"a.2"`, want: false},
+		{name: "one of several", header: `"a.0" , "a.1"`, tag: `"a.1"`, want: true},
+		{name: "wildcard", header: `*`, tag: `"a.1"`, want: true},
+		{name: "weak header", header: `W/"a.1"`, tag: `"a.1"`, want: true},
+		{name: "weak tag", header: `"a.1"`, tag: `W/"a.1"`, want: true},
+		{name: "both weak", header: `W/"a.1"`, tag: `W/"a.1"`, want: true},
+		{name: "revealed representation", header: `"a.1;revealed"`, tag: `"a.1"`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := noneMatch(tt.header, tt.tag); got != tt.want {
+				t.Errorf("noneMatch(%q, %q) = %v, want %v", tt.header, tt.tag, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+type ExportFormat string
+
+const (
+	FormatDotenv        ExportFormat = "dotenv"
+	FormatJSON          ExportFormat = "json"
+	FormatYAML          ExportFormat = "yaml"
+	FormatShell         ExportFormat = "shell"
+	FormatDocker        ExportFormat = "docker"
+	FormatK8sSecret     ExportFormat = "k8s-secret"
+	FormatK8sConfigMap  ExportFormat = "k8s-configmap"
+	defaultExportFormat              = FormatDotenv
+)
+
+var formatContentTypes = map[ExportFormat]string{
+	FormatDotenv:       "text/plain; charset=utf-8",
+	FormatJSON:         "application/json",
+	FormatYAML:         "application/yaml",
+	FormatShell:        "text/x-shellscript; charset=utf-8",
+	FormatDocker:       "text/plain; charset=utf-8",
+	FormatK8sSecret:    "application/yaml",
+	FormatK8sConfigMap: "application/yaml",
+}
+
+var acceptFormats = map[string]ExportFormat{
+	"application/json":   FormatJSON,
+	"application/yaml":   FormatYAML,
+	"application/x-yaml": FormatYAML,
+	"text/yaml":          FormatYAML,
+	"text/x-shellscript": FormatShell,
+	"text/plain":         FormatDotenv,
+}
+
+// FormatFromAccept picks the first media type of an Accept header that maps to
+// an export format.
+func FormatFromAccept(accept string) (ExportFormat, bool) {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if format, ok := acceptFormats[mediaType]; ok {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+type ExportOptions struct {
+	Format ExportFormat
+	// Name and Namespace are used for the metadata of Kubernetes manifests.
+	Name      string
+	Namespace string
+	// Resolve expands references before rendering, checking Access for every
+	// referenced environment and RevealAccess for every project a revealed
+	// secret comes from.
+	Resolve      bool
+	Access       AccessFunc
+	RevealAccess AccessFunc
+	// Filter limits the export to variables with matching metadata.
+	Filter VariableFilter
+	// Keys, if set, leaves out the variables the caller may not read.
+	Keys KeyAccessFunc
+}
+
+// Export is a rendered environment.
+type Export struct {
+	ContentType string
+	Body        []byte
+	// Omitted lists secret keys left out because the caller cannot see them.
+	Omitted []string
+	// Restricted lists keys left out because of the access rules of the
+	// environment.
+	Restricted []string
+	// Revealed lists the secrets included in plaintext by the environment
+	// they come from.
+	Revealed []RevealSource
+}
+
+func (s *svc) ExportVariables(ctx context.Context, envID pgtype.UUID, opts ExportOptions, reveal bool) (Export, error) {
+	if opts.Format == "" {
+		opts.Format = defaultExportFormat
+	}
+	contentType, ok := formatContentTypes[opts.Format]
+	if !ok {
+		return Export{}, fmt.Errorf("%w: %s", ErrUnsupportedFormat, opts.Format)
+	}
+
+	var vars []Variable
+	var res *Resolution
+	if opts.Resolve {
+		resolution, err := s.ResolveVariables(ctx, ResolveParams{
+			EnvironmentID: envID,
+			Reveal:        reveal,
+			Access:        opts.Access,
+			Keys:          opts.Keys,
+			RevealAccess:  opts.RevealAccess,
+		})
+		if err != nil {
+			return Export{}, err
+		}
+		res = &resolution
+		vars = res.Variables
+	} else {
+		var err error
+		vars, err = s.ListVariables(ctx, envID, reveal)
+		if err != nil {
+			return Export{}, err
+		}
+		if opts.Keys != nil {
+			env, err := s.repo.GetEnvironment(ctx, envID)
+			if err != nil {
+				return Export{}, fmt.Errorf("failed to fetch environment: %w", err)
+			}
+			access, err := opts.Keys(ctx, env)
+			if err != nil {
+				return Export{}, err
+			}
+			vars = access.Redact(vars)
+		}
+	}
+
+	vars = filterVariables(vars, opts.Filter)
+	out := Export{ContentType: contentType, Omitted: []string{}, Restricted: []string{}}
+	visible := make([]Variable, 0, len(vars))
+	var revealed []string
+	for _, v := range vars {
+		if v.Restricted {
+			out.Restricted = append(out.Restricted, v.Key)
+			continue
+		}
+		if v.Masked {
+			out.Omitted = append(out.Omitted, v.Key)
+			continue
+		}
+		if v.IsSecret || (res != nil && slices.Contains(res.Revealed, v.Key)) {
+			revealed = append(revealed, v.Key)
+		}
+		visible = append(visible, v)
+	}
+	switch {
+	case res != nil:
+		out.Revealed = res.Sources(revealed)
+	case len(revealed) > 0:
+		env, err := s.repo.GetEnvironment(ctx, envID)
+		if err != nil {
+			return Export{}, fmt.Errorf("failed to fetch environment: %w", err)
+		}
+		out.Revealed = []RevealSource{{Environment: env, Keys: revealed}}
+	}
+
+	var err error
+	out.Body, err = render(visible, opts)
+	if err != nil {
+		return Export{}, err
+	}
+	return out, nil
+}
+
+// envNamePattern matches the keys that are valid environment variable names
+// for the shell, dotenv and docker formats. Keys may also contain hyphens or
+// start with a digit, which those formats cannot represent.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func checkEnvNames(vars []Variable, format ExportFormat) error {
+	for _, v := range vars {
+		if !envNamePattern.MatchString(v.Key) {
+			return fmt.Errorf("%w: %s is not a valid variable name in %s format", ErrUnsupportedFormat, v.Key, format)
+		}
+	}
+	return nil
+}
+
+func render(vars []Variable, opts ExportOptions) ([]byte, error) {
+	var b bytes.Buffer
+	switch opts.Format {
+	case FormatDotenv, FormatShell, FormatDocker:
+		if err := checkEnvNames(vars, opts.Format); err != nil {
+			return nil, err
+		}
+	}
+
+	switch opts.Format {
+	case FormatDotenv:
+		for _, v := range vars {
+			writeComments(&b, "", v)
+			fmt.Fprintf(&b, "%s=%s\n", v.Key, dotenvQuote(v.Value))
+		}
+	case FormatShell:
+		b.WriteString("#!/bin/sh\n")
+		for _, v := range vars {
+			writeComments(&b, "", v)
+			fmt.Fprintf(&b, "export %s=%s\n", v.Key, shellQuote(v.Value))
+		}
+	case FormatDocker:
+		// docker --env-file takes everything after '=' literally and has no
+		// way to represent line breaks.
+		for _, v := range vars {
+			if strings.ContainsAny(v.Value, "\r\n") {
+				return nil, fmt.Errorf("%w: value of %s contains a line break, which docker env files cannot represent", ErrUnsupportedFormat, v.Key)
+			}
+			writeComments(&b, "", v)
+			fmt.Fprintf(&b, "%s=%s\n", v.Key, v.Value)
+		}
+	case FormatJSON:
+		values := make(map[string]string, len(vars))
+		for _, v := range vars {
+			values[v.Key] = v.Value
+		}
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(values); err != nil {
+			return nil, err
+		}
+	case FormatYAML:
+		if len(vars) == 0 {
+			b.WriteString("{}\n")
+		}
+		for _, v := range vars {
+			writeComments(&b, "", v)
+			fmt.Fprintf(&b, "%s: %s\n", yamlQuote(v.Key), yamlQuote(v.Value))
+		}
+	case FormatK8sSecret, FormatK8sConfigMap:
+		kind := "ConfigMap"
+		if opts.Format == FormatK8sSecret {
+			kind = "Secret"
+		}
+		b.WriteString("apiVersion: v1\n")
+		fmt.Fprintf(&b, "kind: %s\n", kind)
+		b.WriteString("metadata:\n")
+		fmt.Fprintf(&b, "  name: %s\n", yamlQuote(opts.Name))
+		if opts.Namespace != "" {
+			fmt.Fprintf(&b, "  namespace: %s\n", yamlQuote(opts.Namespace))
+		}
+		if kind == "Secret" {
+			b.WriteString("type: Opaque\n")
+		}
+		if len(vars) == 0 {
+			b.WriteString("data: {}\n")
+			break
+		}
+		b.WriteString("data:\n")
+		for _, v := range vars {
+			value := yamlQuote(v.Value)
+			if kind == "Secret" {
+				value = yamlQuote(base64.StdEncoding.EncodeToString([]byte(v.Value)))
+			}
+			writeComments(&b, "  ", v)
+			fmt.Fprintf(&b, "  %s: %s\n", yamlQuote(v.Key), value)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, opts.Format)
+	}
+	return b.Bytes(), nil
+}
+
+// writeComments writes the metadata of a variable and the environment an
+// inherited variable comes from as comments. Every format except JSON
+// supports # comments.
+func writeComments(b *bytes.Buffer, indent string, v Variable) {
+	if m := v.Metadata; m != nil {
+		if m.Description != "" {
+			for _, line := range strings.Split(strings.ReplaceAll(m.Description, "\r", ""), "\n") {
+				fmt.Fprintf(b, "%s# %s\n", indent, line)
+			}
+		}
+		if m.Owner != "" {
+			fmt.Fprintf(b, "%s# owner: %s\n", indent, m.Owner)
+		}
+		if len(m.Tags) > 0 {
+			fmt.Fprintf(b, "%s# tags: %s\n", indent, strings.Join(m.Tags, ", "))
+		}
+		for _, link := range m.Links {
+			fmt.Fprintf(b, "%s# see %s\n", indent, link)
+		}
+	}
+	if v.Inherited {
+		fmt.Fprintf(b, "%s# inherited from %s\n", indent, v.Source)
+	}
+}
+
+var plainDotenvValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+// dotenvQuote leaves simple values bare and double quotes everything else,
+// escaping the sequences understood by dotenv parsers.
+func dotenvQuote(value string) string {
+	if plainDotenvValue.MatchString(value) {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// shellQuote wraps value in single quotes, which POSIX shells take literally.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// yamlQuote returns a double quoted YAML scalar. Go escape sequences are a
+// subset of the ones YAML supports in double quoted strings.
+func yamlQuote(value string) string {
+	return strconv.Quote(value)
+}
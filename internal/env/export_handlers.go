@@ -0,0 +1,61 @@
+package env
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ExportVariables renders the environment in the format given by the format
+// query parameter or, if it is absent, the Accept header.
+// The tag and owner query parameters limit the export like in ListVariables.
+// Variables the caller may not read are left out and listed in the
+// X-Restricted-Variables header.
+func (h *handler) ExportVariables(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	reveal, reason, ok := h.revealRequested(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	format := ExportFormat(query.Get("format"))
+	if format == "" {
+		format, _ = FormatFromAccept(r.Header.Get("Accept"))
+	}
+	name := query.Get("name")
+	if name == "" {
+		name = env.Slug
+	}
+
+	export, err := h.service.ExportVariables(r.Context(), env.ID, ExportOptions{
+		Format:       format,
+		Name:         name,
+		Namespace:    query.Get("namespace"),
+		Resolve:      query.Get("resolve") == "true",
+		Access:       h.readAccess(userID),
+		RevealAccess: h.revealAccess(userID),
+		Filter:       variableFilter(r),
+		Keys:         h.keysAccess(userID),
+	}, reveal)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	if reveal && !h.auditRevealSources(w, r, userID, reason, export.Revealed) {
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType)
+	if len(export.Omitted) > 0 {
+		w.Header().Set("X-Omitted-Secrets", strings.Join(export.Omitted, ","))
+	}
+	if len(export.Restricted) > 0 {
+		w.Header().Set("X-Restricted-Variables", strings.Join(export.Restricted, ","))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(export.Body)
+}
@@ -0,0 +1,140 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/pkg/secretgen"
+)
+
+var ErrNotGenerated = errors.New("variable was not generated by the server")
+
+type RegenerateParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Key           string      `json:"-"`
+	AuthorID      pgtype.UUID `json:"-"`
+	Message       string      `json:"message"`
+	// IfMatch is checked like SetVariableParams.IfMatch.
+	IfMatch string `json:"-"`
+}
+
+// generate fills in the value of c from spec. Generated values are always
+// stored as secrets so that they are never shown without an explicit reveal.
+func generate(c *change, spec *secretgen.Spec) (secretgen.Result, error) {
+	if err := spec.Normalize(); err != nil {
+		return secretgen.Result{}, err
+	}
+	res, err := secretgen.Generate(*spec)
+	if err != nil {
+		return secretgen.Result{}, fmt.Errorf("failed to generate value: %w", err)
+	}
+	c.Value = res.Value
+	c.IsSecret = true
+	return res, nil
+}
+
+// saveGenerator records the spec a variable was generated from, or forgets it
+// when the value was set by hand.
+func saveGenerator(ctx context.Context, q *repo.Queries, v repo.Variable, spec *secretgen.Spec) error {
+	if spec == nil {
+		if err := q.DeleteVariableGenerator(ctx, v.ID); err != nil {
+			return fmt.Errorf("failed to remove generator: %w", err)
+		}
+		return nil
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	if _, err := q.UpsertVariableGenerator(ctx, repo.UpsertVariableGeneratorParams{
+		VariableID: v.ID,
+		Spec:       data,
+	}); err != nil {
+		return fmt.Errorf("failed to save generator: %w", err)
+	}
+	return nil
+}
+
+// generator returns the spec the variable was generated from, or nil.
+func (s *svc) generator(ctx context.Context, variableID pgtype.UUID) (*secretgen.Spec, error) {
+	stored, err := s.repo.GetVariableGenerator(ctx, variableID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch generator: %w", err)
+	}
+	var spec secretgen.Spec
+	if err := json.Unmarshal(stored.Spec, &spec); err != nil {
+		return nil, fmt.Errorf("failed to decode generator: %w", err)
+	}
+	return &spec, nil
+}
+
+// RegenerateVariable replaces the value of a generated variable with a fresh
+// one from its recorded spec. The new value is recorded as a regular update in
+// the version history and counts as a rotation.
+func (s *svc) RegenerateVariable(ctx context.Context, params RegenerateParams) (Variable, error) {
+	if err := s.checkUnprotected(ctx, params.EnvironmentID); err != nil {
+		return Variable{}, err
+	}
+
+	current, err := s.localVariable(ctx, params.EnvironmentID, params.Key)
+	if err != nil {
+		return Variable{}, err
+	}
+	spec, err := s.generator(ctx, current.ID)
+	if err != nil {
+		return Variable{}, err
+	}
+	if spec == nil {
+		return Variable{}, ErrNotGenerated
+	}
+
+	message := params.Message
+	if message == "" {
+		message = "regenerated"
+	}
+	c := change{Key: params.Key, AuthorID: params.AuthorID, Message: message}
+	res, err := generate(&c, spec)
+	if err != nil {
+		return Variable{}, err
+	}
+	if err := s.checkChanges(ctx, params.EnvironmentID, []change{c}, nil); err != nil {
+		return Variable{}, err
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, params.EnvironmentID)
+	if err != nil {
+		return Variable{}, err
+	}
+	var v repo.Variable
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, params.EnvironmentID); err != nil {
+			return err
+		}
+		if err := s.checkETag(ctx, q, params.EnvironmentID, params.Key, params.IfMatch); err != nil {
+			return err
+		}
+		var err error
+		v, err = s.apply(ctx, q, params.EnvironmentID, dataKey, c)
+		return err
+	})
+	if err != nil {
+		return Variable{}, err
+	}
+
+	out, err := toVariable(dataKey, v, false)
+	if err != nil {
+		return Variable{}, err
+	}
+	out.Generator = spec
+	out.PublicKey = res.PublicKey
+	return out, nil
+}
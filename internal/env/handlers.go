@@ -1,12 +1,18 @@
 package env
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
 	"github.com/envm-org/envm/internal/auth"
+	"github.com/envm-org/envm/internal/middleware"
 	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	authPkg "github.com/envm-org/envm/pkg/auth"
+	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -22,6 +28,152 @@ func NewHandler(service Service, authorizer auth.Authorizer) *handler {
 	}
 }
 
+// authorizeEnv resolves the environment from the {id} URL parameter and checks
+// that the caller may read it or, for requests other than GET and HEAD, write
+// to it. On failure the error response has already been written and ok is
+// false.
+func (h *handler) authorizeEnv(w http.ResponseWriter, r *http.Request) (env repo.Environment, userID pgtype.UUID, ok bool) {
+	required := auth.AccessWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		required = auth.AccessRead
+	}
+	return h.authorizeEnvAccess(w, r, required)
+}
+
+// authorizeEnvAccess is authorizeEnv for requests whose method does not say
+// whether they change the environment.
+func (h *handler) authorizeEnvAccess(w http.ResponseWriter, r *http.Request, required auth.Access) (env repo.Environment, userID pgtype.UUID, ok bool) {
+	var envID pgtype.UUID
+	if err := envID.Scan(chi.URLParam(r, "id")); err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return env, userID, false
+	}
+
+	userID, ok = requestUser(w, r)
+	if !ok {
+		return env, userID, false
+	}
+
+	env, err := h.service.GetEnv(r.Context(), envID)
+	if err != nil {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return env, userID, false
+	}
+
+	if err := h.checkEnvAccess(r.Context(), userID, env, required); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return env, userID, false
+	}
+
+	return env, userID, true
+}
+
+// checkEnvAccess checks that the project role of userID grants at least
+// required on env.
+func (h *handler) checkEnvAccess(ctx context.Context, userID pgtype.UUID, env repo.Environment, required auth.Access) error {
+	access, err := h.authorizer.EnvironmentAccess(ctx, userID, env)
+	if err != nil {
+		return err
+	}
+	if !access.Allows(required) {
+		return fmt.Errorf("insufficient permissions: %s access to environment %s required", required, env.Slug)
+	}
+	return nil
+}
+
+// authorizeManage checks that the caller may create, change and delete the
+// environments of the project envID belongs to. On failure the error response
+// has already been written and false is returned.
+func (h *handler) authorizeManage(w http.ResponseWriter, r *http.Request, envID pgtype.UUID) bool {
+	userID, ok := requestUser(w, r)
+	if !ok {
+		return false
+	}
+	env, err := h.service.GetEnv(r.Context(), envID)
+	if err != nil {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return false
+	}
+	if err := h.authorizer.CanManageEnvironments(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requestUser returns the ID of the authenticated user. On failure the error
+// response has already been written and ok is false.
+func requestUser(w http.ResponseWriter, r *http.Request) (userID pgtype.UUID, ok bool) {
+	claims, isClaims := r.Context().Value(middleware.UserKey).(*authPkg.Claims)
+	if !isClaims {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return userID, false
+	}
+	if err := userID.Scan(claims.UserID); err != nil {
+		http.Error(w, "invalid user id in token", http.StatusUnauthorized)
+		return userID, false
+	}
+	return userID, true
+}
+
+// revealRequested handles the reveal=true query parameter of endpoints that can
+// return plaintext secrets. Revealing requires permission and a reason given in
+// the reason query parameter. On failure the error response has already been
+// written and ok is false.
+func (h *handler) revealRequested(w http.ResponseWriter, r *http.Request, env repo.Environment, userID pgtype.UUID) (reveal bool, reason string, ok bool) {
+	if r.URL.Query().Get("reveal") != "true" {
+		return false, "", true
+	}
+	if err := h.authorizer.CanRevealSecrets(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false, "", false
+	}
+	reason = r.URL.Query().Get("reason")
+	if strings.TrimSpace(reason) == "" {
+		http.Error(w, ErrReasonRequired.Error(), http.StatusBadRequest)
+		return false, "", false
+	}
+	return true, reason, true
+}
+
+// auditReveal records the secret keys about to be sent in plaintext. It must
+// be called before the response is written; on failure nothing is revealed.
+func (h *handler) auditReveal(w http.ResponseWriter, r *http.Request, env repo.Environment, userID pgtype.UUID, reason string, keys []string) bool {
+	if err := h.service.AuditReveal(r.Context(), env.ID, userID, keys, reason); err != nil {
+		writeVariableError(w, err)
+		return false
+	}
+	return true
+}
+
+// auditRevealSources records the reveal of secrets coming from several
+// environments, one audit entry per environment. It must be called before the
+// response is written; on failure nothing is revealed.
+func (h *handler) auditRevealSources(w http.ResponseWriter, r *http.Request, userID pgtype.UUID, reason string, sources []RevealSource) bool {
+	for _, source := range sources {
+		if !h.auditReveal(w, r, source.Environment, userID, reason, source.Keys) {
+			return false
+		}
+	}
+	return true
+}
+
+// revealAccess checks that userID may reveal the secrets of environments
+// referenced while resolving variables.
+func (h *handler) revealAccess(userID pgtype.UUID) AccessFunc {
+	return func(ctx context.Context, env repo.Environment) error {
+		return h.authorizer.CanRevealSecrets(ctx, userID, env.ProjectID)
+	}
+}
+
+// readAccess checks that userID may read environments referenced while
+// resolving variables.
+func (h *handler) readAccess(userID pgtype.UUID) AccessFunc {
+	return func(ctx context.Context, env repo.Environment) error {
+		return h.checkEnvAccess(ctx, userID, env, auth.AccessRead)
+	}
+}
+
 func (h *handler) ListEnvs(w http.ResponseWriter, r *http.Request) {
 	projectIDStr := r.URL.Query().Get("project_id")
 	if projectIDStr == "" {
@@ -34,13 +186,30 @@ func (h *handler) ListEnvs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.HasProjectAccess(r.Context(), userID, projectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	envs, err := h.service.ListEnvs(r.Context(), projectID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	
-	HTTPwriter.JSON(w, http.StatusOK, envs)
+	// Leave out the environments the caller's role has no access to.
+	visible := make([]repo.Environment, 0, len(envs))
+	for _, env := range envs {
+		if err := h.checkEnvAccess(r.Context(), userID, env, auth.AccessRead); err == nil {
+			visible = append(visible, env)
+		}
+	}
+	envs = visible
+
+	writeTaggedJSON(w, r, envs)
 }
 
 func (h *handler) CreateEnv(w http.ResponseWriter, r *http.Request) {
@@ -50,7 +219,15 @@ func (h *handler) CreateEnv(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	
+	userID, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanManageEnvironments(r.Context(), userID, tempEnv.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	env, err := h.service.CreateEnv(r.Context(), tempEnv)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -71,13 +248,24 @@ func (h *handler) GetEnv(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+
 	env, err := h.service.GetEnv(r.Context(), envID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	
-	
+	if err := h.checkEnvAccess(r.Context(), userID, env, auth.AccessRead); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if notModified(w, r, EnvironmentETag(env)) {
+		return
+	}
+
 	HTTPwriter.JSON(w, http.StatusOK, env)
 }
 
@@ -100,11 +288,35 @@ func (h *handler) UpdateEnv(w http.ResponseWriter, r *http.Request) {
 	}
 	tempEnv.ID = envID
 
+	if !h.authorizeManage(w, r, envID) {
+		return
+	}
+
+	// With If-Match the update only applies to the version the client holds.
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		current, err := h.service.GetEnv(r.Context(), envID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !matchETag(ifMatch, EnvironmentETag(current)) {
+			writeVariableError(w, ErrPreconditionFailed)
+			return
+		}
+		tempEnv.Version = pgtype.Int4{Int32: current.Version, Valid: true}
+	}
+
+	r, ok := h.lockOverride(w, r, tempEnv.ID)
+	if !ok {
+		return
+	}
+
 	env, err := h.service.UpdateEnv(r.Context(), tempEnv)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeVariableError(w, err)
 		return
 	}
+	w.Header().Set("ETag", EnvironmentETag(env))
 	HTTPwriter.JSON(w, http.StatusOK, env)
 }
 
@@ -120,9 +332,18 @@ func (h *handler) DeleteEnv(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeManage(w, r, envID) {
+		return
+	}
+
+	r, ok := h.lockOverride(w, r, envID)
+	if !ok {
+		return
+	}
+
 	err := h.service.DeleteEnv(r.Context(), envID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeVariableError(w, err)
 		return
 	}
 	HTTPwriter.JSON(w, http.StatusOK, nil)
@@ -0,0 +1,334 @@
+package env
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+// Thresholds of the hygiene report. A secret shorter than minSecretLength is
+// reported as short, one whose estimated entropy is below
+// minSecretEntropyBits as low entropy.
+const (
+	defaultSecretMaxAge  = 90 * 24 * time.Hour
+	minSecretLength      = 16
+	minSecretEntropyBits = 56
+)
+
+// Reasons a secret is reported as weak.
+const (
+	weakShort      = "short"
+	weakLowEntropy = "low_entropy"
+)
+
+// Reasons a secret is reported as stale: it is overdue under its own rotation
+// policy, or it has none and is older than the maximum age of the report.
+const (
+	staleRotation = "rotation"
+	staleMaxAge   = "max_age"
+)
+
+// Environment slugs that are compared by the production to development check.
+var (
+	productionSlugs  = []string{"prod", "production"}
+	developmentSlugs = []string{"dev", "development"}
+)
+
+// HygienePolicy configures a hygiene report. Secrets without a rotation policy
+// are stale once they have not been changed for MaxAge.
+type HygienePolicy struct {
+	MaxAge time.Duration
+}
+
+// SecretLocation is where a secret is defined.
+type SecretLocation struct {
+	ProjectID     pgtype.UUID `json:"project_id"`
+	Project       string      `json:"project"`
+	EnvironmentID pgtype.UUID `json:"environment_id"`
+	Environment   string      `json:"environment"`
+	Key           string      `json:"key"`
+}
+
+// ReusedSecret is a secret value defined in more than one environment. Hash is
+// keyed for the report it appears in and only identifies the value within it.
+type ReusedSecret struct {
+	Hash      string           `json:"hash"`
+	Locations []SecretLocation `json:"locations"`
+}
+
+type WeakSecret struct {
+	SecretLocation
+	Reason string `json:"reason"`
+}
+
+type AgedSecret struct {
+	SecretLocation
+	UpdatedAt time.Time `json:"updated_at"`
+	Reason    string    `json:"reason"`
+}
+
+// ProductionMatch is a production secret whose effective value is the same as
+// in development.
+type ProductionMatch struct {
+	ProjectID   pgtype.UUID `json:"project_id"`
+	Project     string      `json:"project"`
+	Key         string      `json:"key"`
+	Production  string      `json:"production"`
+	Development string      `json:"development"`
+}
+
+// HygieneReport flags risky secrets of an organization. It never contains
+// values. Ephemeral environments are left out since they are copies by design.
+type HygieneReport struct {
+	OrganizationID pgtype.UUID       `json:"organization_id"`
+	GeneratedAt    time.Time         `json:"generated_at"`
+	MaxAge         string            `json:"max_age"`
+	Reused         []ReusedSecret    `json:"reused"`
+	Weak           []WeakSecret      `json:"weak"`
+	Stale          []AgedSecret      `json:"stale"`
+	ProductionDev  []ProductionMatch `json:"production_matches_development"`
+}
+
+// Findings returns the number of problems in the report.
+func (r HygieneReport) Findings() int {
+	return len(r.Reused) + len(r.Weak) + len(r.Stale) + len(r.ProductionDev)
+}
+
+// entropyBits estimates the entropy of s from the frequency of its
+// characters. It underestimates random strings that are short, which are
+// reported as short anyway.
+func entropyBits(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var perChar float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		perChar -= p * math.Log2(p)
+	}
+	return perChar * float64(n)
+}
+
+// hygieneSecret is a secret seen by the report. The value is kept only as a
+// keyed hash.
+type hygieneSecret struct {
+	SecretLocation
+	hash string
+}
+
+// HygieneReport builds the hygiene report of the organization. Values are
+// decrypted one environment at a time and compared through a hash keyed for
+// this report only, so equal values can be found without keeping plaintext.
+func (s *svc) HygieneReport(ctx context.Context, orgID pgtype.UUID, policy HygienePolicy) (HygieneReport, error) {
+	if policy.MaxAge <= 0 {
+		policy.MaxAge = defaultSecretMaxAge
+	}
+	hashKey := make([]byte, 32)
+	if _, err := rand.Read(hashKey); err != nil {
+		return HygieneReport{}, err
+	}
+	hash := func(value string) string {
+		mac := hmac.New(sha256.New, hashKey)
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+
+	now := time.Now()
+	report := HygieneReport{
+		OrganizationID: orgID,
+		GeneratedAt:    now,
+		MaxAge:         policy.MaxAge.String(),
+		Reused:         []ReusedSecret{},
+		Weak:           []WeakSecret{},
+		Stale:          []AgedSecret{},
+		ProductionDev:  []ProductionMatch{},
+	}
+
+	projects, err := s.repo.ListProjects(ctx, orgID)
+	if err != nil {
+		return HygieneReport{}, fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	var local []hygieneSecret
+	for _, project := range projects {
+		envs, err := s.repo.ListEnvironments(ctx, project.ID)
+		if err != nil {
+			return HygieneReport{}, fmt.Errorf("failed to list environments: %w", err)
+		}
+		ephemeral, err := s.repo.ListEphemeralEnvironments(ctx, repo.ListEphemeralEnvironmentsParams{
+			ProjectID: project.ID,
+			ExpiresAt: pgtype.Timestamptz{Valid: true},
+		})
+		if err != nil {
+			return HygieneReport{}, fmt.Errorf("failed to list ephemeral environments: %w", err)
+		}
+
+		// Effective secrets of the production and development environments
+		// by key, as hashes.
+		production := make(map[string]hygieneSecret)
+		development := make(map[string]hygieneSecret)
+		for _, env := range envs {
+			if slices.ContainsFunc(ephemeral, func(e repo.ListEphemeralEnvironmentsRow) bool { return e.ID == env.ID }) {
+				continue
+			}
+			vars, err := s.effective(ctx, env)
+			if err != nil {
+				return HygieneReport{}, err
+			}
+
+			var ids []pgtype.UUID
+			for _, l := range vars {
+				if !l.v.IsSecret.Bool {
+					continue
+				}
+				v, err := s.toLayeredVariable(ctx, env, l, true)
+				if err != nil {
+					return HygieneReport{}, err
+				}
+				secret := hygieneSecret{
+					SecretLocation: SecretLocation{
+						ProjectID:     project.ID,
+						Project:       project.Slug,
+						EnvironmentID: env.ID,
+						Environment:   env.Slug,
+						Key:           v.Key,
+					},
+					hash: hash(v.Value),
+				}
+				if slices.Contains(productionSlugs, env.Slug) {
+					production[v.Key] = secret
+				}
+				if slices.Contains(developmentSlugs, env.Slug) {
+					development[v.Key] = secret
+				}
+				if v.Inherited || v.Value == "" {
+					continue
+				}
+
+				local = append(local, secret)
+				ids = append(ids, l.v.ID)
+				if len([]rune(v.Value)) < minSecretLength {
+					report.Weak = append(report.Weak, WeakSecret{SecretLocation: secret.SecretLocation, Reason: weakShort})
+				} else if entropyBits(v.Value) < minSecretEntropyBits {
+					report.Weak = append(report.Weak, WeakSecret{SecretLocation: secret.SecretLocation, Reason: weakLowEntropy})
+				}
+			}
+
+			stale, err := s.staleSecrets(ctx, env, vars, ids, now.Add(-policy.MaxAge))
+			if err != nil {
+				return HygieneReport{}, err
+			}
+			for i := range stale {
+				stale[i].ProjectID = project.ID
+				stale[i].Project = project.Slug
+			}
+			report.Stale = append(report.Stale, stale...)
+		}
+
+		keys := make([]string, 0, len(production))
+		for key := range production {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			prod := production[key]
+			dev, ok := development[key]
+			if ok && dev.hash == prod.hash {
+				report.ProductionDev = append(report.ProductionDev, ProductionMatch{
+					ProjectID:   project.ID,
+					Project:     project.Slug,
+					Key:         key,
+					Production:  prod.Environment,
+					Development: dev.Environment,
+				})
+			}
+		}
+	}
+
+	// Group the locally defined secrets by value, in the order they were
+	// first seen.
+	groups := make(map[string][]SecretLocation)
+	var order []string
+	for _, secret := range local {
+		if _, ok := groups[secret.hash]; !ok {
+			order = append(order, secret.hash)
+		}
+		groups[secret.hash] = append(groups[secret.hash], secret.SecretLocation)
+	}
+	for _, h := range order {
+		locations := groups[h]
+		envs := make(map[pgtype.UUID]bool)
+		for _, l := range locations {
+			envs[l.EnvironmentID] = true
+		}
+		if len(envs) > 1 {
+			report.Reused = append(report.Reused, ReusedSecret{Hash: h, Locations: locations})
+		}
+	}
+	return report, nil
+}
+
+// staleSecrets returns the secrets among ids, the local secrets of env, that
+// are overdue for rotation or, without a rotation policy, whose value was last
+// written before cutoff. Edits of metadata and policies do not count as
+// changes of the value.
+func (s *svc) staleSecrets(ctx context.Context, env repo.Environment, vars []layered, ids []pgtype.UUID, cutoff time.Time) ([]AgedSecret, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	stored, err := s.repo.ListVariableRotations(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rotation policies: %w", err)
+	}
+	rotations := make(map[pgtype.UUID]Rotation, len(stored))
+	for _, r := range stored {
+		rotations[r.VariableID] = toRotation(r)
+	}
+	times, err := s.repo.ListValueChangeTimes(ctx, env.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list variable versions: %w", err)
+	}
+	changed := make(map[string]time.Time, len(times))
+	for _, t := range times {
+		changed[t.Key] = t.ChangedAt.Time
+	}
+
+	var out []AgedSecret
+	for _, l := range vars {
+		if !slices.Contains(ids, l.v.ID) {
+			continue
+		}
+		aged := AgedSecret{
+			SecretLocation: SecretLocation{EnvironmentID: env.ID, Environment: env.Slug, Key: l.v.Key},
+			UpdatedAt:      changed[l.v.Key],
+		}
+		// Variables written before versions were recorded have none.
+		if aged.UpdatedAt.IsZero() {
+			aged.UpdatedAt = l.v.CreatedAt.Time
+		}
+		if rotation, ok := rotations[l.v.ID]; ok {
+			if rotation.Overdue {
+				aged.UpdatedAt = rotation.RotatedAt
+				aged.Reason = staleRotation
+				out = append(out, aged)
+			}
+		} else if aged.UpdatedAt.Before(cutoff) {
+			aged.Reason = staleMaxAge
+			out = append(out, aged)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,42 @@
+package env
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStaleSecretsIgnoreMetadata(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	if _, err := f.s.SetVariable(ctx, SetVariableParams{
+		EnvironmentID: f.env.ID,
+		Key:           "API_KEY",
+		Value:         "k3Jx9vQ2mLp8Rt5wZy7B",
+		IsSecret:      true,
+		AuthorID:      f.user,
+	}); err != nil {
+		t.Fatalf("SetVariable() error = %v", err)
+	}
+	// The value was written two years ago; editing the metadata today does
+	// not make it fresh.
+	if _, err := f.s.db.Exec(ctx, "UPDATE variable_versions SET created_at = now() - interval '2 years'"); err != nil {
+		t.Fatalf("failed to age the variable: %v", err)
+	}
+	if _, err := f.s.SetMetadata(ctx, SetMetadataParams{
+		EnvironmentID: f.env.ID,
+		Key:           "API_KEY",
+		AuthorID:      f.user,
+		Metadata:      Metadata{Owner: "payments"},
+	}); err != nil {
+		t.Fatalf("SetMetadata() error = %v", err)
+	}
+
+	report, err := f.s.HygieneReport(ctx, f.project.OrganizationID, HygienePolicy{MaxAge: 365 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("HygieneReport() error = %v", err)
+	}
+	if len(report.Stale) != 1 || report.Stale[0].Key != "API_KEY" || report.Stale[0].Reason != staleMaxAge {
+		t.Errorf("HygieneReport().Stale = %+v, want API_KEY past its maximum age", report.Stale)
+	}
+}
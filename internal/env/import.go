@@ -0,0 +1,220 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/pkg/dotenv"
+)
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// ImportMode decides how imported keys are combined with existing ones.
+type ImportMode string
+
+const (
+	// ImportMerge only adds keys that do not exist yet.
+	ImportMerge ImportMode = "merge"
+	// ImportOverwrite adds new keys and overwrites existing ones.
+	ImportOverwrite ImportMode = "overwrite"
+	// ImportReplace makes the environment match the file exactly, removing
+	// keys that are not in it.
+	ImportReplace ImportMode = "replace"
+)
+
+type ImportParams struct {
+	EnvironmentID pgtype.UUID
+	Content       string
+	Mode          ImportMode
+	DryRun        bool
+	// IsSecret marks added keys as secret. Existing keys keep their flag.
+	IsSecret bool
+	AuthorID pgtype.UUID
+	Message  string
+	// Keys is what the author may do with the variables of the environment.
+	// The import fails with ErrRestricted if it touches a key the author may
+	// not change, even one whose value is unchanged.
+	Keys KeyAccess
+}
+
+// ImportPlan lists the keys an import adds, changes or removes. Values are
+// never included so that a preview does not leak secrets.
+type ImportPlan struct {
+	Mode      ImportMode `json:"mode"`
+	DryRun    bool       `json:"dry_run"`
+	Added     []string   `json:"added"`
+	Changed   []string   `json:"changed"`
+	Removed   []string   `json:"removed"`
+	Unchanged []string   `json:"unchanged"`
+	// Skipped lists existing keys with a different value that merge mode kept.
+	Skipped []string `json:"skipped"`
+}
+
+// ImportVariables parses dotenv content and applies it to the environment in a
+// single transaction. With DryRun set only the plan is returned. The import
+// fails with ErrConcurrentChange if another request creates a key it adds.
+func (s *svc) ImportVariables(ctx context.Context, params ImportParams) (ImportPlan, error) {
+	if !params.DryRun {
+		if err := s.checkUnprotected(ctx, params.EnvironmentID); err != nil {
+			return ImportPlan{}, err
+		}
+	}
+
+	switch params.Mode {
+	case "":
+		params.Mode = ImportMerge
+	case ImportMerge, ImportOverwrite, ImportReplace:
+	default:
+		return ImportPlan{}, fmt.Errorf("%w: unknown mode %q", ErrInvalidImport, params.Mode)
+	}
+
+	entries, err := dotenv.Parse(params.Content)
+	if err != nil {
+		return ImportPlan{}, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	for _, e := range entries {
+		if err := validateKey(e.Key); err != nil {
+			return ImportPlan{}, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, e.Line, err)
+		}
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, params.EnvironmentID)
+	if err != nil {
+		return ImportPlan{}, err
+	}
+	if params.DryRun {
+		current, err := s.repo.ListVariables(ctx, params.EnvironmentID)
+		if err != nil {
+			return ImportPlan{}, fmt.Errorf("failed to list variables: %w", err)
+		}
+		plan, _, err := s.planImport(ctx, params, entries, current, dataKey)
+		return plan, err
+	}
+
+	var plan ImportPlan
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, params.EnvironmentID); err != nil {
+			return err
+		}
+		// The plan is made from the locked variables so that a concurrent
+		// write cannot change what the import overwrites or skips.
+		current, err := q.LockVariables(ctx, params.EnvironmentID)
+		if err != nil {
+			return fmt.Errorf("failed to list variables: %w", err)
+		}
+		var changes []change
+		if plan, changes, err = s.planImport(ctx, params, entries, current, dataKey); err != nil {
+			return err
+		}
+		for _, c := range changes {
+			if _, err := s.apply(ctx, q, params.EnvironmentID, dataKey, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportPlan{}, err
+	}
+	return plan, nil
+}
+
+// planImport compares the entries with the current variables of the
+// environment and returns the plan and the changes that carry it out.
+func (s *svc) planImport(ctx context.Context, params ImportParams, entries []dotenv.Entry, current []repo.Variable, dataKey []byte) (ImportPlan, []change, error) {
+	existing := make(map[string]repo.Variable, len(current))
+	for _, v := range current {
+		existing[v.Key] = v
+	}
+
+	message := params.Message
+	if message == "" {
+		message = "import from .env"
+	}
+
+	plan := ImportPlan{
+		Mode:      params.Mode,
+		DryRun:    params.DryRun,
+		Added:     []string{},
+		Changed:   []string{},
+		Removed:   []string{},
+		Unchanged: []string{},
+		Skipped:   []string{},
+	}
+	var changes []change
+	imported := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		imported[e.Key] = true
+
+		v, ok := existing[e.Key]
+		if !ok {
+			// Existing keys are locked, but another request can still
+			// create a key that the import adds.
+			plan.Added = append(plan.Added, e.Key)
+			changes = append(changes, change{
+				Key:      e.Key,
+				Value:    e.Value,
+				IsSecret: params.IsSecret,
+				Base:     pgtype.Text{Valid: true},
+				AuthorID: params.AuthorID,
+				Message:  message,
+			})
+			continue
+		}
+
+		value, err := openValue(dataKey, v.EnvironmentID, v.Key, v.Value, v.Encrypted)
+		if err != nil {
+			return ImportPlan{}, nil, fmt.Errorf("failed to decrypt variable %s: %w", v.Key, err)
+		}
+		switch {
+		case value == e.Value:
+			plan.Unchanged = append(plan.Unchanged, e.Key)
+		case params.Mode == ImportMerge:
+			plan.Skipped = append(plan.Skipped, e.Key)
+		default:
+			plan.Changed = append(plan.Changed, e.Key)
+			changes = append(changes, change{
+				Key:      e.Key,
+				Value:    e.Value,
+				IsSecret: v.IsSecret.Bool,
+				AuthorID: params.AuthorID,
+				Message:  message,
+			})
+		}
+	}
+
+	if params.Mode == ImportReplace {
+		for _, v := range current {
+			if imported[v.Key] {
+				continue
+			}
+			plan.Removed = append(plan.Removed, v.Key)
+			changes = append(changes, change{
+				Key:      v.Key,
+				Delete:   true,
+				AuthorID: params.AuthorID,
+				Message:  message,
+			})
+		}
+	}
+
+	touched := make([]string, 0, len(entries)+len(plan.Removed))
+	for _, e := range entries {
+		touched = append(touched, e.Key)
+	}
+	if err := checkKeys(append(touched, plan.Removed...), params.Keys.CanWrite); err != nil {
+		return ImportPlan{}, nil, err
+	}
+
+	if len(changes) == 0 {
+		return plan, nil, nil
+	}
+	if err := s.checkChanges(ctx, params.EnvironmentID, changes, nil); err != nil {
+		return ImportPlan{}, nil, err
+	}
+	return plan, changes, nil
+}
@@ -0,0 +1,52 @@
+package env
+
+import (
+	"io"
+	"net/http"
+
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+)
+
+// maxImportSize caps the size of an uploaded dotenv file.
+const maxImportSize = 1 << 20
+
+// ImportVariables accepts the raw dotenv content as the request body. The mode,
+// dry_run, is_secret and message options are read from the query string.
+func (h *handler) ImportVariables(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		http.Error(w, "request body too large or unreadable", http.StatusBadRequest)
+		return
+	}
+
+	access, ok := h.keyAccess(w, r, env, userID)
+	if !ok {
+		return
+	}
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	plan, err := h.service.ImportVariables(r.Context(), ImportParams{
+		EnvironmentID: env.ID,
+		Content:       string(content),
+		Mode:          ImportMode(query.Get("mode")),
+		DryRun:        query.Get("dry_run") == "true",
+		IsSecret:      query.Get("is_secret") == "true",
+		AuthorID:      userID,
+		Message:       query.Get("message"),
+		Keys:          access,
+	})
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, plan)
+}
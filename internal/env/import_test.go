@@ -0,0 +1,107 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+func TestImportVariables(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  ImportMode
+		plan  ImportPlan
+		after map[string]string
+	}{
+		{
+			name:  "merge",
+			mode:  ImportMerge,
+			plan:  ImportPlan{Added: []string{"NEW"}, Unchanged: []string{"PORT"}, Skipped: []string{"DEBUG"}},
+			after: map[string]string{"PORT": "8080", "DEBUG": "true", "OLD": "1", "NEW": "x"},
+		},
+		{
+			name:  "overwrite",
+			mode:  ImportOverwrite,
+			plan:  ImportPlan{Added: []string{"NEW"}, Changed: []string{"DEBUG"}, Unchanged: []string{"PORT"}},
+			after: map[string]string{"PORT": "8080", "DEBUG": "false", "OLD": "1", "NEW": "x"},
+		},
+		{
+			name:  "replace",
+			mode:  ImportReplace,
+			plan:  ImportPlan{Added: []string{"NEW"}, Changed: []string{"DEBUG"}, Removed: []string{"OLD"}, Unchanged: []string{"PORT"}},
+			after: map[string]string{"PORT": "8080", "DEBUG": "false", "NEW": "x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.set(t, f.env.ID, "PORT", "8080")
+			f.set(t, f.env.ID, "DEBUG", "true")
+			f.set(t, f.env.ID, "OLD", "1")
+
+			for _, dryRun := range []bool{true, false} {
+				plan, err := f.s.ImportVariables(context.Background(), ImportParams{
+					EnvironmentID: f.env.ID,
+					Content:       "PORT=8080\nDEBUG=false\nNEW=x\n",
+					Mode:          tt.mode,
+					DryRun:        dryRun,
+					AuthorID:      f.user,
+				})
+				if err != nil {
+					t.Fatalf("ImportVariables(dry run %v) error = %v", dryRun, err)
+				}
+				if !slices.Equal(plan.Added, tt.plan.Added) || !slices.Equal(plan.Changed, tt.plan.Changed) ||
+					!slices.Equal(plan.Removed, tt.plan.Removed) || !slices.Equal(plan.Unchanged, tt.plan.Unchanged) ||
+					!slices.Equal(plan.Skipped, tt.plan.Skipped) {
+					t.Errorf("ImportVariables(dry run %v) = %+v, want %+v", dryRun, plan, tt.plan)
+				}
+			}
+			if got := f.values(t, f.env.ID); !maps.Equal(got, tt.after) {
+				t.Errorf("variables after import = %v, want %v", got, tt.after)
+			}
+		})
+	}
+}
+
+func TestApplyBase(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.set(t, f.env.ID, "PORT", "8080")
+	dataKey, err := f.s.keys.dataKey(ctx, f.q, f.env.ID)
+	if err != nil {
+		t.Fatalf("dataKey() error = %v", err)
+	}
+	current, err := f.q.GetVariable(ctx, repo.GetVariableParams{EnvironmentID: f.env.ID, Key: "PORT"})
+	if err != nil {
+		t.Fatalf("GetVariable() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		c    change
+		want error
+	}{
+		{name: "create over an existing key", c: change{Key: "PORT", Value: "1", Base: pgtype.Text{Valid: true}}, want: ErrConcurrentChange},
+		{name: "update of an older version", c: change{Key: "PORT", Value: "1", Base: pgtype.Text{String: etag(current.ID, current.Version-1), Valid: true}}, want: ErrConcurrentChange},
+		{name: "update of a deleted key", c: change{Key: "GONE", Value: "1", Base: pgtype.Text{String: etag(current.ID, current.Version), Valid: true}}, want: ErrConcurrentChange},
+		{name: "update of the current version", c: change{Key: "PORT", Value: "1", Base: pgtype.Text{String: etag(current.ID, current.Version), Valid: true}}},
+		{name: "create of a new key", c: change{Key: "NEW", Value: "1", Base: pgtype.Text{Valid: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.s.withTx(ctx, func(q *repo.Queries) error {
+				_, err := f.s.apply(ctx, q, f.env.ID, dataKey, tt.c)
+				return err
+			})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("apply() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
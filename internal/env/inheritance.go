@@ -0,0 +1,202 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+var (
+	ErrInheritanceCycle = errors.New("inheritance cycle: the parent environment inherits from this environment")
+	ErrInvalidParent    = errors.New("parent environment must exist and belong to the same project")
+)
+
+// SetParent makes env inherit the variables of parentID. An invalid parentID
+// removes the parent. Changes to the parents of a project are serialized so
+// that two concurrent calls cannot create a cycle.
+func (s *svc) SetParent(ctx context.Context, envID, parentID pgtype.UUID) (repo.Environment, error) {
+	if err := s.checkUnprotected(ctx, envID); err != nil {
+		return repo.Environment{}, err
+	}
+
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return repo.Environment{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, envID); err != nil {
+			return err
+		}
+		if err := q.LockEnvironmentTree(ctx, env.ProjectID); err != nil {
+			return fmt.Errorf("failed to lock environments: %w", err)
+		}
+		if parentID.Valid {
+			parent, err := q.GetEnvironment(ctx, parentID)
+			if err == pgx.ErrNoRows {
+				return ErrInvalidParent
+			}
+			if err != nil {
+				return fmt.Errorf("failed to fetch parent environment: %w", err)
+			}
+			if parent.ProjectID != env.ProjectID {
+				return ErrInvalidParent
+			}
+
+			ancestors, err := s.chain(ctx, q, parent)
+			if err != nil {
+				return err
+			}
+			for _, a := range ancestors {
+				if a.ID == envID {
+					return ErrInheritanceCycle
+				}
+			}
+		}
+
+		env, err = q.SetEnvironmentParent(ctx, repo.SetEnvironmentParentParams{
+			ID:       envID,
+			ParentID: parentID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set parent environment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return repo.Environment{}, err
+	}
+	return env, nil
+}
+
+// chain returns env followed by its ancestors, nearest first, read through q.
+func (s *svc) chain(ctx context.Context, q *repo.Queries, env repo.Environment) ([]repo.Environment, error) {
+	chain := []repo.Environment{env}
+	seen := map[[16]byte]bool{env.ID.Bytes: true}
+	for env.ParentID.Valid {
+		if seen[env.ParentID.Bytes] {
+			return nil, ErrInheritanceCycle
+		}
+		parent, err := q.GetEnvironment(ctx, env.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch parent environment: %w", err)
+		}
+		seen[parent.ID.Bytes] = true
+		chain = append(chain, parent)
+		env = parent
+	}
+	return chain, nil
+}
+
+// Ancestors returns the environments envID inherits from, nearest first.
+func (s *svc) Ancestors(ctx context.Context, envID pgtype.UUID) ([]repo.Environment, error) {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	chain, err := s.chain(ctx, s.repo, env)
+	if err != nil {
+		return nil, err
+	}
+	return chain[1:], nil
+}
+
+// layered is a variable of an effective set together with the environment
+// that defines it.
+type layered struct {
+	v      repo.Variable
+	source repo.Environment
+}
+
+// effective returns the variables of env overlaid on those of its ancestors,
+// sorted by key. Tombstones hide keys inherited from further up the chain.
+func (s *svc) effective(ctx context.Context, env repo.Environment) ([]layered, error) {
+	chain, err := s.chain(ctx, s.repo, env)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]layered)
+	for i := len(chain) - 1; i >= 0; i-- {
+		layer := chain[i]
+		if i < len(chain)-1 {
+			tombstones, err := s.repo.ListVariableTombstones(ctx, layer.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list tombstones: %w", err)
+			}
+			for _, t := range tombstones {
+				delete(set, t.Key)
+			}
+		}
+
+		vars, err := s.repo.ListVariables(ctx, layer.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list variables: %w", err)
+		}
+		for _, v := range vars {
+			set[v.Key] = layered{v: v, source: layer}
+		}
+	}
+
+	out := make([]layered, 0, len(set))
+	for _, l := range set {
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].v.Key < out[j].v.Key })
+	return out, nil
+}
+
+// inherits reports whether a key is provided to env by one of its ancestors.
+func (s *svc) inherits(ctx context.Context, env repo.Environment, key string) (bool, error) {
+	keys, err := s.inheritedKeys(ctx, env)
+	if err != nil {
+		return false, err
+	}
+	return keys[key], nil
+}
+
+// inheritedKeys returns the keys provided to env by its ancestors, that is the
+// keys env would have without any local variables or tombstones.
+func (s *svc) inheritedKeys(ctx context.Context, env repo.Environment) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	if !env.ParentID.Valid {
+		return keys, nil
+	}
+	parent, err := s.repo.GetEnvironment(ctx, env.ParentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch parent environment: %w", err)
+	}
+	vars, err := s.effective(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range vars {
+		keys[l.v.Key] = true
+	}
+	return keys, nil
+}
+
+// toLayeredVariable is toVariable for a variable of an effective set of env.
+func (s *svc) toLayeredVariable(ctx context.Context, env repo.Environment, l layered, reveal bool) (Variable, error) {
+	var dataKey []byte
+	if !l.v.IsSecret.Bool || reveal {
+		var err error
+		dataKey, err = s.keys.dataKey(ctx, s.repo, l.source.ID)
+		if err != nil {
+			return Variable{}, err
+		}
+	}
+	out, err := toVariable(dataKey, l.v, reveal)
+	if err != nil {
+		return Variable{}, err
+	}
+	out.Source = l.source.Slug
+	out.Inherited = l.source.ID != env.ID
+	return out, nil
+}
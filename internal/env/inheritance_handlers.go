@@ -0,0 +1,75 @@
+package env
+
+import (
+	"encoding/json"
+	"net/http"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/internal/auth"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type setParentRequest struct {
+	// ParentID is null to stop inheriting.
+	ParentID pgtype.UUID `json:"parent_id"`
+}
+
+// SetParent changes what the environment inherits from, which only those who
+// manage the environments of the project can do. They must also be able to
+// read the new parent and its ancestors.
+func (h *handler) SetParent(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanManageEnvironments(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var req setParentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ParentID.Valid && !h.checkParentAccess(w, r, env, userID, req.ParentID) {
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	updated, err := h.service.SetParent(r.Context(), env.ID, req.ParentID)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, updated)
+}
+
+// checkParentAccess checks that userID may read parentID and its ancestors
+// before env inherits from them. A parent in another project is left to the
+// service to reject. On failure the error response has already been written
+// and false is returned.
+func (h *handler) checkParentAccess(w http.ResponseWriter, r *http.Request, env repo.Environment, userID, parentID pgtype.UUID) bool {
+	parent, err := h.service.GetEnv(r.Context(), parentID)
+	if err != nil || parent.ProjectID != env.ProjectID {
+		return true
+	}
+	ancestors, err := h.service.Ancestors(r.Context(), parent.ID)
+	if err != nil {
+		writeVariableError(w, err)
+		return false
+	}
+	for _, a := range append([]repo.Environment{parent}, ancestors...) {
+		if err := h.checkEnvAccess(r.Context(), userID, a, auth.AccessRead); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestInheritance(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	base := f.newEnv(t, "base")
+	f.set(t, base.ID, "PORT", "8080")
+	f.set(t, base.ID, "DEBUG", "false")
+	f.set(t, f.env.ID, "DEBUG", "true")
+
+	if _, err := f.s.SetParent(ctx, f.env.ID, base.ID); err != nil {
+		t.Fatalf("SetParent() error = %v", err)
+	}
+	if got, want := f.values(t, f.env.ID), map[string]string{"PORT": "8080", "DEBUG": "true"}; !maps.Equal(got, want) {
+		t.Errorf("variables = %v, want %v", got, want)
+	}
+
+	// Deleting an inherited key masks it with a tombstone.
+	if err := f.s.DeleteVariable(ctx, DeleteVariableParams{EnvironmentID: f.env.ID, Key: "PORT", AuthorID: f.user}); err != nil {
+		t.Fatalf("DeleteVariable() error = %v", err)
+	}
+	if got, want := f.values(t, f.env.ID), map[string]string{"DEBUG": "true"}; !maps.Equal(got, want) {
+		t.Errorf("variables after masking PORT = %v, want %v", got, want)
+	}
+
+	// Deleting the local value with Inherit set falls back to the parent.
+	for _, key := range []string{"PORT", "DEBUG"} {
+		if err := f.s.DeleteVariable(ctx, DeleteVariableParams{EnvironmentID: f.env.ID, Key: key, Inherit: true, AuthorID: f.user}); err != nil {
+			t.Fatalf("DeleteVariable(%s, inherit) error = %v", key, err)
+		}
+	}
+	if got, want := f.values(t, f.env.ID), map[string]string{"PORT": "8080", "DEBUG": "false"}; !maps.Equal(got, want) {
+		t.Errorf("variables after inheriting again = %v, want %v", got, want)
+	}
+
+	if _, err := f.s.SetParent(ctx, base.ID, f.env.ID); !errors.Is(err, ErrInheritanceCycle) {
+		t.Errorf("SetParent() of a cycle error = %v, want %v", err, ErrInheritanceCycle)
+	}
+	if _, err := f.s.SetParent(ctx, f.env.ID, pgtype.UUID{}); err != nil {
+		t.Fatalf("SetParent(none) error = %v", err)
+	}
+	if got := f.values(t, f.env.ID); len(got) != 0 {
+		t.Errorf("variables without a parent = %v, want none", got)
+	}
+}
+
+func TestSetParentConcurrently(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	a, b := f.newEnv(t, "a"), f.newEnv(t, "b")
+
+	for range 10 {
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i, pair := range [][2]pgtype.UUID{{a.ID, b.ID}, {b.ID, a.ID}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = f.s.SetParent(ctx, pair[0], pair[1])
+			}()
+		}
+		wg.Wait()
+
+		failed := 0
+		for _, err := range errs {
+			if errors.Is(err, ErrInheritanceCycle) {
+				failed++
+			} else if err != nil {
+				t.Fatalf("SetParent() error = %v", err)
+			}
+		}
+		if failed != 1 {
+			t.Fatalf("SetParent() in both directions: %d calls failed, want 1", failed)
+		}
+		for _, id := range []pgtype.UUID{a.ID, b.ID} {
+			if _, err := f.s.SetParent(ctx, id, pgtype.UUID{}); err != nil {
+				t.Fatalf("SetParent(none) error = %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+// AccessFunc reports whether the caller may read the variables of env. It is
+// called for every environment a reference points to.
+type AccessFunc func(ctx context.Context, env repo.Environment) error
+
+// KeyAccessFunc returns what the caller may do with the variables of env under
+// its access rules.
+type KeyAccessFunc func(ctx context.Context, env repo.Environment) (KeyAccess, error)
+
+// Reasons a reference could not be resolved.
+const (
+	reasonInvalid      = "invalid reference"
+	reasonNoVariable   = "variable not found"
+	reasonNoEnv        = "environment not found"
+	reasonNoProject    = "project not found"
+	reasonAccessDenied = "access denied"
+	reasonCycle        = "reference cycle"
+)
+
+type ResolveParams struct {
+	EnvironmentID pgtype.UUID
+	// Key limits the resolution to a single variable when set.
+	Key    string
+	Reveal bool
+	Access AccessFunc
+	// Keys, if set, hides the variables the caller may not read: they are
+	// returned as placeholders and references to them are left dangling.
+	Keys KeyAccessFunc
+	// RevealAccess, if set, checks that the caller may reveal the secrets of
+	// env. It is called once per project, other than the project of the
+	// environment being resolved, that a secret in a revealed value comes from.
+	// A value with a secret the caller may not reveal stays masked.
+	RevealAccess AccessFunc
+}
+
+// DanglingReference is a reference that was left unresolved. Via lists the
+// references followed from Key before reaching it.
+type DanglingReference struct {
+	Key       string   `json:"key"`
+	Reference string   `json:"reference"`
+	Via       []string `json:"via,omitempty"`
+	Reason    string   `json:"reason"`
+}
+
+type Resolution struct {
+	Variables []Variable          `json:"variables"`
+	Dangling  []DanglingReference `json:"dangling"`
+	// Revealed lists keys whose value includes a secret and was returned in
+	// plaintext.
+	Revealed []string `json:"-"`
+
+	root    repo.Environment
+	secrets map[string][]secretRef
+}
+
+// RevealSource lists the secrets of an environment that were returned in
+// plaintext.
+type RevealSource struct {
+	Environment repo.Environment
+	Keys        []string
+}
+
+// secretRef is a secret variable whose value was expanded into another.
+type secretRef struct {
+	env repo.Environment
+	key string
+}
+
+// Sources groups the secrets included in the revealed values of keys by the
+// environment they come from, so that each environment audits the reveal of
+// its own secrets. The keys themselves count as revealed in the resolved
+// environment.
+func (res Resolution) Sources(keys []string) []RevealSource {
+	index := make(map[[16]byte]int)
+	var out []RevealSource
+	add := func(env repo.Environment, key string) {
+		i, ok := index[env.ID.Bytes]
+		if !ok {
+			i = len(out)
+			index[env.ID.Bytes] = i
+			out = append(out, RevealSource{Environment: env})
+		}
+		if !slices.Contains(out[i].Keys, key) {
+			out[i].Keys = append(out[i].Keys, key)
+		}
+	}
+	for _, key := range keys {
+		add(res.root, key)
+		for _, ref := range res.secrets[key] {
+			add(ref.env, ref.key)
+		}
+	}
+	return out
+}
+
+// ResolveVariables expands references in variable values. ${KEY} refers to a
+// variable of the same environment, ${env.KEY} to another environment of the
+// same project and ${project.env.KEY} to an environment of another project in
+// the same organization. $${ produces a literal ${. Unresolvable references
+// are left in place and reported as dangling.
+//
+// A value that includes a secret is masked like a secret unless Reveal is set.
+// A value the caller may not read is never expanded into another.
+func (s *svc) ResolveVariables(ctx context.Context, params ResolveParams) (Resolution, error) {
+	root, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return Resolution{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	r := &resolver{
+		s:            s,
+		access:       params.Access,
+		keys:         params.Keys,
+		revealAccess: params.RevealAccess,
+		root:         root.ID,
+		envs:         make(map[[16]byte]*envValues),
+		results:      make(map[node]resolved),
+		visiting:     make(map[node]bool),
+		revealable:   map[[16]byte]bool{root.ProjectID.Bytes: true},
+	}
+	rootVars, err := r.load(ctx, root)
+	if err != nil {
+		return Resolution{}, err
+	}
+
+	keys := rootVars.keys
+	if params.Key != "" {
+		if _, ok := rootVars.vars[params.Key]; !ok {
+			return Resolution{}, ErrVariableNotFound
+		}
+		keys = []string{params.Key}
+	}
+
+	ids := make([]pgtype.UUID, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, rootVars.vars[key].v.ID)
+	}
+	metadata, err := s.metadataByVariable(ctx, ids)
+	if err != nil {
+		return Resolution{}, err
+	}
+
+	out := Resolution{
+		Variables: make([]Variable, 0, len(keys)),
+		Dangling:  []DanglingReference{},
+		root:      root,
+		secrets:   make(map[string][]secretRef),
+	}
+	for _, key := range keys {
+		l := rootVars.vars[key]
+		view := Variable{
+			Key:       key,
+			IsSecret:  l.v.IsSecret.Bool,
+			Source:    l.source.Slug,
+			Inherited: l.source.ID != root.ID,
+			Metadata:  metadata[l.v.ID],
+			CreatedAt: l.v.CreatedAt,
+			UpdatedAt: l.v.UpdatedAt,
+		}
+		if !rootVars.access.CanReadVariable(view) {
+			out.Variables = append(out.Variables, redacted(view))
+			continue
+		}
+		res, err := r.resolve(ctx, rootVars, key)
+		if err != nil {
+			return Resolution{}, err
+		}
+		revealable := params.Reveal
+		if res.secret && revealable {
+			if revealable, err = r.canReveal(ctx, res.secrets); err != nil {
+				return Resolution{}, err
+			}
+		}
+		switch {
+		case res.secret && !revealable:
+			view.Masked = true
+		case res.secret:
+			view.Value = res.value
+			out.Revealed = append(out.Revealed, key)
+			out.secrets[key] = res.secrets
+		default:
+			view.Value = res.value
+		}
+		out.Variables = append(out.Variables, view)
+
+		for _, d := range res.dangling {
+			d.Key = key
+			out.Dangling = append(out.Dangling, d)
+		}
+	}
+	return out, nil
+}
+
+type node struct {
+	env [16]byte
+	key string
+}
+
+type resolved struct {
+	value    string
+	secret   bool
+	dangling []DanglingReference
+	// secrets lists the secret variables expanded into the value.
+	secrets []secretRef
+}
+
+// envValues holds the decrypted effective variables of an environment.
+type envValues struct {
+	env  repo.Environment
+	keys []string
+	vars map[string]layered
+	// values holds the decrypted value of each variable.
+	values map[string]string
+	// denied is set when the caller may not read the environment.
+	denied bool
+	// access holds the access rules of the environment for the caller.
+	access KeyAccess
+}
+
+type resolver struct {
+	s            *svc
+	access       AccessFunc
+	keys         KeyAccessFunc
+	revealAccess AccessFunc
+	root         pgtype.UUID
+
+	envs     map[[16]byte]*envValues
+	results  map[node]resolved
+	visiting map[node]bool
+	// revealable caches by project whether the caller may reveal its secrets.
+	revealable map[[16]byte]bool
+}
+
+// canReveal reports whether the caller may reveal every secret in refs.
+func (r *resolver) canReveal(ctx context.Context, refs []secretRef) (bool, error) {
+	for _, ref := range refs {
+		project := ref.env.ProjectID.Bytes
+		allowed, ok := r.revealable[project]
+		if !ok {
+			allowed = r.revealAccess == nil || r.revealAccess(ctx, ref.env) == nil
+			r.revealable[project] = allowed
+		}
+		if !allowed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// load returns the decrypted variables of env, checking access for every
+// environment other than the one being resolved.
+func (r *resolver) load(ctx context.Context, env repo.Environment) (*envValues, error) {
+	if ev, ok := r.envs[env.ID.Bytes]; ok {
+		return ev, nil
+	}
+
+	ev := &envValues{env: env}
+	r.envs[env.ID.Bytes] = ev
+	if env.ID != r.root && r.access != nil {
+		if err := r.access(ctx, env); err != nil {
+			ev.denied = true
+			return ev, nil
+		}
+	}
+
+	if r.keys != nil {
+		access, err := r.keys(ctx, env)
+		if err != nil {
+			return nil, err
+		}
+		ev.access = access
+	}
+
+	vars, err := r.s.effective(ctx, env)
+	if err != nil {
+		return nil, err
+	}
+	ev.vars = make(map[string]layered, len(vars))
+	ev.values = make(map[string]string, len(vars))
+	for _, l := range vars {
+		dataKey, err := r.s.keys.dataKey(ctx, r.s.repo, l.source.ID)
+		if err != nil {
+			return nil, err
+		}
+		value, err := openValue(dataKey, l.v.EnvironmentID, l.v.Key, l.v.Value, l.v.Encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt variable %s: %w", l.v.Key, err)
+		}
+		ev.keys = append(ev.keys, l.v.Key)
+		ev.vars[l.v.Key] = l
+		ev.values[l.v.Key] = value
+	}
+	return ev, nil
+}
+
+// lookup finds the environment a reference points to, relative to from. An
+// empty reason means the environment was found.
+func (r *resolver) lookup(ctx context.Context, from *envValues, path []string) (*envValues, string, error) {
+	switch len(path) {
+	case 0:
+		return from, "", nil
+	case 1:
+		env, err := r.s.repo.GetEnvironmentBySlug(ctx, repo.GetEnvironmentBySlugParams{
+			ProjectID: from.env.ProjectID,
+			Slug:      path[0],
+		})
+		if err == pgx.ErrNoRows {
+			return nil, reasonNoEnv, nil
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch environment %s: %w", path[0], err)
+		}
+		ev, err := r.load(ctx, env)
+		return ev, "", err
+	default:
+		current, err := r.s.repo.GetProject(ctx, from.env.ProjectID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch project: %w", err)
+		}
+		project, err := r.s.repo.GetProjectBySlug(ctx, repo.GetProjectBySlugParams{
+			OrganizationID: current.OrganizationID,
+			Slug:           path[0],
+		})
+		if err == pgx.ErrNoRows {
+			return nil, reasonNoProject, nil
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch project %s: %w", path[0], err)
+		}
+		env, err := r.s.repo.GetEnvironmentBySlug(ctx, repo.GetEnvironmentBySlugParams{
+			ProjectID: project.ID,
+			Slug:      path[1],
+		})
+		if err == pgx.ErrNoRows {
+			return nil, reasonNoEnv, nil
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch environment %s: %w", path[1], err)
+		}
+		ev, err := r.load(ctx, env)
+		return ev, "", err
+	}
+}
+
+// resolve expands the references in the value of key in ev.
+func (r *resolver) resolve(ctx context.Context, ev *envValues, key string) (resolved, error) {
+	n := node{env: ev.env.ID.Bytes, key: key}
+	if res, ok := r.results[n]; ok {
+		return res, nil
+	}
+	r.visiting[n] = true
+	defer delete(r.visiting, n)
+
+	res := resolved{secret: ev.vars[key].v.IsSecret.Bool}
+	if res.secret {
+		res.secrets = []secretRef{{env: ev.env, key: key}}
+	}
+	value := ev.values[key]
+
+	var b strings.Builder
+	for {
+		i := strings.Index(value, "${")
+		if i < 0 {
+			b.WriteString(value)
+			break
+		}
+		if i > 0 && value[i-1] == '$' {
+			b.WriteString(value[:i-1])
+			b.WriteString("${")
+			value = value[i+2:]
+			continue
+		}
+		end := strings.IndexByte(value[i:], '}')
+		if end < 0 {
+			b.WriteString(value)
+			break
+		}
+		b.WriteString(value[:i])
+		ref := value[i+2 : i+end]
+		literal := value[i : i+end+1]
+		value = value[i+end+1:]
+
+		sub, reason, err := r.follow(ctx, ev, ref)
+		if err != nil {
+			return resolved{}, err
+		}
+		if reason != "" {
+			b.WriteString(literal)
+			res.dangling = append(res.dangling, DanglingReference{Reference: ref, Reason: reason})
+			continue
+		}
+		b.WriteString(sub.value)
+		res.secret = res.secret || sub.secret
+		res.secrets = append(res.secrets, sub.secrets...)
+		for _, d := range sub.dangling {
+			d.Via = append([]string{ref}, d.Via...)
+			res.dangling = append(res.dangling, d)
+		}
+	}
+
+	res.value = b.String()
+	r.results[n] = res
+	return res, nil
+}
+
+// follow resolves a single reference found in ev.
+func (r *resolver) follow(ctx context.Context, ev *envValues, ref string) (resolved, string, error) {
+	parts := strings.Split(ref, ".")
+	if len(parts) > 3 {
+		return resolved{}, reasonInvalid, nil
+	}
+	for _, p := range parts {
+		if p == "" {
+			return resolved{}, reasonInvalid, nil
+		}
+	}
+	key := parts[len(parts)-1]
+	if validateKey(key) != nil {
+		return resolved{}, reasonInvalid, nil
+	}
+
+	target, reason, err := r.lookup(ctx, ev, parts[:len(parts)-1])
+	if err != nil || reason != "" {
+		return resolved{}, reason, err
+	}
+	if target.denied {
+		return resolved{}, reasonAccessDenied, nil
+	}
+	l, ok := target.vars[key]
+	if !ok {
+		return resolved{}, reasonNoVariable, nil
+	}
+	if !target.access.CanReadVariable(Variable{Key: key, Source: l.source.Slug, Inherited: l.source.ID != target.env.ID}) {
+		return resolved{}, reasonAccessDenied, nil
+	}
+	if r.visiting[node{env: target.env.ID.Bytes, key: key}] {
+		return resolved{}, reasonCycle, nil
+	}
+
+	res, err := r.resolve(ctx, target, key)
+	return res, "", err
+}
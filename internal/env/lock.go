@@ -0,0 +1,211 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+var (
+	ErrEnvironmentLocked = errors.New("environment is locked")
+	ErrNotLocked         = errors.New("environment is not locked")
+	ErrInvalidLock       = errors.New("invalid lock")
+)
+
+// Lock freezes an environment. LockedUntil is nil for a lock that lasts until
+// it is removed.
+type Lock struct {
+	LockedBy    pgtype.UUID `json:"locked_by"`
+	Reason      string      `json:"reason"`
+	LockedUntil *time.Time  `json:"locked_until,omitempty"`
+	LockedAt    time.Time   `json:"locked_at"`
+}
+
+type LockParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	UserID        pgtype.UUID `json:"-"`
+	Reason        string      `json:"reason"`
+	Until         *time.Time  `json:"until"`
+}
+
+// LockedError rejects a mutation of a locked environment.
+type LockedError struct {
+	Lock Lock
+}
+
+func (e *LockedError) Error() string {
+	msg := "environment is locked: " + e.Lock.Reason
+	if e.Lock.LockedUntil != nil {
+		msg += " (until " + e.Lock.LockedUntil.UTC().Format(time.RFC3339) + ")"
+	}
+	return msg
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrEnvironmentLocked
+}
+
+type lockOverrideKey struct{}
+
+// WithLockOverride marks ctx as allowed to write to locked environments. The
+// caller is responsible for checking permission and auditing the override.
+func WithLockOverride(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lockOverrideKey{}, true)
+}
+
+func toLock(l repo.EnvironmentLock) Lock {
+	out := Lock{
+		LockedBy: l.LockedBy,
+		Reason:   l.Reason,
+		LockedAt: l.CreatedAt.Time,
+	}
+	if l.LockedUntil.Valid {
+		until := l.LockedUntil.Time
+		out.LockedUntil = &until
+	}
+	return out
+}
+
+// GetLock returns the active lock of the environment, or nil. Locks past
+// their end time are no longer active.
+func (s *svc) GetLock(ctx context.Context, envID pgtype.UUID) (*Lock, error) {
+	return activeLock(ctx, s.repo, envID)
+}
+
+func activeLock(ctx context.Context, q *repo.Queries, envID pgtype.UUID) (*Lock, error) {
+	stored, err := q.GetEnvironmentLock(ctx, envID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch lock: %w", err)
+	}
+	if stored.LockedUntil.Valid && !stored.LockedUntil.Time.After(time.Now()) {
+		return nil, nil
+	}
+	lock := toLock(stored)
+	return &lock, nil
+}
+
+// checkUnlocked fails with a LockedError if the environment is locked, unless
+// ctx carries a lock override. q must be a transaction: the environment
+// cannot be locked until it ends, so the writes made through it are checked.
+func (s *svc) checkUnlocked(ctx context.Context, q *repo.Queries, envID pgtype.UUID) error {
+	if override, _ := ctx.Value(lockOverrideKey{}).(bool); override {
+		return nil
+	}
+	if err := q.ShareEnvironmentLock(ctx, envID); err != nil {
+		return fmt.Errorf("failed to fetch lock: %w", err)
+	}
+	lock, err := activeLock(ctx, q, envID)
+	if err != nil {
+		return err
+	}
+	if lock != nil {
+		return &LockedError{Lock: *lock}
+	}
+	return nil
+}
+
+// LockEnvironment rejects all further mutations of the environment until it
+// is unlocked or params.Until has passed.
+func (s *svc) LockEnvironment(ctx context.Context, params LockParams) (Lock, error) {
+	params.Reason = strings.TrimSpace(params.Reason)
+	if params.Reason == "" {
+		return Lock{}, ErrReasonRequired
+	}
+	var until pgtype.Timestamptz
+	if params.Until != nil {
+		if !params.Until.After(time.Now()) {
+			return Lock{}, fmt.Errorf("%w: until must be in the future", ErrInvalidLock)
+		}
+		until = pgtype.Timestamptz{Time: *params.Until, Valid: true}
+	}
+
+	env, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return Lock{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	var lock repo.EnvironmentLock
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := q.ClaimEnvironmentLock(ctx, env.ID); err != nil {
+			return fmt.Errorf("failed to lock environment: %w", err)
+		}
+		current, err := activeLock(ctx, q, env.ID)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			return &LockedError{Lock: *current}
+		}
+		lock, err = q.UpsertEnvironmentLock(ctx, repo.UpsertEnvironmentLockParams{
+			EnvironmentID: env.ID,
+			LockedBy:      params.UserID,
+			Reason:        params.Reason,
+			LockedUntil:   until,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to lock environment: %w", err)
+		}
+		return s.audit(ctx, q, env, params.UserID, auditLock, map[string]any{
+			"reason":       params.Reason,
+			"locked_until": params.Until,
+		})
+	})
+	if err != nil {
+		return Lock{}, err
+	}
+	return toLock(lock), nil
+}
+
+func (s *svc) UnlockEnvironment(ctx context.Context, envID, userID pgtype.UUID) error {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	return s.withTx(ctx, func(q *repo.Queries) error {
+		if err := q.ClaimEnvironmentLock(ctx, envID); err != nil {
+			return fmt.Errorf("failed to unlock environment: %w", err)
+		}
+		lock, err := activeLock(ctx, q, envID)
+		if err != nil {
+			return err
+		}
+		if lock == nil {
+			return ErrNotLocked
+		}
+		if err := q.DeleteEnvironmentLock(ctx, envID); err != nil {
+			return fmt.Errorf("failed to unlock environment: %w", err)
+		}
+		return s.audit(ctx, q, env, userID, auditUnlock, map[string]any{
+			"locked_by": lock.LockedBy,
+			"reason":    lock.Reason,
+		})
+	})
+}
+
+// AuditLockOverride records that userID bypasses the lock of the environment
+// for the given operation.
+func (s *svc) AuditLockOverride(ctx context.Context, envID, userID pgtype.UUID, reason, operation string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ErrReasonRequired
+	}
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	return s.audit(ctx, s.repo, env, userID, auditLockOverride, map[string]any{
+		"operation": operation,
+		"reason":    reason,
+	})
+}
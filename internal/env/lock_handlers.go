@@ -0,0 +1,130 @@
+package env
+
+import (
+	"encoding/json"
+	"net/http"
+
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// lockOverride handles the override=true query parameter of mutating
+// endpoints. If the environment is locked, the caller must be allowed to
+// override locks and give a reason, and the override is audited before the
+// returned request is allowed to write. On failure the error response has
+// already been written and ok is false.
+func (h *handler) lockOverride(w http.ResponseWriter, r *http.Request, envID pgtype.UUID) (*http.Request, bool) {
+	if r.URL.Query().Get("override") != "true" {
+		return r, true
+	}
+
+	lock, err := h.service.GetLock(r.Context(), envID)
+	if err != nil {
+		writeVariableError(w, err)
+		return r, false
+	}
+	if lock == nil {
+		return r, true
+	}
+
+	userID, ok := requestUser(w, r)
+	if !ok {
+		return r, false
+	}
+	env, err := h.service.GetEnv(r.Context(), envID)
+	if err != nil {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return r, false
+	}
+	if err := h.authorizer.CanOverrideLock(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return r, false
+	}
+	operation := r.Method + " " + r.URL.Path
+	if err := h.service.AuditLockOverride(r.Context(), envID, userID, r.URL.Query().Get("reason"), operation); err != nil {
+		writeVariableError(w, err)
+		return r, false
+	}
+	return r.WithContext(WithLockOverride(r.Context())), true
+}
+
+func (h *handler) GetLock(w http.ResponseWriter, r *http.Request) {
+	env, _, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	lock, err := h.service.GetLock(r.Context(), env.ID)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	if lock == nil {
+		http.Error(w, ErrNotLocked.Error(), http.StatusNotFound)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, lock)
+}
+
+// LockEnvironment freezes the environment. The body gives the reason and an
+// optional until time after which the lock expires. Only users who manage the
+// environments of the project can lock them.
+func (h *handler) LockEnvironment(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	if !h.authorizeManage(w, r, env.ID) {
+		return
+	}
+
+	var params LockParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.UserID = userID
+
+	lock, err := h.service.LockEnvironment(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, lock)
+}
+
+// UnlockEnvironment removes the lock of the environment. Users who manage the
+// environments of the project can remove their own locks; lifting a lock
+// someone else placed before it expires takes permission to override locks.
+func (h *handler) UnlockEnvironment(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	if !h.authorizeManage(w, r, env.ID) {
+		return
+	}
+
+	lock, err := h.service.GetLock(r.Context(), env.ID)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	if lock == nil {
+		http.Error(w, ErrNotLocked.Error(), http.StatusNotFound)
+		return
+	}
+	if lock.LockedBy != userID {
+		if err := h.authorizer.CanOverrideLock(r.Context(), userID, env.ProjectID); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := h.service.UnlockEnvironment(r.Context(), env.ID, userID); err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, map[string]string{"message": "environment unlocked"})
+}
@@ -0,0 +1,88 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockedEnvironment(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.set(t, f.env.ID, "PORT", "8080")
+	if _, err := f.s.LockEnvironment(ctx, LockParams{EnvironmentID: f.env.ID, UserID: f.user, Reason: "release"}); err != nil {
+		t.Fatalf("LockEnvironment() error = %v", err)
+	}
+
+	setVariable := func(ctx context.Context) error {
+		_, err := f.s.SetVariable(ctx, SetVariableParams{EnvironmentID: f.env.ID, Key: "PORT", Value: "9090", AuthorID: f.user})
+		return err
+	}
+	setACL := func(ctx context.Context) error {
+		_, err := f.s.SetACL(ctx, SetACLParams{EnvironmentID: f.env.ID, Pattern: "DB_*", Readers: []string{"role:admin"}, AuthorID: f.user})
+		return err
+	}
+	setProtection := func(ctx context.Context) error {
+		_, err := f.s.SetProtection(ctx, f.env.ID, f.user, Protection{RequiredApprovals: 1})
+		return err
+	}
+	// Protection goes last, as it keeps SetVariable from writing directly.
+	mutations := []struct {
+		name   string
+		mutate func(context.Context) error
+	}{
+		{"SetVariable", setVariable},
+		{"SetACL", setACL},
+		{"SetProtection", setProtection},
+	}
+	for _, m := range mutations {
+		var locked *LockedError
+		if err := m.mutate(ctx); !errors.As(err, &locked) {
+			t.Errorf("%s() of a locked environment error = %v, want a LockedError", m.name, err)
+		}
+		if err := m.mutate(WithLockOverride(ctx)); err != nil {
+			t.Errorf("%s() with a lock override error = %v", m.name, err)
+		}
+	}
+
+	if err := f.s.UnlockEnvironment(ctx, f.env.ID, f.user); err != nil {
+		t.Fatalf("UnlockEnvironment() error = %v", err)
+	}
+	if err := f.s.UnlockEnvironment(ctx, f.env.ID, f.user); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("UnlockEnvironment() of an unlocked environment error = %v, want %v", err, ErrNotLocked)
+	}
+}
+
+func TestLockWaitsForWrites(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	// A write that has checked the lock holds off locking until it commits.
+	tx, err := f.s.db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+	if err := f.s.checkUnlocked(ctx, f.q.WithTx(tx), f.env.ID); err != nil {
+		t.Fatalf("checkUnlocked() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := f.s.LockEnvironment(ctx, LockParams{EnvironmentID: f.env.ID, UserID: f.user, Reason: "release"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("LockEnvironment() returned %v while a write was in progress", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("LockEnvironment() error = %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// Limits on the size of variable metadata.
+const (
+	maxDescriptionLength = 4096
+	maxOwnerLength       = 255
+	maxTags              = 32
+	maxTagLength         = 64
+	maxLinks             = 16
+)
+
+var metadataTagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/-]*$`)
+
+// Metadata documents a variable: what it is for, who owns it and where to
+// read more. Owner is free-form so that it can name a user or a team.
+type Metadata struct {
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	Links       []string `json:"links,omitempty"`
+}
+
+type SetMetadataParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Key           string      `json:"-"`
+	AuthorID      pgtype.UUID `json:"-"`
+	// IfMatch is checked like SetVariableParams.IfMatch.
+	IfMatch string `json:"-"`
+	Metadata
+}
+
+// VariableFilter selects variables by metadata. A variable matches if it has
+// all of Tags and, when Owner is set, that owner.
+type VariableFilter struct {
+	Tags  []string
+	Owner string
+}
+
+func (f VariableFilter) empty() bool {
+	return len(f.Tags) == 0 && f.Owner == ""
+}
+
+func (f VariableFilter) match(v Variable) bool {
+	if f.empty() {
+		return true
+	}
+	if v.Metadata == nil {
+		return false
+	}
+	if f.Owner != "" && !strings.EqualFold(v.Metadata.Owner, f.Owner) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(v.Metadata.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterVariables returns the variables matching f.
+func filterVariables(vars []Variable, f VariableFilter) []Variable {
+	if f.empty() {
+		return vars
+	}
+	out := make([]Variable, 0, len(vars))
+	for _, v := range vars {
+		if f.match(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// normalize trims m, sorts and deduplicates its tags and validates it.
+func (m *Metadata) normalize() error {
+	m.Description = strings.TrimSpace(m.Description)
+	m.Owner = strings.TrimSpace(m.Owner)
+	if len(m.Description) > maxDescriptionLength {
+		return fmt.Errorf("%w: description is longer than %d characters", ErrInvalidMetadata, maxDescriptionLength)
+	}
+	if len(m.Owner) > maxOwnerLength || strings.ContainsAny(m.Owner, "\r\n") {
+		return fmt.Errorf("%w: owner must be a single line of at most %d characters", ErrInvalidMetadata, maxOwnerLength)
+	}
+
+	tags := make([]string, 0, len(m.Tags))
+	for _, tag := range m.Tags {
+		tag = strings.TrimSpace(tag)
+		if len(tag) > maxTagLength || !metadataTagPattern.MatchString(tag) {
+			return fmt.Errorf("%w: invalid tag %q", ErrInvalidMetadata, tag)
+		}
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	m.Tags = slices.Compact(tags)
+	if len(m.Tags) > maxTags {
+		return fmt.Errorf("%w: more than %d tags", ErrInvalidMetadata, maxTags)
+	}
+
+	if len(m.Links) > maxLinks {
+		return fmt.Errorf("%w: more than %d links", ErrInvalidMetadata, maxLinks)
+	}
+	for i, link := range m.Links {
+		link = strings.TrimSpace(link)
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: links must be http or https URLs, got %q", ErrInvalidMetadata, link)
+		}
+		m.Links[i] = link
+	}
+	return nil
+}
+
+func (m Metadata) empty() bool {
+	return m.Description == "" && m.Owner == "" && len(m.Tags) == 0 && len(m.Links) == 0
+}
+
+func toMetadata(stored repo.VariableMetadatum) *Metadata {
+	return &Metadata{
+		Description: stored.Description,
+		Tags:        stored.Tags,
+		Owner:       stored.Owner,
+		Links:       stored.Links,
+	}
+}
+
+// saveMetadata replaces the metadata of the variable through q. Empty metadata
+// is removed.
+func saveMetadata(ctx context.Context, q *repo.Queries, v repo.Variable, m Metadata) error {
+	if m.empty() {
+		if err := q.DeleteVariableMetadata(ctx, v.ID); err != nil {
+			return fmt.Errorf("failed to remove metadata: %w", err)
+		}
+		return nil
+	}
+	if m.Tags == nil {
+		m.Tags = []string{}
+	}
+	if m.Links == nil {
+		m.Links = []string{}
+	}
+	if _, err := q.UpsertVariableMetadata(ctx, repo.UpsertVariableMetadataParams{
+		VariableID:  v.ID,
+		Description: m.Description,
+		Tags:        m.Tags,
+		Owner:       m.Owner,
+		Links:       m.Links,
+	}); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+	return nil
+}
+
+// metadata returns the metadata of the variable, or nil.
+func (s *svc) metadata(ctx context.Context, variableID pgtype.UUID) (*Metadata, error) {
+	stored, err := s.repo.GetVariableMetadata(ctx, variableID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	return toMetadata(stored), nil
+}
+
+// metadataByVariable returns the metadata of the given variables that have
+// any, keyed by variable ID.
+func (s *svc) metadataByVariable(ctx context.Context, ids []pgtype.UUID) (map[pgtype.UUID]*Metadata, error) {
+	out := make(map[pgtype.UUID]*Metadata)
+	if len(ids) == 0 {
+		return out, nil
+	}
+	stored, err := s.repo.ListVariableMetadata(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list metadata: %w", err)
+	}
+	for _, m := range stored {
+		out[m.VariableID] = toMetadata(m)
+	}
+	return out, nil
+}
+
+// SetMetadata replaces the metadata of a variable of the environment. Like
+// rotation policies, metadata of inherited variables is edited in the
+// environment that defines them. Metadata is not versioned.
+func (s *svc) SetMetadata(ctx context.Context, params SetMetadataParams) (Metadata, error) {
+	if err := params.Metadata.normalize(); err != nil {
+		return Metadata{}, err
+	}
+	env, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, env.ID); err != nil {
+			return err
+		}
+		if err := s.checkETag(ctx, q, env.ID, params.Key, params.IfMatch); err != nil {
+			return err
+		}
+		v, err := lockLocalVariable(ctx, q, env.ID, params.Key)
+		if err != nil {
+			return err
+		}
+		if err := saveMetadata(ctx, q, v, params.Metadata); err != nil {
+			return err
+		}
+		if err := q.TouchVariable(ctx, v.ID); err != nil {
+			return fmt.Errorf("failed to update variable version: %w", err)
+		}
+		return s.audit(ctx, q, env, params.AuthorID, auditMetadataSet, map[string]any{
+			"key":      v.Key,
+			"metadata": params.Metadata,
+		})
+	})
+	if err != nil {
+		return Metadata{}, err
+	}
+	return params.Metadata, nil
+}
@@ -0,0 +1,253 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+var (
+	ErrInvalidPromotion  = errors.New("invalid promotion")
+	ErrPromotionConflict = errors.New("promotion conflicts with existing values")
+)
+
+// ConflictPolicy decides what happens to keys that already exist in the target
+// with a different value.
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictFail      ConflictPolicy = "fail"
+)
+
+type PromoteParams struct {
+	SourceID pgtype.UUID `json:"-"`
+	TargetID pgtype.UUID `json:"target_id"`
+	// Keys selects the variables to promote by exact name or glob pattern
+	// such as DB_*. All variables are promoted when it is empty.
+	Keys     []string       `json:"keys"`
+	Policy   ConflictPolicy `json:"policy"`
+	DryRun   bool           `json:"dry_run"`
+	AuthorID pgtype.UUID    `json:"-"`
+	Message  string         `json:"message"`
+}
+
+// PromotePlan lists what a promotion does to the target. Values are never
+// included.
+type PromotePlan struct {
+	Policy      ConflictPolicy `json:"policy"`
+	DryRun      bool           `json:"dry_run"`
+	Added       []string       `json:"added"`
+	Overwritten []string       `json:"overwritten"`
+	Skipped     []string       `json:"skipped"`
+	Unchanged   []string       `json:"unchanged"`
+	// Conflicts lists keys with a different value in the target.
+	Conflicts []string `json:"conflicts"`
+}
+
+// PromoteVariables copies the selected variables of the source environment to
+// the target in a single transaction and records the promotion in the audit
+// log. With the fail policy nothing is written if any key conflicts, and the
+// plan is returned together with ErrPromotionConflict.
+func (s *svc) PromoteVariables(ctx context.Context, params PromoteParams) (PromotePlan, error) {
+	if !params.DryRun {
+		if err := s.checkUnprotected(ctx, params.TargetID); err != nil {
+			return PromotePlan{}, err
+		}
+	}
+
+	switch params.Policy {
+	case "":
+		params.Policy = ConflictFail
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return PromotePlan{}, fmt.Errorf("%w: unknown policy %q", ErrInvalidPromotion, params.Policy)
+	}
+	if params.SourceID == params.TargetID {
+		return PromotePlan{}, fmt.Errorf("%w: source and target are the same environment", ErrInvalidPromotion)
+	}
+	for _, pattern := range params.Keys {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return PromotePlan{}, fmt.Errorf("%w: bad key pattern %q", ErrInvalidPromotion, pattern)
+		}
+	}
+
+	sourceEnv, err := s.repo.GetEnvironment(ctx, params.SourceID)
+	if err != nil {
+		return PromotePlan{}, fmt.Errorf("failed to fetch source environment: %w", err)
+	}
+	target, err := s.repo.GetEnvironment(ctx, params.TargetID)
+	if err != nil {
+		return PromotePlan{}, fmt.Errorf("failed to fetch target environment: %w", err)
+	}
+	source, err := s.ListVariables(ctx, params.SourceID, true)
+	if err != nil {
+		return PromotePlan{}, err
+	}
+	selected, err := selectKeys(source, params.Keys)
+	if err != nil {
+		return PromotePlan{}, err
+	}
+	message := params.Message
+	if message == "" {
+		message = "promoted from " + sourceEnv.Slug
+	}
+
+	if params.DryRun {
+		plan, _, err := s.planPromotion(ctx, params, selected, nil, message)
+		return plan, err
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, params.TargetID)
+	if err != nil {
+		return PromotePlan{}, err
+	}
+	var plan PromotePlan
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, params.TargetID); err != nil {
+			return err
+		}
+		// The plan is made once the variables of the target are locked, so
+		// that a concurrent write is either seen as a conflict or fails the
+		// promotion instead of being overwritten.
+		locked, err := q.LockVariables(ctx, params.TargetID)
+		if err != nil {
+			return fmt.Errorf("failed to list variables: %w", err)
+		}
+		bases := make(map[string]pgtype.Text, len(locked))
+		for _, v := range locked {
+			bases[v.Key] = pgtype.Text{String: etag(v.ID, v.Version), Valid: true}
+		}
+		var changes []change
+		if plan, changes, err = s.planPromotion(ctx, params, selected, bases, message); err != nil {
+			return err
+		}
+		for _, c := range changes {
+			if _, err := s.apply(ctx, q, params.TargetID, dataKey, c); err != nil {
+				return err
+			}
+		}
+		return s.audit(ctx, q, target, params.AuthorID, auditPromote, map[string]any{
+			"source_environment_id": params.SourceID,
+			"policy":                plan.Policy,
+			"added":                 plan.Added,
+			"overwritten":           plan.Overwritten,
+			"skipped":               plan.Skipped,
+			"message":               message,
+		})
+	})
+	if errors.Is(err, ErrPromotionConflict) {
+		return plan, err
+	}
+	if err != nil {
+		return PromotePlan{}, err
+	}
+	return plan, nil
+}
+
+// planPromotion compares the selected source variables with the effective
+// variables of the target and returns the plan and the changes that carry it
+// out. bases holds the entity tags of the locked local variables of the
+// target, which the changes are based on; a dry run passes nil. With the fail
+// policy and conflicting keys the plan is returned with ErrPromotionConflict.
+func (s *svc) planPromotion(ctx context.Context, params PromoteParams, selected []Variable, bases map[string]pgtype.Text, message string) (PromotePlan, []change, error) {
+	current, err := s.ListVariables(ctx, params.TargetID, true)
+	if err != nil {
+		return PromotePlan{}, nil, err
+	}
+	existing := make(map[string]Variable, len(current))
+	for _, v := range current {
+		existing[v.Key] = v
+	}
+
+	plan := PromotePlan{
+		Policy:      params.Policy,
+		DryRun:      params.DryRun,
+		Added:       []string{},
+		Overwritten: []string{},
+		Skipped:     []string{},
+		Unchanged:   []string{},
+		Conflicts:   []string{},
+	}
+	var changes []change
+	for _, v := range selected {
+		c := change{
+			Key:      v.Key,
+			Value:    v.Value,
+			IsSecret: v.IsSecret,
+			AuthorID: params.AuthorID,
+			Message:  message,
+		}
+		if bases != nil {
+			// A key without a local value must still have none when the
+			// promotion writes it.
+			c.Base = bases[v.Key]
+			c.Base.Valid = true
+		}
+		old, ok := existing[v.Key]
+		switch {
+		case !ok:
+			plan.Added = append(plan.Added, v.Key)
+			changes = append(changes, c)
+		case old.Value == v.Value && old.IsSecret == v.IsSecret:
+			plan.Unchanged = append(plan.Unchanged, v.Key)
+		default:
+			plan.Conflicts = append(plan.Conflicts, v.Key)
+			if params.Policy == ConflictOverwrite {
+				plan.Overwritten = append(plan.Overwritten, v.Key)
+				changes = append(changes, c)
+			} else {
+				plan.Skipped = append(plan.Skipped, v.Key)
+			}
+		}
+	}
+
+	if params.Policy == ConflictFail && len(plan.Conflicts) > 0 {
+		return plan, nil, ErrPromotionConflict
+	}
+	if len(changes) == 0 {
+		return plan, nil, nil
+	}
+	if err := s.checkChanges(ctx, params.TargetID, changes, nil); err != nil {
+		return PromotePlan{}, nil, err
+	}
+	return plan, changes, nil
+}
+
+// selectKeys returns the variables matching any of the patterns. Every pattern
+// must match at least one variable.
+func selectKeys(vars []Variable, patterns []string) ([]Variable, error) {
+	if len(patterns) == 0 {
+		if len(vars) == 0 {
+			return nil, fmt.Errorf("%w: the source environment has no variables", ErrInvalidPromotion)
+		}
+		return vars, nil
+	}
+
+	matched := make(map[string]bool, len(patterns))
+	var out []Variable
+	for _, v := range vars {
+		selected := false
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, v.Key); ok {
+				matched[pattern] = true
+				selected = true
+			}
+		}
+		if selected {
+			out = append(out, v)
+		}
+	}
+	for _, pattern := range patterns {
+		if !matched[pattern] {
+			return nil, fmt.Errorf("%w: %q matches no variables", ErrInvalidPromotion, pattern)
+		}
+	}
+	return out, nil
+}
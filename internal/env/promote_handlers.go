@@ -0,0 +1,84 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/envm-org/envm/internal/auth"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+)
+
+// PromoteVariables copies variables from the environment in the URL to the
+// target environment in the request body. A conflict under the fail policy is
+// answered with 409 and the plan listing the conflicting keys. The caller needs
+// read access to the source and write access to the target, and may only
+// promote keys the access rules let them read in the source and change in the
+// target. Promoting secrets to another project hands their values to whoever
+// can reveal them there, so it also requires permission to reveal secrets of
+// the source project.
+func (h *handler) PromoteVariables(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnvAccess(w, r, auth.AccessRead)
+	if !ok {
+		return
+	}
+
+	var params PromoteParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.SourceID = env.ID
+	params.AuthorID = userID
+
+	target, err := h.service.GetEnv(r.Context(), params.TargetID)
+	if err != nil {
+		http.Error(w, "target environment not found", http.StatusNotFound)
+		return
+	}
+	if err := h.checkEnvAccess(r.Context(), userID, target, auth.AccessWrite); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	vars, err := h.service.ListVariables(r.Context(), env.ID, false)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	selected, err := selectKeys(vars, params.Keys)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	keys := make([]string, len(selected))
+	for i, v := range selected {
+		keys[i] = v.Key
+	}
+	if !h.checkKeys(w, r, env, userID, keys, false) || !h.checkKeys(w, r, target, userID, keys, true) {
+		return
+	}
+	if target.ProjectID != env.ProjectID && slices.ContainsFunc(selected, func(v Variable) bool { return v.IsSecret }) {
+		if err := h.authorizer.CanRevealSecrets(r.Context(), userID, env.ProjectID); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	r, ok = h.lockOverride(w, r, params.TargetID)
+	if !ok {
+		return
+	}
+
+	plan, err := h.service.PromoteVariables(r.Context(), params)
+	if err != nil {
+		if errors.Is(err, ErrPromotionConflict) {
+			HTTPwriter.JSON(w, http.StatusConflict, plan)
+			return
+		}
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, plan)
+}
@@ -0,0 +1,71 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestPromoteVariables(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ConflictPolicy
+		want   PromotePlan
+		err    error
+		after  map[string]string
+	}{
+		{
+			name:   "skip",
+			policy: ConflictSkip,
+			want:   PromotePlan{Added: []string{"NEW"}, Skipped: []string{"DEBUG"}, Unchanged: []string{"PORT"}, Conflicts: []string{"DEBUG"}},
+			after:  map[string]string{"PORT": "8080", "DEBUG": "false", "NEW": "x"},
+		},
+		{
+			name:   "overwrite",
+			policy: ConflictOverwrite,
+			want:   PromotePlan{Added: []string{"NEW"}, Overwritten: []string{"DEBUG"}, Unchanged: []string{"PORT"}, Conflicts: []string{"DEBUG"}},
+			after:  map[string]string{"PORT": "8080", "DEBUG": "true", "NEW": "x"},
+		},
+		{
+			name:   "fail",
+			policy: ConflictFail,
+			want:   PromotePlan{Added: []string{"NEW"}, Skipped: []string{"DEBUG"}, Unchanged: []string{"PORT"}, Conflicts: []string{"DEBUG"}},
+			err:    ErrPromotionConflict,
+			after:  map[string]string{"PORT": "8080", "DEBUG": "false"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			staging := f.newEnv(t, "staging")
+			f.set(t, f.env.ID, "PORT", "8080")
+			f.set(t, f.env.ID, "DEBUG", "true")
+			f.set(t, f.env.ID, "NEW", "x")
+			f.set(t, staging.ID, "PORT", "8080")
+			f.set(t, staging.ID, "DEBUG", "false")
+
+			for _, dryRun := range []bool{true, false} {
+				plan, err := f.s.PromoteVariables(context.Background(), PromoteParams{
+					SourceID: f.env.ID,
+					TargetID: staging.ID,
+					Policy:   tt.policy,
+					DryRun:   dryRun,
+					AuthorID: f.user,
+				})
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("PromoteVariables(dry run %v) error = %v, want %v", dryRun, err, tt.err)
+				}
+				if !slices.Equal(plan.Added, tt.want.Added) || !slices.Equal(plan.Overwritten, tt.want.Overwritten) ||
+					!slices.Equal(plan.Skipped, tt.want.Skipped) || !slices.Equal(plan.Unchanged, tt.want.Unchanged) ||
+					!slices.Equal(plan.Conflicts, tt.want.Conflicts) {
+					t.Errorf("PromoteVariables(dry run %v) = %+v, want %+v", dryRun, plan, tt.want)
+				}
+			}
+			if got := f.values(t, staging.ID); !maps.Equal(got, tt.after) {
+				t.Errorf("target after promotion = %v, want %v", got, tt.after)
+			}
+		})
+	}
+}
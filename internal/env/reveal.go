@@ -0,0 +1,55 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrReasonRequired = errors.New("a reason is required to reveal secrets")
+
+type RevealParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Key           string      `json:"-"`
+	UserID        pgtype.UUID `json:"-"`
+	Reason        string      `json:"reason"`
+}
+
+// RevealVariable returns the plaintext value of a variable. Revealing a secret
+// is recorded in the audit log before the value is returned.
+func (s *svc) RevealVariable(ctx context.Context, params RevealParams) (Variable, error) {
+	v, err := s.GetVariable(ctx, params.EnvironmentID, params.Key, true)
+	if err != nil {
+		return Variable{}, err
+	}
+	if !v.IsSecret {
+		return v, nil
+	}
+	if err := s.AuditReveal(ctx, params.EnvironmentID, params.UserID, []string{v.Key}, params.Reason); err != nil {
+		return Variable{}, err
+	}
+	return v, nil
+}
+
+// AuditReveal records that userID saw the plaintext of the given secret keys.
+func (s *svc) AuditReveal(ctx context.Context, envID, userID pgtype.UUID, keys []string, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ErrReasonRequired
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	return s.audit(ctx, s.repo, env, userID, auditReveal, map[string]any{
+		"keys":   keys,
+		"reason": reason,
+	})
+}
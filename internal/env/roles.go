@@ -0,0 +1,132 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/internal/auth"
+)
+
+var ErrInvalidRoleAccess = errors.New("invalid role access")
+
+// overridableRoles are the project roles whose access can be set per
+// environment. Project admins always have write access.
+var overridableRoles = []auth.Role{auth.RoleViewer, auth.RoleDeveloper, auth.RoleMaintainer}
+
+// RoleAccess is the access of a project role to an environment. Default is set
+// when the environment does not override the access of the role.
+type RoleAccess struct {
+	Role    auth.Role   `json:"role"`
+	Access  auth.Access `json:"access"`
+	Default bool        `json:"default,omitempty"`
+}
+
+type SetRoleAccessParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Role          auth.Role   `json:"-"`
+	Access        auth.Access `json:"access"`
+	AuthorID      pgtype.UUID `json:"-"`
+}
+
+func checkOverridableRole(role auth.Role) error {
+	if !slices.Contains(overridableRoles, role) {
+		return fmt.Errorf("%w: role must be one of %v", ErrInvalidRoleAccess, overridableRoles)
+	}
+	return nil
+}
+
+// ListRoleAccess returns the access of every project role that can be
+// overridden to the environment.
+func (s *svc) ListRoleAccess(ctx context.Context, envID pgtype.UUID) ([]RoleAccess, error) {
+	stored, err := s.repo.ListEnvironmentRoleAccess(ctx, envID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role access: %w", err)
+	}
+
+	out := make([]RoleAccess, 0, len(overridableRoles))
+	for _, role := range overridableRoles {
+		access := RoleAccess{Role: role, Access: auth.DefaultAccess(role), Default: true}
+		for _, o := range stored {
+			if auth.Role(o.Role) == role {
+				access = RoleAccess{Role: role, Access: auth.Access(o.Access)}
+			}
+		}
+		out = append(out, access)
+	}
+	return out, nil
+}
+
+// SetRoleAccess overrides the access of a project role to the environment.
+func (s *svc) SetRoleAccess(ctx context.Context, params SetRoleAccessParams) (RoleAccess, error) {
+	if err := checkOverridableRole(params.Role); err != nil {
+		return RoleAccess{}, err
+	}
+	if !params.Access.Valid() {
+		return RoleAccess{}, fmt.Errorf("%w: access must be none, read or write", ErrInvalidRoleAccess)
+	}
+	env, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return RoleAccess{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, env.ID); err != nil {
+			return err
+		}
+		if _, err := q.UpsertEnvironmentRoleAccess(ctx, repo.UpsertEnvironmentRoleAccessParams{
+			EnvironmentID: env.ID,
+			Role:          string(params.Role),
+			Access:        string(params.Access),
+			UpdatedBy:     params.AuthorID,
+		}); err != nil {
+			return fmt.Errorf("failed to save role access: %w", err)
+		}
+		return s.audit(ctx, q, env, params.AuthorID, auditRoleAccess, map[string]any{
+			"role":   params.Role,
+			"access": params.Access,
+		})
+	})
+	if err != nil {
+		return RoleAccess{}, err
+	}
+	return RoleAccess{Role: params.Role, Access: params.Access}, nil
+}
+
+// ResetRoleAccess removes the override of a project role, which gets its
+// default access to the environment again.
+func (s *svc) ResetRoleAccess(ctx context.Context, envID pgtype.UUID, role auth.Role, userID pgtype.UUID) (RoleAccess, error) {
+	if err := checkOverridableRole(role); err != nil {
+		return RoleAccess{}, err
+	}
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return RoleAccess{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	access := RoleAccess{Role: role, Access: auth.DefaultAccess(role), Default: true}
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, env.ID); err != nil {
+			return err
+		}
+		if err := q.DeleteEnvironmentRoleAccess(ctx, repo.DeleteEnvironmentRoleAccessParams{
+			EnvironmentID: env.ID,
+			Role:          string(role),
+		}); err != nil {
+			return fmt.Errorf("failed to reset role access: %w", err)
+		}
+		return s.audit(ctx, q, env, userID, auditRoleAccess, map[string]any{
+			"role":   role,
+			"access": access.Access,
+			"reset":  true,
+		})
+	})
+	if err != nil {
+		return RoleAccess{}, err
+	}
+	return access, nil
+}
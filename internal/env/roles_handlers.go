@@ -0,0 +1,85 @@
+package env
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/envm-org/envm/internal/auth"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/go-chi/chi/v5"
+)
+
+// ListRoleAccess returns what each project role may do with the environment.
+func (h *handler) ListRoleAccess(w http.ResponseWriter, r *http.Request) {
+	env, _, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	access, err := h.service.ListRoleAccess(r.Context(), env.ID)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, access)
+}
+
+// SetRoleAccess overrides the access of the project role in the URL to the
+// environment, for example to make prod read-only for developers. Only owners
+// and admins of the organization can change role access.
+func (h *handler) SetRoleAccess(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanAssignRoles(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var params SetRoleAccessParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.Role = auth.Role(chi.URLParam(r, "role"))
+	params.AuthorID = userID
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	access, err := h.service.SetRoleAccess(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, access)
+}
+
+// ResetRoleAccess gives the project role in the URL its default access to the
+// environment again.
+func (h *handler) ResetRoleAccess(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanAssignRoles(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	access, err := h.service.ResetRoleAccess(r.Context(), env.ID, auth.Role(chi.URLParam(r, "role")), userID)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, access)
+}
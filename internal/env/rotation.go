@@ -0,0 +1,323 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+var ErrInvalidRotation = errors.New("invalid rotation policy")
+
+// SetRotationParams attaches a rotation policy to a variable. Interval is a
+// duration such as 720h after which the value should be rotated, ExpiresAt a
+// hard deadline. At least one of them must be set.
+type SetRotationParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Key           string      `json:"-"`
+	Interval      string      `json:"interval"`
+	ExpiresAt     *time.Time  `json:"expires_at"`
+	AuthorID      pgtype.UUID `json:"-"`
+}
+
+// Rotation is the rotation policy of a variable. The value is due for
+// rotation at DueAt, the earlier of the hard expiry and the last rotation plus
+// the interval. Writing a new value resets the interval but not the expiry.
+type Rotation struct {
+	Interval  string     `json:"interval,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RotatedAt time.Time  `json:"rotated_at"`
+	DueAt     time.Time  `json:"due_at"`
+	Overdue   bool       `json:"overdue"`
+}
+
+// StaleSecret is a variable that is overdue for rotation or will be soon.
+// Generated variables can be rotated through the regenerate endpoint.
+type StaleSecret struct {
+	EnvironmentID pgtype.UUID `json:"environment_id"`
+	Environment   string      `json:"environment"`
+	Key           string      `json:"key"`
+	DueAt         time.Time   `json:"due_at"`
+	Overdue       bool        `json:"overdue"`
+	Generated     bool        `json:"generated"`
+}
+
+func toRotation(r repo.VariableRotation) Rotation {
+	out := Rotation{RotatedAt: r.RotatedAt.Time}
+	if r.IntervalSeconds.Valid {
+		interval := time.Duration(r.IntervalSeconds.Int32) * time.Second
+		out.Interval = interval.String()
+		out.DueAt = out.RotatedAt.Add(interval)
+	}
+	if r.ExpiresAt.Valid {
+		expires := r.ExpiresAt.Time
+		out.ExpiresAt = &expires
+		if out.DueAt.IsZero() || expires.Before(out.DueAt) {
+			out.DueAt = expires
+		}
+	}
+	out.Overdue = !out.DueAt.After(time.Now())
+	return out
+}
+
+// localVariable returns the variable defined in the environment itself, as
+// opposed to one inherited from an ancestor.
+func (s *svc) localVariable(ctx context.Context, envID pgtype.UUID, key string) (repo.Variable, error) {
+	v, err := s.repo.GetVariable(ctx, repo.GetVariableParams{
+		EnvironmentID: envID,
+		Key:           key,
+	})
+	if err == pgx.ErrNoRows {
+		return repo.Variable{}, ErrVariableNotFound
+	}
+	if err != nil {
+		return repo.Variable{}, fmt.Errorf("failed to fetch variable: %w", err)
+	}
+	return v, nil
+}
+
+// lockLocalVariable is localVariable within a transaction. The row stays
+// locked until it ends.
+func lockLocalVariable(ctx context.Context, q *repo.Queries, envID pgtype.UUID, key string) (repo.Variable, error) {
+	v, err := q.LockVariable(ctx, repo.LockVariableParams{
+		EnvironmentID: envID,
+		Key:           key,
+	})
+	if err == pgx.ErrNoRows {
+		return repo.Variable{}, ErrVariableNotFound
+	}
+	if err != nil {
+		return repo.Variable{}, fmt.Errorf("failed to fetch variable: %w", err)
+	}
+	return v, nil
+}
+
+// rotation returns the rotation policy of the variable, or nil.
+func (s *svc) rotation(ctx context.Context, variableID pgtype.UUID) (*Rotation, error) {
+	stored, err := s.repo.GetVariableRotation(ctx, variableID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rotation policy: %w", err)
+	}
+	out := toRotation(stored)
+	return &out, nil
+}
+
+// SetRotation attaches a rotation policy to a variable of the environment,
+// replacing any existing one. Inherited variables have to be configured in the
+// environment that defines them.
+func (s *svc) SetRotation(ctx context.Context, params SetRotationParams) (Rotation, error) {
+	var interval pgtype.Int4
+	if params.Interval != "" {
+		d, err := time.ParseDuration(params.Interval)
+		if err != nil || d < time.Minute || d > 10*365*24*time.Hour {
+			return Rotation{}, fmt.Errorf("%w: interval must be a duration between 1m and 87600h", ErrInvalidRotation)
+		}
+		interval = pgtype.Int4{Int32: int32(d / time.Second), Valid: true}
+	}
+	var expiresAt pgtype.Timestamptz
+	if params.ExpiresAt != nil {
+		expiresAt = pgtype.Timestamptz{Time: *params.ExpiresAt, Valid: true}
+	}
+	if !interval.Valid && !expiresAt.Valid {
+		return Rotation{}, fmt.Errorf("%w: set an interval, an expiry or both", ErrInvalidRotation)
+	}
+
+	env, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return Rotation{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	var stored repo.VariableRotation
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, params.EnvironmentID); err != nil {
+			return err
+		}
+		v, err := lockLocalVariable(ctx, q, env.ID, params.Key)
+		if err != nil {
+			return err
+		}
+		stored, err = q.UpsertVariableRotation(ctx, repo.UpsertVariableRotationParams{
+			VariableID:      v.ID,
+			IntervalSeconds: interval,
+			ExpiresAt:       expiresAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save rotation policy: %w", err)
+		}
+		if err := q.TouchVariable(ctx, v.ID); err != nil {
+			return fmt.Errorf("failed to update variable version: %w", err)
+		}
+		return s.audit(ctx, q, env, params.AuthorID, auditRotationSet, map[string]any{
+			"key":        v.Key,
+			"interval":   params.Interval,
+			"expires_at": params.ExpiresAt,
+		})
+	})
+	if err != nil {
+		return Rotation{}, err
+	}
+	return toRotation(stored), nil
+}
+
+func (s *svc) DeleteRotation(ctx context.Context, envID pgtype.UUID, key string, userID pgtype.UUID) error {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	return s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, envID); err != nil {
+			return err
+		}
+		v, err := lockLocalVariable(ctx, q, env.ID, key)
+		if err != nil {
+			return err
+		}
+		if err := q.DeleteVariableRotation(ctx, v.ID); err != nil {
+			return fmt.Errorf("failed to delete rotation policy: %w", err)
+		}
+		if err := q.TouchVariable(ctx, v.ID); err != nil {
+			return fmt.Errorf("failed to update variable version: %w", err)
+		}
+		return s.audit(ctx, q, env, userID, auditRotationDelete, map[string]any{"key": v.Key})
+	})
+}
+
+// ListStaleSecrets returns the variables of the project that are overdue for
+// rotation or will be due within the given window, earliest first.
+func (s *svc) ListStaleSecrets(ctx context.Context, projectID pgtype.UUID, within time.Duration) ([]StaleSecret, error) {
+	now := time.Now()
+	rows, err := s.repo.ListStaleRotations(ctx, repo.ListStaleRotationsParams{
+		ProjectID: projectID,
+		DueBefore: pgtype.Timestamptz{Time: now.Add(within), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale secrets: %w", err)
+	}
+
+	out := make([]StaleSecret, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, StaleSecret{
+			EnvironmentID: row.EnvironmentID,
+			Environment:   row.EnvironmentSlug,
+			Key:           row.Key,
+			DueAt:         row.DueAt.Time,
+			Overdue:       !row.DueAt.Time.After(now),
+			Generated:     row.Generated,
+		})
+	}
+	return out, nil
+}
+
+// NotifyExpiringSecrets emails the admins of every project with variables
+// that are due for rotation within the given window. Each variable is reported
+// to each admin once until it is rotated or its policy changes; an admin whose
+// mail fails is retried on the next run without mailing the others again.
+// Values are never included.
+func (s *svc) NotifyExpiringSecrets(ctx context.Context, within time.Duration) (int, error) {
+	rows, err := s.repo.ListUnnotifiedRotations(ctx, pgtype.Timestamptz{Time: time.Now().Add(within), Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expiring secrets: %w", err)
+	}
+
+	byProject := make(map[pgtype.UUID][]repo.ListUnnotifiedRotationsRow)
+	var projects []pgtype.UUID
+	for _, row := range rows {
+		if _, ok := byProject[row.ProjectID]; !ok {
+			projects = append(projects, row.ProjectID)
+		}
+		byProject[row.ProjectID] = append(byProject[row.ProjectID], row)
+	}
+
+	notified := 0
+	var errs []error
+	for _, projectID := range projects {
+		n, err := s.notifyProject(ctx, projectID, byProject[projectID])
+		notified += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return notified, errors.Join(errs...)
+}
+
+// notifyProject mails the rows of one project to each of its admins who has
+// not been told about them yet. The rows are marked notified once every admin
+// has been.
+func (s *svc) notifyProject(ctx context.Context, projectID pgtype.UUID, rows []repo.ListUnnotifiedRotationsRow) (int, error) {
+	project, err := s.repo.GetProject(ctx, projectID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch project: %w", err)
+	}
+	recipients, err := s.repo.ListProjectAdminEmails(ctx, projectID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list project admins: %w", err)
+	}
+
+	ids := make([]pgtype.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.VariableID
+	}
+	notices, err := s.repo.ListRotationNotices(ctx, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list rotation notices: %w", err)
+	}
+	type notice struct {
+		variableID pgtype.UUID
+		email      string
+	}
+	sent := make(map[notice]bool, len(notices))
+	for _, n := range notices {
+		sent[notice{n.VariableID, n.Email}] = true
+	}
+
+	subject := fmt.Sprintf("Secrets due for rotation in %s", project.Name)
+	var errs []error
+	for _, to := range recipients {
+		var body strings.Builder
+		var pending []pgtype.UUID
+		var cycles []pgtype.Timestamptz
+		fmt.Fprintf(&body, "The following secrets in project %s are due for rotation:\n\n", project.Name)
+		for _, row := range rows {
+			if sent[notice{row.VariableID, to}] {
+				continue
+			}
+			fmt.Fprintf(&body, "  %s/%s  due %s\n", row.EnvironmentSlug, row.Key, row.DueAt.Time.UTC().Format(time.RFC3339))
+			pending = append(pending, row.VariableID)
+			cycles = append(cycles, row.UpdatedAt)
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		if err := s.mailer.SendEmail(to, subject, body.String()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to notify %s: %w", to, err))
+			continue
+		}
+		if err := s.repo.RecordRotationNotices(ctx, repo.RecordRotationNoticesParams{
+			VariableIds: pending,
+			Email:       to,
+			Cycles:      cycles,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to record rotation notice: %w", err))
+		}
+	}
+	if len(errs) > 0 {
+		return 0, errors.Join(errs...)
+	}
+
+	for _, row := range rows {
+		if err := s.repo.MarkRotationNotified(ctx, row.VariableID); err != nil {
+			return 0, fmt.Errorf("failed to mark rotation notified: %w", err)
+		}
+	}
+	return len(rows), nil
+}
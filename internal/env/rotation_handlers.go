@@ -0,0 +1,117 @@
+package env
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/envm-org/envm/internal/auth"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func (h *handler) SetRotation(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	var params SetRotationParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.Key = chi.URLParam(r, "key")
+	params.AuthorID = userID
+	if !h.checkKey(w, r, env, userID, params.Key, true) {
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	rotation, err := h.service.SetRotation(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, rotation)
+}
+
+func (h *handler) DeleteRotation(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	key := chi.URLParam(r, "key")
+	if !h.checkKey(w, r, env, userID, key, true) {
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRotation(r.Context(), env.ID, key, userID); err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, map[string]string{"message": "rotation policy deleted"})
+}
+
+// ListStaleSecrets lists the variables of the project given by the project_id
+// query parameter that are overdue for rotation. With within set to a duration
+// such as 168h it also includes those that will be due in that window.
+func (h *handler) ListStaleSecrets(w http.ResponseWriter, r *http.Request) {
+	var projectID pgtype.UUID
+	if err := projectID.Scan(r.URL.Query().Get("project_id")); err != nil {
+		http.Error(w, "invalid project_id format", http.StatusBadRequest)
+		return
+	}
+	var within time.Duration
+	if withinStr := r.URL.Query().Get("within"); withinStr != "" {
+		var err error
+		within, err = time.ParseDuration(withinStr)
+		if err != nil || within < 0 {
+			http.Error(w, "invalid within format: use a duration such as 168h", http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.HasProjectAccess(r.Context(), userID, projectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	stale, err := h.service.ListStaleSecrets(r.Context(), projectID, within)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	envs, err := h.service.ListEnvs(r.Context(), projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Leave out the secrets of environments the caller's role has no access to.
+	readable := make(map[pgtype.UUID]bool, len(envs))
+	for _, env := range envs {
+		readable[env.ID] = h.checkEnvAccess(r.Context(), userID, env, auth.AccessRead) == nil
+	}
+	visible := make([]StaleSecret, 0, len(stale))
+	for _, s := range stale {
+		if readable[s.EnvironmentID] {
+			visible = append(visible, s)
+		}
+	}
+	HTTPwriter.JSON(w, http.StatusOK, visible)
+}
@@ -0,0 +1,69 @@
+package env
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+func TestNotifyExpiringSecrets(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	admin := f.newUser(t, "admin")
+	if _, err := f.q.AddProjectMember(ctx, repo.AddProjectMemberParams{
+		ProjectID: f.project.ID,
+		UserID:    admin,
+		Role:      "admin",
+	}); err != nil {
+		t.Fatalf("failed to add project member: %v", err)
+	}
+	f.set(t, f.env.ID, "API_KEY", "secret")
+	expires := time.Now().Add(time.Hour)
+	if _, err := f.s.SetRotation(ctx, SetRotationParams{
+		EnvironmentID: f.env.ID,
+		Key:           "API_KEY",
+		ExpiresAt:     &expires,
+		AuthorID:      f.user,
+	}); err != nil {
+		t.Fatalf("SetRotation() error = %v", err)
+	}
+	mailer := f.s.mailer.(*testMailer)
+
+	// A failed recipient does not keep the others from being notified.
+	mailer.fail = map[string]bool{"admin@example.com": true}
+	if n, err := f.s.NotifyExpiringSecrets(ctx, 24*time.Hour); err == nil || n != 0 {
+		t.Fatalf("NotifyExpiringSecrets() = %d, %v, want an error", n, err)
+	}
+	if want := []string{"owner@example.com"}; !slices.Equal(mailer.sent, want) {
+		t.Fatalf("mail sent to %v, want %v", mailer.sent, want)
+	}
+
+	// The next run only retries the recipient that failed.
+	mailer.fail, mailer.sent = nil, nil
+	if n, err := f.s.NotifyExpiringSecrets(ctx, 24*time.Hour); err != nil || n != 1 {
+		t.Fatalf("NotifyExpiringSecrets() = %d, %v, want 1", n, err)
+	}
+	if want := []string{"admin@example.com"}; !slices.Equal(mailer.sent, want) {
+		t.Fatalf("mail sent to %v, want %v", mailer.sent, want)
+	}
+
+	// Once everyone has been told, nothing is sent until the policy changes.
+	mailer.sent = nil
+	if n, err := f.s.NotifyExpiringSecrets(ctx, 24*time.Hour); err != nil || n != 0 || len(mailer.sent) != 0 {
+		t.Fatalf("NotifyExpiringSecrets() = %d, %v, sent %v, want nothing", n, err, mailer.sent)
+	}
+	if _, err := f.s.SetRotation(ctx, SetRotationParams{
+		EnvironmentID: f.env.ID,
+		Key:           "API_KEY",
+		ExpiresAt:     &expires,
+		AuthorID:      f.user,
+	}); err != nil {
+		t.Fatalf("SetRotation() error = %v", err)
+	}
+	if n, err := f.s.NotifyExpiringSecrets(ctx, 24*time.Hour); err != nil || n != 1 || len(mailer.sent) != 2 {
+		t.Fatalf("NotifyExpiringSecrets() after a policy change = %d, %v, sent %v, want both admins", n, err, mailer.sent)
+	}
+}
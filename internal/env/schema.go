@@ -0,0 +1,144 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/envm-org/envm/pkg/varschema"
+)
+
+// SchemaError rejects a write that would violate the project schema.
+type SchemaError struct {
+	Violations []varschema.Violation `json:"violations"`
+}
+
+func (e *SchemaError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Key + ": " + v.Message
+	}
+	return "schema violation: " + strings.Join(msgs, "; ")
+}
+
+// ComplianceReport lists the violations of the project schema in the
+// effective variables of an environment.
+type ComplianceReport struct {
+	HasSchema  bool                  `json:"has_schema"`
+	Compliant  bool                  `json:"compliant"`
+	Violations []varschema.Violation `json:"violations"`
+}
+
+// projectSchema returns the schema of the project, or nil if it has none.
+func (s *svc) projectSchema(ctx context.Context, projectID pgtype.UUID) (*varschema.Schema, error) {
+	stored, err := s.repo.GetProjectSchema(ctx, projectID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schema: %w", err)
+	}
+	return varschema.Parse(stored.Definition)
+}
+
+// checkChanges validates changes against the schema of the environment's
+// project before they are applied. masked lists keys the write hides with a
+// tombstone; other deleted keys fall back to their inherited value, if any.
+func (s *svc) checkChanges(ctx context.Context, envID pgtype.UUID, changes []change, masked []string) error {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	schema, err := s.projectSchema(ctx, env.ProjectID)
+	if err != nil || schema == nil {
+		return err
+	}
+
+	current, err := s.effective(ctx, env)
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]bool, len(current))
+	for _, l := range current {
+		keys[l.v.Key] = true
+	}
+	inherited, err := s.inheritedKeys(ctx, env)
+	if err != nil {
+		return err
+	}
+	for _, key := range masked {
+		inherited[key] = false
+	}
+
+	var violations []varschema.Violation
+	var written []string
+	for _, c := range changes {
+		if c.Delete {
+			keys[c.Key] = inherited[c.Key]
+			continue
+		}
+		keys[c.Key] = true
+		written = append(written, c.Key)
+		violations = append(violations, schema.CheckKey(c.Key)...)
+		violations = append(violations, schema.CheckValue(c.Key, c.Value)...)
+	}
+	for _, c := range changes {
+		if c.Delete && !keys[c.Key] && schema.Variables[c.Key].Required {
+			violations = append(violations, varschema.Violation{
+				Key:     c.Key,
+				Rule:    varschema.RuleRequired,
+				Message: "required key cannot be deleted",
+			})
+		}
+	}
+
+	all := make([]string, 0, len(keys))
+	for key, present := range keys {
+		if present {
+			all = append(all, key)
+		}
+	}
+	sort.Strings(all)
+	violations = append(violations, schema.Collisions(all, written)...)
+
+	if len(violations) > 0 {
+		return &SchemaError{Violations: violations}
+	}
+	return nil
+}
+
+// CheckCompliance validates the effective variables of the environment against
+// the project schema.
+func (s *svc) CheckCompliance(ctx context.Context, envID pgtype.UUID) (ComplianceReport, error) {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return ComplianceReport{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	schema, err := s.projectSchema(ctx, env.ProjectID)
+	if err != nil {
+		return ComplianceReport{}, err
+	}
+	report := ComplianceReport{Compliant: true, Violations: []varschema.Violation{}}
+	if schema == nil {
+		return report, nil
+	}
+	report.HasSchema = true
+
+	vars, err := s.ListVariables(ctx, envID, true)
+	if err != nil {
+		return ComplianceReport{}, err
+	}
+	values := make(map[string]string, len(vars))
+	for _, v := range vars {
+		values[v.Key] = v.Value
+	}
+	if violations := schema.Check(values); len(violations) > 0 {
+		report.Compliant = false
+		report.Violations = violations
+	}
+	return report, nil
+}
@@ -2,10 +2,17 @@ package env
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/internal/auth"
+	"github.com/envm-org/envm/pkg/email"
+	"github.com/envm-org/envm/pkg/encryption"
 )
 
 type Service interface {
@@ -14,14 +21,109 @@ type Service interface {
 	GetEnv(ctx context.Context, id pgtype.UUID) (repo.Environment, error)
 	UpdateEnv(ctx context.Context, tempEnv repo.UpdateEnvironmentParams) (repo.Environment, error)
 	DeleteEnv(ctx context.Context, id pgtype.UUID) error
+	SetParent(ctx context.Context, envID, parentID pgtype.UUID) (repo.Environment, error)
+	Ancestors(ctx context.Context, envID pgtype.UUID) ([]repo.Environment, error)
+	CloneEnvironment(ctx context.Context, params CloneParams) (EphemeralEnvironment, error)
+	ListEphemeralEnvironments(ctx context.Context, projectID pgtype.UUID) ([]EphemeralEnvironment, error)
+	ReapEphemeralEnvironments(ctx context.Context) (int, error)
+	GetLock(ctx context.Context, envID pgtype.UUID) (*Lock, error)
+	LockEnvironment(ctx context.Context, params LockParams) (Lock, error)
+	UnlockEnvironment(ctx context.Context, envID, userID pgtype.UUID) error
+	AuditLockOverride(ctx context.Context, envID, userID pgtype.UUID, reason, operation string) error
+	GetProtection(ctx context.Context, envID pgtype.UUID) (*Protection, error)
+	SetProtection(ctx context.Context, envID, userID pgtype.UUID, protection Protection) (Protection, error)
+	DeleteProtection(ctx context.Context, envID, userID pgtype.UUID) error
+	ListRoleAccess(ctx context.Context, envID pgtype.UUID) ([]RoleAccess, error)
+	SetRoleAccess(ctx context.Context, params SetRoleAccessParams) (RoleAccess, error)
+	ResetRoleAccess(ctx context.Context, envID pgtype.UUID, role auth.Role, userID pgtype.UUID) (RoleAccess, error)
+
+	ListVariables(ctx context.Context, envID pgtype.UUID, reveal bool) ([]Variable, error)
+	GetVariable(ctx context.Context, envID pgtype.UUID, key string, reveal bool) (Variable, error)
+	SetVariable(ctx context.Context, params SetVariableParams) (Variable, error)
+	RegenerateVariable(ctx context.Context, params RegenerateParams) (Variable, error)
+	SetRotation(ctx context.Context, params SetRotationParams) (Rotation, error)
+	DeleteRotation(ctx context.Context, envID pgtype.UUID, key string, userID pgtype.UUID) error
+	SetMetadata(ctx context.Context, params SetMetadataParams) (Metadata, error)
+	ListStaleSecrets(ctx context.Context, projectID pgtype.UUID, within time.Duration) ([]StaleSecret, error)
+	NotifyExpiringSecrets(ctx context.Context, within time.Duration) (int, error)
+	HygieneReport(ctx context.Context, orgID pgtype.UUID, policy HygienePolicy) (HygieneReport, error)
+	RevealVariable(ctx context.Context, params RevealParams) (Variable, error)
+	AuditReveal(ctx context.Context, envID, userID pgtype.UUID, keys []string, reason string) error
+	DeleteVariable(ctx context.Context, params DeleteVariableParams) error
+	CheckETag(ctx context.Context, envID pgtype.UUID, key, ifMatch string) error
+
+	ListACLs(ctx context.Context, envID pgtype.UUID) ([]ACL, error)
+	SetACL(ctx context.Context, params SetACLParams) (ACL, error)
+	DeleteACL(ctx context.Context, envID, id, userID pgtype.UUID) error
+	KeyAccess(ctx context.Context, envID, userID pgtype.UUID) (KeyAccess, error)
+
+	ListVariableVersions(ctx context.Context, envID pgtype.UUID, key string, reveal bool) ([]VariableVersion, error)
+	ListVariablesAt(ctx context.Context, envID pgtype.UUID, at time.Time, reveal bool) ([]Variable, error)
+	RollbackVariable(ctx context.Context, params RollbackVariableParams) (RollbackResult, error)
+	RollbackEnvironment(ctx context.Context, params RollbackEnvironmentParams) (RollbackResult, error)
+
+	ResolveVariables(ctx context.Context, params ResolveParams) (Resolution, error)
+	DiffEnvironments(ctx context.Context, params DiffParams) (Diff, error)
+	PromoteVariables(ctx context.Context, params PromoteParams) (PromotePlan, error)
+	CheckCompliance(ctx context.Context, envID pgtype.UUID) (ComplianceReport, error)
+
+	CreateSnapshot(ctx context.Context, params CreateSnapshotParams) (Snapshot, error)
+	ListSnapshots(ctx context.Context, envID pgtype.UUID) ([]Snapshot, error)
+	GetSnapshot(ctx context.Context, envID pgtype.UUID, tag string, reveal bool) (Snapshot, error)
+	RestoreSnapshot(ctx context.Context, params RestoreSnapshotParams) (RollbackResult, error)
+
+	CreateChangeRequest(ctx context.Context, params CreateChangeRequestParams) (ChangeRequest, error)
+	ListChangeRequests(ctx context.Context, envID pgtype.UUID, status string) ([]ChangeRequest, error)
+	GetChangeRequest(ctx context.Context, envID, id pgtype.UUID, reveal bool) (ChangeRequest, error)
+	ApproveChangeRequest(ctx context.Context, params ReviewParams) (ChangeRequest, error)
+	RejectChangeRequest(ctx context.Context, params ReviewParams) (ChangeRequest, error)
+	CommentChangeRequest(ctx context.Context, params CommentParams) (Comment, error)
+
+	RegisterConnection(ctx context.Context, params RegisterConnectionParams) (DatabaseConnection, error)
+	ListConnections(ctx context.Context, envID pgtype.UUID) ([]DatabaseConnection, error)
+	GetConnection(ctx context.Context, envID, id pgtype.UUID) (DatabaseConnection, error)
+	DeleteConnection(ctx context.Context, envID, id, userID pgtype.UUID) error
+	IssueCredentials(ctx context.Context, params IssueParams) (Credentials, error)
+	ListLeases(ctx context.Context, envID, connectionID pgtype.UUID) ([]Lease, error)
+	GetLease(ctx context.Context, envID, connectionID, id pgtype.UUID) (Lease, error)
+	RevokeLease(ctx context.Context, envID, connectionID, id, userID pgtype.UUID) error
+	ReapCredentialLeases(ctx context.Context) (int, error)
+
+	ImportVariables(ctx context.Context, params ImportParams) (ImportPlan, error)
+	ApplyBatch(ctx context.Context, params BatchParams) (Batch, error)
+	ExportVariables(ctx context.Context, envID pgtype.UUID, opts ExportOptions, reveal bool) (Export, error)
+
+	EncryptPlaintextVariables(ctx context.Context) (int, error)
 }
 
 type svc struct {
-	repo *repo.Queries
+	repo   *repo.Queries
+	db     *pgxpool.Pool
+	keys   *keyring
+	mailer email.Sender
 }
 
-func NewService(repo *repo.Queries) Service {
-	return &svc{repo: repo}
+func NewService(repo *repo.Queries, db *pgxpool.Pool, keyProvider encryption.KeyProvider, mailer email.Sender) Service {
+	return &svc{
+		repo:   repo,
+		db:     db,
+		keys:   newKeyring(keyProvider),
+		mailer: mailer,
+	}
+}
+
+// withTx runs fn in a transaction that is committed if fn returns no error.
+func (s *svc) withTx(ctx context.Context, fn func(q *repo.Queries) error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(s.repo.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (s *svc) ListEnvs(ctx context.Context, projectID pgtype.UUID) ([]repo.Environment, error) {
@@ -36,10 +138,36 @@ func (s *svc) GetEnv(ctx context.Context, id pgtype.UUID) (repo.Environment, err
 	return s.repo.GetEnvironment(ctx, id)
 }
 
+// UpdateEnv renames the environment. With Version set the update only applies
+// if the environment is still at that version.
 func (s *svc) UpdateEnv(ctx context.Context, tempEnv repo.UpdateEnvironmentParams) (repo.Environment, error) {
-	return s.repo.UpdateEnvironment(ctx, tempEnv)
+	var env repo.Environment
+	err := s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, tempEnv.ID); err != nil {
+			return err
+		}
+		var err error
+		env, err = q.UpdateEnvironment(ctx, tempEnv)
+		if err == pgx.ErrNoRows && tempEnv.Version.Valid {
+			return ErrPreconditionFailed
+		}
+		return err
+	})
+	return env, err
 }
 
 func (s *svc) DeleteEnv(ctx context.Context, id pgtype.UUID) error {
-	return s.repo.DeleteEnvironment(ctx, id)
+	// Checked before the credentials are revoked as well as when deleting.
+	if err := s.checkUnlocked(ctx, s.repo, id); err != nil {
+		return err
+	}
+	if err := s.deleteConnections(ctx, id, "environment deleted"); err != nil {
+		return err
+	}
+	return s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, id); err != nil {
+			return err
+		}
+		return q.DeleteEnvironment(ctx, id)
+	})
 }
\ No newline at end of file
@@ -0,0 +1,301 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	ErrSnapshotExists   = errors.New("a snapshot with this tag already exists")
+	ErrInvalidTag       = errors.New("invalid tag: use letters, digits, dots, underscores and hyphens (max 255 characters)")
+)
+
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Snapshot is an immutable copy of the effective variables of an environment
+// under a tag such as v2026.10.17. Variables are only included when a single
+// snapshot is fetched.
+type Snapshot struct {
+	ID        pgtype.UUID        `json:"id"`
+	Tag       string             `json:"tag"`
+	Message   string             `json:"message,omitempty"`
+	AuthorID  pgtype.UUID        `json:"author_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	Variables []Variable         `json:"variables,omitempty"`
+}
+
+type CreateSnapshotParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Tag           string      `json:"tag"`
+	Message       string      `json:"message"`
+	AuthorID      pgtype.UUID `json:"-"`
+}
+
+type RestoreSnapshotParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Tag           string      `json:"-"`
+	AuthorID      pgtype.UUID `json:"-"`
+	Message       string      `json:"message"`
+	// Keys is what the author may do with the variables of the environment.
+	// The restore fails with ErrRestricted if it changes a key the author may
+	// not change.
+	Keys KeyAccess `json:"-"`
+}
+
+func toSnapshot(s repo.EnvironmentSnapshot) Snapshot {
+	return Snapshot{
+		ID:        s.ID,
+		Tag:       s.Tag,
+		Message:   s.Message.String,
+		AuthorID:  s.AuthorID,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+// CreateSnapshot freezes the effective variables of the environment, including
+// inherited ones, under a new tag. Values are re-encrypted with the data key
+// of the environment so that the snapshot does not depend on its ancestors.
+func (s *svc) CreateSnapshot(ctx context.Context, params CreateSnapshotParams) (Snapshot, error) {
+	if len(params.Tag) > 255 || !tagPattern.MatchString(params.Tag) {
+		return Snapshot{}, ErrInvalidTag
+	}
+	_, err := s.repo.GetSnapshotByTag(ctx, repo.GetSnapshotByTagParams{
+		EnvironmentID: params.EnvironmentID,
+		Tag:           params.Tag,
+	})
+	if err == nil {
+		return Snapshot{}, ErrSnapshotExists
+	}
+	if err != pgx.ErrNoRows {
+		return Snapshot{}, fmt.Errorf("failed to fetch snapshot: %w", err)
+	}
+
+	vars, err := s.ListVariables(ctx, params.EnvironmentID, true)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	dataKey, err := s.keys.dataKey(ctx, s.repo, params.EnvironmentID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var snapshot repo.EnvironmentSnapshot
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		var err error
+		snapshot, err = q.CreateSnapshot(ctx, repo.CreateSnapshotParams{
+			EnvironmentID: params.EnvironmentID,
+			Tag:           params.Tag,
+			Message:       pgtype.Text{String: params.Message, Valid: params.Message != ""},
+			AuthorID:      params.AuthorID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create snapshot: %w", err)
+		}
+		for _, v := range vars {
+			sealed, err := sealValue(dataKey, params.EnvironmentID, v.Key, v.Value)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt variable: %w", err)
+			}
+			if err := q.CreateSnapshotVariable(ctx, repo.CreateSnapshotVariableParams{
+				SnapshotID: snapshot.ID,
+				Key:        v.Key,
+				Value:      sealed,
+				IsSecret:   v.IsSecret,
+				Encrypted:  true,
+			}); err != nil {
+				return fmt.Errorf("failed to save snapshot variable: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return toSnapshot(snapshot), nil
+}
+
+func (s *svc) ListSnapshots(ctx context.Context, envID pgtype.UUID) ([]Snapshot, error) {
+	snapshots, err := s.repo.ListSnapshots(ctx, envID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	out := make([]Snapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		out = append(out, toSnapshot(snapshot))
+	}
+	return out, nil
+}
+
+// GetSnapshot returns the snapshot with the given tag and its variables.
+// Secret values are masked unless reveal is set.
+func (s *svc) GetSnapshot(ctx context.Context, envID pgtype.UUID, tag string, reveal bool) (Snapshot, error) {
+	snapshot, err := s.repo.GetSnapshotByTag(ctx, repo.GetSnapshotByTagParams{
+		EnvironmentID: envID,
+		Tag:           tag,
+	})
+	if err == pgx.ErrNoRows {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to fetch snapshot: %w", err)
+	}
+
+	out := toSnapshot(snapshot)
+	if out.Variables, err = s.snapshotVariables(ctx, envID, snapshot, reveal); err != nil {
+		return Snapshot{}, err
+	}
+	return out, nil
+}
+
+func (s *svc) snapshotVariables(ctx context.Context, envID pgtype.UUID, snapshot repo.EnvironmentSnapshot, reveal bool) ([]Variable, error) {
+	vars, err := s.repo.ListSnapshotVariables(ctx, snapshot.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshot variables: %w", err)
+	}
+	out := make([]Variable, 0, len(vars))
+	if len(vars) == 0 {
+		return out, nil
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, envID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vars {
+		variable := Variable{
+			Key:       v.Key,
+			IsSecret:  v.IsSecret,
+			CreatedAt: snapshot.CreatedAt,
+			UpdatedAt: snapshot.CreatedAt,
+		}
+		if v.IsSecret && !reveal {
+			variable.Masked = true
+		} else if variable.Value, err = openValue(dataKey, envID, v.Key, v.Value, v.Encrypted); err != nil {
+			return nil, fmt.Errorf("failed to decrypt variable %s: %w", v.Key, err)
+		}
+		out = append(out, variable)
+	}
+	return out, nil
+}
+
+// RestoreSnapshot makes the effective variables of the environment equal to
+// the snapshot. Differing values are written to the environment itself, local
+// keys missing from the snapshot are deleted and inherited ones are masked.
+func (s *svc) RestoreSnapshot(ctx context.Context, params RestoreSnapshotParams) (RollbackResult, error) {
+	if err := s.checkUnprotected(ctx, params.EnvironmentID); err != nil {
+		return RollbackResult{}, err
+	}
+
+	snapshot, err := s.GetSnapshot(ctx, params.EnvironmentID, params.Tag, true)
+	if err != nil {
+		return RollbackResult{}, err
+	}
+	env, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return RollbackResult{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	current, err := s.ListVariables(ctx, params.EnvironmentID, true)
+	if err != nil {
+		return RollbackResult{}, err
+	}
+	inherited, err := s.inheritedKeys(ctx, env)
+	if err != nil {
+		return RollbackResult{}, err
+	}
+
+	message := params.Message
+	if message == "" {
+		message = "restore snapshot " + params.Tag
+	}
+	existing := make(map[string]Variable, len(current))
+	for _, v := range current {
+		existing[v.Key] = v
+	}
+	wanted := make(map[string]bool, len(snapshot.Variables))
+
+	result := RollbackResult{Restored: []string{}, Deleted: []string{}}
+	var changes []change
+	for _, v := range snapshot.Variables {
+		wanted[v.Key] = true
+		if old, ok := existing[v.Key]; ok && old.Value == v.Value && old.IsSecret == v.IsSecret {
+			continue
+		}
+		changes = append(changes, change{
+			Key:      v.Key,
+			Value:    v.Value,
+			IsSecret: v.IsSecret,
+			Action:   actionRollback,
+			AuthorID: params.AuthorID,
+			Message:  message,
+		})
+		result.Restored = append(result.Restored, v.Key)
+	}
+	// Inherited keys without a local value only need a tombstone, but they are
+	// still checked as deletions against the schema.
+	var maskOnly []change
+	var masked []string
+	for _, v := range current {
+		if wanted[v.Key] {
+			continue
+		}
+		c := change{
+			Key:      v.Key,
+			Delete:   true,
+			AuthorID: params.AuthorID,
+			Message:  message,
+		}
+		if v.Inherited {
+			maskOnly = append(maskOnly, c)
+		} else {
+			changes = append(changes, c)
+		}
+		if inherited[v.Key] {
+			masked = append(masked, v.Key)
+		}
+		result.Deleted = append(result.Deleted, v.Key)
+	}
+
+	checks := append(slices.Clone(changes), maskOnly...)
+	if err := checkKeys(changedKeys(checks), params.Keys.CanWrite); err != nil {
+		return RollbackResult{}, err
+	}
+	if err := s.checkChanges(ctx, params.EnvironmentID, checks, masked); err != nil {
+		return RollbackResult{}, err
+	}
+	dataKey, err := s.keys.dataKey(ctx, s.repo, params.EnvironmentID)
+	if err != nil {
+		return RollbackResult{}, err
+	}
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, params.EnvironmentID); err != nil {
+			return err
+		}
+		for _, c := range changes {
+			if _, err := s.apply(ctx, q, params.EnvironmentID, dataKey, c); err != nil {
+				return err
+			}
+		}
+		for _, key := range masked {
+			if err := q.CreateVariableTombstone(ctx, repo.CreateVariableTombstoneParams{
+				EnvironmentID: params.EnvironmentID,
+				Key:           key,
+			}); err != nil {
+				return fmt.Errorf("failed to mask inherited variable: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return RollbackResult{}, err
+	}
+	return result, nil
+}
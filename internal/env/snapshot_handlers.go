@@ -0,0 +1,152 @@
+package env
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *handler) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	var params CreateSnapshotParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.AuthorID = userID
+
+	snapshot, err := h.service.CreateSnapshot(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, snapshot)
+}
+
+func (h *handler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	env, _, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	snapshots, err := h.service.ListSnapshots(r.Context(), env.ID)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, snapshots)
+}
+
+// GetSnapshot returns a snapshot and its variables by tag. Secrets are masked
+// unless reveal=true is given together with a reason.
+func (h *handler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	reveal, reason, ok := h.revealRequested(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	access, ok := h.keyAccess(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	snapshot, err := h.service.GetSnapshot(r.Context(), env.ID, chi.URLParam(r, "tag"), reveal)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	snapshot.Variables = access.Redact(snapshot.Variables)
+	if reveal {
+		var keys []string
+		for _, v := range snapshot.Variables {
+			if v.IsSecret && !v.Restricted {
+				keys = append(keys, v.Key)
+			}
+		}
+		if !h.auditReveal(w, r, env, userID, reason, keys) {
+			return
+		}
+	}
+	HTTPwriter.JSON(w, http.StatusOK, snapshot)
+}
+
+// DiffSnapshot compares a snapshot (left) with the live variables of the
+// environment (right).
+func (h *handler) DiffSnapshot(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	reveal, reason, ok := h.revealRequested(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	access, ok := h.keyAccess(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	diff, err := h.service.DiffEnvironments(r.Context(), DiffParams{
+		Left:   DiffSide{EnvironmentID: env.ID, Tag: chi.URLParam(r, "tag")},
+		Right:  DiffSide{EnvironmentID: env.ID},
+		Reveal: reveal,
+	})
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	diff.redact(access, access)
+	if reveal {
+		left, right := diff.revealed()
+		if !h.auditReveal(w, r, env, userID, reason, mergeKeys(left, right)) {
+			return
+		}
+	}
+	HTTPwriter.JSON(w, http.StatusOK, diff)
+}
+
+func (h *handler) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	var params RestoreSnapshotParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil && err != io.EOF {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.Tag = chi.URLParam(r, "tag")
+	params.AuthorID = userID
+	if params.Keys, ok = h.keyAccess(w, r, env, userID); !ok {
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.RestoreSnapshot(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,476 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/pkg/secretgen"
+)
+
+var (
+	ErrVariableNotFound = errors.New("variable not found")
+	ErrInvalidKey       = errors.New("invalid key: use letters, digits, underscores and hyphens (max 255 characters)")
+	ErrConcurrentChange = errors.New("variable was changed by another request: retry")
+)
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]*$`)
+
+// Variable is the API representation of a stored variable. Secret values are
+// only populated when the caller explicitly asked for them. Source is the slug
+// of the environment that defines the value and Inherited is set when that is
+// an ancestor of the environment. Generator is the spec of a value generated
+// by the server, and PublicKey the public half of a generated key pair, which
+// is only returned when the value is generated. Rotation is the rotation
+// policy of the variable and Metadata its documentation, if it has any.
+// Restricted marks a placeholder for a variable the caller may not read under
+// the access rules of the environment; it only carries the key. Version counts
+// the changes to the variable and ETag is the entity tag of that version.
+type Variable struct {
+	Key        string             `json:"key"`
+	Value      string             `json:"value"`
+	IsSecret   bool               `json:"is_secret"`
+	Masked     bool               `json:"masked,omitempty"`
+	Restricted bool               `json:"restricted,omitempty"`
+	Source     string             `json:"source,omitempty"`
+	Inherited  bool               `json:"inherited,omitempty"`
+	Generator  *secretgen.Spec    `json:"generator,omitempty"`
+	PublicKey  string             `json:"public_key,omitempty"`
+	Rotation   *Rotation          `json:"rotation,omitempty"`
+	Metadata   *Metadata          `json:"metadata,omitempty"`
+	Version    int32              `json:"version,omitempty"`
+	ETag       string             `json:"-"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+type SetVariableParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Key           string      `json:"-"`
+	Value         string      `json:"value"`
+	IsSecret      bool        `json:"is_secret"`
+	// Generate makes the server generate the value instead of taking Value.
+	Generate *secretgen.Spec `json:"generate"`
+	// Metadata replaces the metadata of the variable if set.
+	Metadata *Metadata   `json:"metadata"`
+	AuthorID pgtype.UUID `json:"-"`
+	Message  string      `json:"message"`
+	// IfMatch makes the write fail with ErrPreconditionFailed unless it names
+	// the entity tag of the current variable.
+	IfMatch string `json:"-"`
+}
+
+type DeleteVariableParams struct {
+	EnvironmentID pgtype.UUID
+	Key           string
+	// Inherit removes the local value and any tombstone so that the key takes
+	// the inherited value again. Otherwise an inherited key is masked.
+	Inherit  bool
+	AuthorID pgtype.UUID
+	Message  string
+	// IfMatch is checked like SetVariableParams.IfMatch.
+	IfMatch string
+}
+
+func validateKey(key string) error {
+	if len(key) > 255 || !keyPattern.MatchString(key) {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// toVariable decrypts v with the environment's data key. Secret values are left
+// encrypted and masked unless reveal is set.
+func toVariable(dataKey []byte, v repo.Variable, reveal bool) (Variable, error) {
+	out := Variable{
+		Key:       v.Key,
+		IsSecret:  v.IsSecret.Bool,
+		Version:   v.Version,
+		ETag:      etag(v.ID, v.Version),
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+	}
+	if out.IsSecret && !reveal {
+		out.Masked = true
+		return out, nil
+	}
+
+	value, err := openValue(dataKey, v.EnvironmentID, v.Key, v.Value, v.Encrypted)
+	if err != nil {
+		return Variable{}, fmt.Errorf("failed to decrypt variable %s: %w", v.Key, err)
+	}
+	out.Value = value
+	return out, nil
+}
+
+// ListVariables returns the effective variables of the environment, including
+// those inherited from its ancestors.
+func (s *svc) ListVariables(ctx context.Context, envID pgtype.UUID, reveal bool) ([]Variable, error) {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	vars, err := s.effective(ctx, env)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]pgtype.UUID, 0, len(vars))
+	for _, l := range vars {
+		ids = append(ids, l.v.ID)
+	}
+	metadata, err := s.metadataByVariable(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Variable, 0, len(vars))
+	for _, l := range vars {
+		out, err := s.toLayeredVariable(ctx, env, l, reveal)
+		if err != nil {
+			return nil, err
+		}
+		out.Metadata = metadata[l.v.ID]
+		result = append(result, out)
+	}
+	return result, nil
+}
+
+func (s *svc) GetVariable(ctx context.Context, envID pgtype.UUID, key string, reveal bool) (Variable, error) {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return Variable{}, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+
+	v, err := s.repo.GetVariable(ctx, repo.GetVariableParams{
+		EnvironmentID: envID,
+		Key:           key,
+	})
+	if err == nil {
+		out, err := s.toLayeredVariable(ctx, env, layered{v: v, source: env}, reveal)
+		if err != nil {
+			return Variable{}, err
+		}
+		return s.withPolicies(ctx, out, v.ID)
+	}
+	if err != pgx.ErrNoRows {
+		return Variable{}, fmt.Errorf("failed to fetch variable: %w", err)
+	}
+	if !env.ParentID.Valid {
+		return Variable{}, ErrVariableNotFound
+	}
+
+	vars, err := s.effective(ctx, env)
+	if err != nil {
+		return Variable{}, err
+	}
+	for _, l := range vars {
+		if l.v.Key == key {
+			out, err := s.toLayeredVariable(ctx, env, l, reveal)
+			if err != nil {
+				return Variable{}, err
+			}
+			return s.withPolicies(ctx, out, l.v.ID)
+		}
+	}
+	return Variable{}, ErrVariableNotFound
+}
+
+// withPolicies adds the generator, rotation policy and metadata of the
+// variable to out.
+func (s *svc) withPolicies(ctx context.Context, out Variable, variableID pgtype.UUID) (Variable, error) {
+	var err error
+	if out.Generator, err = s.generator(ctx, variableID); err != nil {
+		return Variable{}, err
+	}
+	if out.Rotation, err = s.rotation(ctx, variableID); err != nil {
+		return Variable{}, err
+	}
+	if out.Metadata, err = s.metadata(ctx, variableID); err != nil {
+		return Variable{}, err
+	}
+	return out, nil
+}
+
+// Actions recorded for each variable version.
+const (
+	actionCreate   = "create"
+	actionUpdate   = "update"
+	actionDelete   = "delete"
+	actionRollback = "rollback"
+)
+
+// change is a single mutation of a variable. Every write to the variables table
+// goes through apply so that it is recorded in the version history.
+type change struct {
+	Key      string
+	Value    string
+	IsSecret bool
+	Delete   bool
+	// Action overrides the create/update action derived from the change.
+	// Deletes are always recorded as actionDelete so that point-in-time reads
+	// and rollbacks see the key as removed; the message says why.
+	Action string
+	// Base, if valid, is the entity tag of the variable the change was
+	// planned against, or empty if the key did not exist then. apply fails
+	// with ErrConcurrentChange if the variable has changed since.
+	Base     pgtype.Text
+	AuthorID pgtype.UUID
+	Message  string
+}
+
+// apply writes c to the environment through q and records the new version.
+// The variable stays locked until the transaction of q ends. dataKey must be
+// the data key of the environment.
+func (s *svc) apply(ctx context.Context, q *repo.Queries, envID pgtype.UUID, dataKey []byte, c change) (repo.Variable, error) {
+	current, err := q.LockVariable(ctx, repo.LockVariableParams{
+		EnvironmentID: envID,
+		Key:           c.Key,
+	})
+	exists := err == nil
+	if err != nil && err != pgx.ErrNoRows {
+		return repo.Variable{}, fmt.Errorf("failed to fetch variable: %w", err)
+	}
+	if c.Base.Valid {
+		var tag string
+		if exists {
+			tag = etag(current.ID, current.Version)
+		}
+		if tag != c.Base.String {
+			return repo.Variable{}, ErrConcurrentChange
+		}
+	}
+
+	var v repo.Variable
+	action := c.Action
+	if c.Delete {
+		if !exists {
+			return repo.Variable{}, ErrVariableNotFound
+		}
+		if err := q.DeleteVariable(ctx, repo.DeleteVariableParams{
+			EnvironmentID: envID,
+			Key:           c.Key,
+		}); err != nil {
+			return repo.Variable{}, fmt.Errorf("failed to delete variable: %w", err)
+		}
+		v = current
+		action = actionDelete
+	} else {
+		if err := validateKey(c.Key); err != nil {
+			return repo.Variable{}, err
+		}
+		sealed, err := sealValue(dataKey, envID, c.Key, c.Value)
+		if err != nil {
+			return repo.Variable{}, fmt.Errorf("failed to encrypt variable: %w", err)
+		}
+
+		if exists {
+			v, err = q.UpdateVariable(ctx, repo.UpdateVariableParams{
+				EnvironmentID: envID,
+				Key:           c.Key,
+				Value:         sealed,
+				IsSecret:      pgtype.Bool{Bool: c.IsSecret, Valid: true},
+				Encrypted:     true,
+			})
+			if action == "" {
+				action = actionUpdate
+			}
+		} else {
+			v, err = q.CreateVariable(ctx, repo.CreateVariableParams{
+				EnvironmentID: envID,
+				Key:           c.Key,
+				Value:         sealed,
+				IsSecret:      pgtype.Bool{Bool: c.IsSecret, Valid: true},
+				Encrypted:     true,
+			})
+			if action == "" {
+				action = actionCreate
+			}
+		}
+		if err != nil {
+			return repo.Variable{}, fmt.Errorf("failed to save variable: %w", err)
+		}
+		if _, err := q.DeleteVariableTombstone(ctx, repo.DeleteVariableTombstoneParams{
+			EnvironmentID: envID,
+			Key:           c.Key,
+		}); err != nil {
+			return repo.Variable{}, fmt.Errorf("failed to remove tombstone: %w", err)
+		}
+		if err := q.MarkVariableRotated(ctx, v.ID); err != nil {
+			return repo.Variable{}, fmt.Errorf("failed to record rotation: %w", err)
+		}
+	}
+
+	version := repo.CreateVariableVersionParams{
+		EnvironmentID: envID,
+		Key:           c.Key,
+		Action:        action,
+		IsSecret:      v.IsSecret.Bool,
+		AuthorID:      c.AuthorID,
+		Message:       pgtype.Text{String: c.Message, Valid: c.Message != ""},
+	}
+	if !c.Delete {
+		version.Value = v.Value
+		version.Encrypted = v.Encrypted
+	}
+	if _, err := q.CreateVariableVersion(ctx, version); err != nil {
+		return repo.Variable{}, fmt.Errorf("failed to record variable version: %w", err)
+	}
+	return v, nil
+}
+
+// SetVariable creates the variable if the key does not exist yet in the
+// environment and overwrites it otherwise. With Generate set the value is
+// generated by the server and the spec is kept so that it can be regenerated.
+func (s *svc) SetVariable(ctx context.Context, params SetVariableParams) (Variable, error) {
+	if err := s.checkUnprotected(ctx, params.EnvironmentID); err != nil {
+		return Variable{}, err
+	}
+
+	c := change{
+		Key:      params.Key,
+		Value:    params.Value,
+		IsSecret: params.IsSecret,
+		AuthorID: params.AuthorID,
+		Message:  params.Message,
+	}
+	var generated secretgen.Result
+	if params.Generate != nil {
+		if params.Value != "" {
+			return Variable{}, fmt.Errorf("%w: value and generate are mutually exclusive", secretgen.ErrInvalidSpec)
+		}
+		var err error
+		if generated, err = generate(&c, params.Generate); err != nil {
+			return Variable{}, err
+		}
+	}
+	if params.Metadata != nil {
+		if err := params.Metadata.normalize(); err != nil {
+			return Variable{}, err
+		}
+	}
+	if err := s.checkChanges(ctx, params.EnvironmentID, []change{c}, nil); err != nil {
+		return Variable{}, err
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, params.EnvironmentID)
+	if err != nil {
+		return Variable{}, err
+	}
+
+	var v repo.Variable
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, params.EnvironmentID); err != nil {
+			return err
+		}
+		if err := s.checkETag(ctx, q, params.EnvironmentID, params.Key, params.IfMatch); err != nil {
+			return err
+		}
+		var err error
+		v, err = s.apply(ctx, q, params.EnvironmentID, dataKey, c)
+		if err != nil {
+			return err
+		}
+		if params.Metadata != nil {
+			if err := saveMetadata(ctx, q, v, *params.Metadata); err != nil {
+				return err
+			}
+		}
+		return saveGenerator(ctx, q, v, params.Generate)
+	})
+	if err != nil {
+		return Variable{}, err
+	}
+
+	out, err := toVariable(dataKey, v, false)
+	if err != nil {
+		return Variable{}, err
+	}
+	out.Generator = params.Generate
+	out.PublicKey = generated.PublicKey
+	out.Metadata = params.Metadata
+	return out, nil
+}
+
+// DeleteVariable removes the key from the effective set of the environment.
+// A key inherited from an ancestor is masked with a tombstone unless Inherit is
+// set, in which case only the local value and tombstone are removed.
+func (s *svc) DeleteVariable(ctx context.Context, params DeleteVariableParams) error {
+	if err := s.checkUnprotected(ctx, params.EnvironmentID); err != nil {
+		return err
+	}
+
+	env, err := s.repo.GetEnvironment(ctx, params.EnvironmentID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	inherited, err := s.inherits(ctx, env, params.Key)
+	if err != nil {
+		return err
+	}
+	var masked []string
+	if inherited && !params.Inherit {
+		masked = []string{params.Key}
+	}
+	if err := s.checkChanges(ctx, params.EnvironmentID, []change{{Key: params.Key, Delete: true}}, masked); err != nil {
+		return err
+	}
+
+	return s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, params.EnvironmentID); err != nil {
+			return err
+		}
+		if err := s.checkETag(ctx, q, params.EnvironmentID, params.Key, params.IfMatch); err != nil {
+			return err
+		}
+		return s.deleteKey(ctx, q, params, inherited)
+	})
+}
+
+// deleteKey is the write of DeleteVariable through q. inherited tells whether
+// an ancestor provides the key.
+func (s *svc) deleteKey(ctx context.Context, q *repo.Queries, params DeleteVariableParams, inherited bool) error {
+	_, err := s.apply(ctx, q, params.EnvironmentID, nil, change{
+		Key:      params.Key,
+		Delete:   true,
+		AuthorID: params.AuthorID,
+		Message:  params.Message,
+	})
+	deleted := err == nil
+	if err != nil && !errors.Is(err, ErrVariableNotFound) {
+		return err
+	}
+
+	if params.Inherit {
+		removed, err := q.DeleteVariableTombstone(ctx, repo.DeleteVariableTombstoneParams{
+			EnvironmentID: params.EnvironmentID,
+			Key:           params.Key,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove tombstone: %w", err)
+		}
+		if !deleted && removed == 0 {
+			return ErrVariableNotFound
+		}
+		return nil
+	}
+
+	if !inherited {
+		if !deleted {
+			return ErrVariableNotFound
+		}
+		return nil
+	}
+	if err := q.CreateVariableTombstone(ctx, repo.CreateVariableTombstoneParams{
+		EnvironmentID: params.EnvironmentID,
+		Key:           params.Key,
+	}); err != nil {
+		return fmt.Errorf("failed to mask inherited variable: %w", err)
+	}
+	return nil
+}
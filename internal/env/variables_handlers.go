@@ -0,0 +1,398 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/envm-org/envm/internal/auth"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/envm-org/envm/pkg/secretgen"
+	"github.com/go-chi/chi/v5"
+)
+
+// variableFilter reads the tag and owner query parameters. tag can be given
+// more than once.
+func variableFilter(r *http.Request) VariableFilter {
+	return VariableFilter{
+		Tags:  r.URL.Query()["tag"],
+		Owner: r.URL.Query().Get("owner"),
+	}
+}
+
+// ListVariables lists the variables of the environment, optionally filtered
+// by metadata. Metadata is not versioned, so the filters cannot be combined
+// with at. Variables the caller may not read are listed as placeholders.
+func (h *handler) ListVariables(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	access, ok := h.keyAccess(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	filter := variableFilter(r)
+	var vars []Variable
+	var err error
+	if atStr := r.URL.Query().Get("at"); atStr != "" {
+		at, parseErr := time.Parse(time.RFC3339, atStr)
+		if parseErr != nil {
+			http.Error(w, "invalid at format: use RFC 3339", http.StatusBadRequest)
+			return
+		}
+		if !filter.empty() {
+			http.Error(w, "tag and owner filters cannot be combined with at", http.StatusBadRequest)
+			return
+		}
+		vars, err = h.service.ListVariablesAt(r.Context(), env.ID, at, false)
+	} else {
+		vars, err = h.service.ListVariables(r.Context(), env.ID, false)
+	}
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	writeTaggedJSON(w, r, filterVariables(access.Redact(vars), filter))
+}
+
+// GetVariable returns a variable, or a placeholder if the caller may not read
+// it. A readable variable carries its entity tag, and a client that already
+// holds that version gets 304 Not Modified.
+func (h *handler) GetVariable(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	access, ok := h.keyAccess(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	v, err := h.service.GetVariable(r.Context(), env.ID, chi.URLParam(r, "key"), false)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	if !access.CanReadVariable(v) {
+		v = redacted(v)
+	} else if notModified(w, r, v.ETag) {
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, v)
+}
+
+func (h *handler) SetVariable(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	var params SetVariableParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.Key = chi.URLParam(r, "key")
+	params.AuthorID = userID
+	params.IfMatch = r.Header.Get("If-Match")
+	if !h.checkKey(w, r, env, userID, params.Key, true) {
+		return
+	}
+
+	// Change requests carry values only, so metadata sent to a protected
+	// environment would be lost when the write is proposed instead.
+	if params.Metadata != nil {
+		protection, err := h.service.GetProtection(r.Context(), env.ID)
+		if err != nil {
+			writeVariableError(w, err)
+			return
+		}
+		if protection != nil {
+			writeVariableError(w, fmt.Errorf("%w: metadata cannot be proposed, set it separately once the change is merged", ErrInvalidChangeRequest))
+			return
+		}
+	}
+
+	if err := h.service.CheckETag(r.Context(), env.ID, params.Key, params.IfMatch); err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	proposed := ProposedChange{
+		Key:      params.Key,
+		Value:    params.Value,
+		IsSecret: params.IsSecret,
+		Generate: params.Generate,
+	}
+	if h.proposeInstead(w, r, env, userID, params.Message, proposed) {
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	v, err := h.service.SetVariable(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	w.Header().Set("ETag", v.ETag)
+	HTTPwriter.JSON(w, http.StatusOK, v)
+}
+
+func (h *handler) DeleteVariable(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	params := DeleteVariableParams{
+		EnvironmentID: env.ID,
+		Key:           chi.URLParam(r, "key"),
+		Inherit:       r.URL.Query().Get("inherit") == "true",
+		AuthorID:      userID,
+		Message:       r.URL.Query().Get("message"),
+		IfMatch:       r.Header.Get("If-Match"),
+	}
+	if !h.checkKey(w, r, env, userID, params.Key, true) {
+		return
+	}
+	if err := h.service.CheckETag(r.Context(), env.ID, params.Key, params.IfMatch); err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	proposed := ProposedChange{Key: params.Key, Delete: true, Inherit: params.Inherit}
+	if h.proposeInstead(w, r, env, userID, params.Message, proposed) {
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	err := h.service.DeleteVariable(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, map[string]string{"message": "variable deleted"})
+}
+
+// ResolveVariables returns the variables of the environment with references
+// expanded, or a single one when the key query parameter is set.
+func (h *handler) ResolveVariables(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	reveal, reason, ok := h.revealRequested(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.ResolveVariables(r.Context(), ResolveParams{
+		EnvironmentID: env.ID,
+		Key:           r.URL.Query().Get("key"),
+		Reveal:        reveal,
+		Access:        h.readAccess(userID),
+		Keys:          h.keysAccess(userID),
+		RevealAccess:  h.revealAccess(userID),
+	})
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	if reveal && !h.auditRevealSources(w, r, userID, reason, res.Sources(res.Revealed)) {
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, res)
+}
+
+// RevealVariable returns the plaintext value of a secret. The caller needs
+// permission to reveal secrets, must be allowed to read the key and must give a
+// reason, which is recorded in the audit log together with the key.
+func (h *handler) RevealVariable(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnvAccess(w, r, auth.AccessRead)
+	if !ok {
+		return
+	}
+	if err := h.authorizer.CanRevealSecrets(r.Context(), userID, env.ProjectID); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var params RevealParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.Key = chi.URLParam(r, "key")
+	params.UserID = userID
+	if !h.checkKey(w, r, env, userID, params.Key, false) {
+		return
+	}
+
+	v, err := h.service.RevealVariable(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	w.Header().Set("ETag", revealedETag(v.ETag))
+	HTTPwriter.JSON(w, http.StatusOK, v)
+}
+
+// RegenerateVariable replaces a generated value with a fresh one from its spec.
+// The new value is masked in the response like any other secret.
+func (h *handler) RegenerateVariable(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	var params RegenerateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil && err != io.EOF {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.Key = chi.URLParam(r, "key")
+	params.AuthorID = userID
+	params.IfMatch = r.Header.Get("If-Match")
+	if !h.checkKey(w, r, env, userID, params.Key, true) {
+		return
+	}
+	if err := h.service.CheckETag(r.Context(), env.ID, params.Key, params.IfMatch); err != nil {
+		writeVariableError(w, err)
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	v, err := h.service.RegenerateVariable(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, v)
+}
+
+// CheckCompliance reports the violations of the project schema in the
+// environment.
+func (h *handler) CheckCompliance(w http.ResponseWriter, r *http.Request) {
+	env, _, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.CheckCompliance(r.Context(), env.ID)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, report)
+}
+
+// SetMetadata replaces the description, tags, owner and links of a variable.
+func (h *handler) SetMetadata(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	var params SetMetadataParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.Key = chi.URLParam(r, "key")
+	params.AuthorID = userID
+	params.IfMatch = r.Header.Get("If-Match")
+	if !h.checkKey(w, r, env, userID, params.Key, true) {
+		return
+	}
+	if err := h.service.CheckETag(r.Context(), env.ID, params.Key, params.IfMatch); err != nil {
+		writeVariableError(w, err)
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	metadata, err := h.service.SetMetadata(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, metadata)
+}
+
+// writeVariableError maps service errors to HTTP status codes.
+func writeVariableError(w http.ResponseWriter, err error) {
+	var schemaErr *SchemaError
+	var lockedErr *LockedError
+	var batchErr *BatchError
+	switch {
+	case errors.As(err, &batchErr):
+		HTTPwriter.JSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"error":   batchErr.Error(),
+			"results": batchErr.Results,
+		})
+	case errors.As(err, &schemaErr):
+		HTTPwriter.JSON(w, http.StatusUnprocessableEntity, map[string]any{
+			"error":      "schema violation",
+			"violations": schemaErr.Violations,
+		})
+	case errors.Is(err, ErrVariableNotFound), errors.Is(err, ErrVersionNotFound),
+		errors.Is(err, ErrSnapshotNotFound), errors.Is(err, ErrChangeRequestNotFound),
+		errors.Is(err, ErrConnectionNotFound), errors.Is(err, ErrLeaseNotFound),
+		errors.Is(err, ErrACLNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrSelfReview), errors.Is(err, ErrRestricted):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrPreconditionFailed):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.As(err, &lockedErr):
+		HTTPwriter.JSON(w, http.StatusLocked, map[string]any{
+			"error": lockedErr.Error(),
+			"lock":  lockedErr.Lock,
+		})
+	case errors.Is(err, ErrSnapshotExists), errors.Is(err, ErrNotLocked),
+		errors.Is(err, ErrReviewRequired), errors.Is(err, ErrNotProtected),
+		errors.Is(err, ErrChangeRequestClosed), errors.Is(err, ErrChangeRequestStale),
+		errors.Is(err, ErrEnvironmentExists), errors.Is(err, ErrConnectionExists),
+		errors.Is(err, ErrLeaseRevoked), errors.Is(err, ErrConcurrentChange):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInvalidKey), errors.Is(err, ErrInvalidImport),
+		errors.Is(err, ErrInvalidParent), errors.Is(err, ErrInheritanceCycle),
+		errors.Is(err, ErrUnsupportedFormat), errors.Is(err, ErrInvalidPromotion),
+		errors.Is(err, ErrReasonRequired), errors.Is(err, secretgen.ErrInvalidSpec),
+		errors.Is(err, ErrNotGenerated), errors.Is(err, ErrInvalidRotation),
+		errors.Is(err, ErrInvalidTag), errors.Is(err, ErrInvalidLock),
+		errors.Is(err, ErrInvalidProtection), errors.Is(err, ErrInvalidChangeRequest),
+		errors.Is(err, ErrInvalidClone), errors.Is(err, ErrInvalidMetadata),
+		errors.Is(err, ErrInvalidConnection), errors.Is(err, ErrInvalidLease),
+		errors.Is(err, ErrInvalidACL), errors.Is(err, ErrInvalidRoleAccess),
+		errors.Is(err, ErrInvalidBatch), errors.Is(err, ErrInheritedHistory):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrTargetDatabase):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
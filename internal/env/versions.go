@@ -0,0 +1,324 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+)
+
+var (
+	ErrVersionNotFound  = errors.New("version not found")
+	ErrInheritedHistory = errors.New("point-in-time reads are not available for an environment that inherits: only its own history is recorded")
+)
+
+// VariableVersion is an immutable entry in the history of a variable.
+type VariableVersion struct {
+	Key       string             `json:"key"`
+	Version   int32              `json:"version"`
+	Action    string             `json:"action"`
+	Value     string             `json:"value"`
+	IsSecret  bool               `json:"is_secret"`
+	Masked    bool               `json:"masked,omitempty"`
+	AuthorID  pgtype.UUID        `json:"author_id"`
+	Message   string             `json:"message,omitempty"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type RollbackVariableParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	Key           string      `json:"-"`
+	Version       int32       `json:"version"`
+	AuthorID      pgtype.UUID `json:"-"`
+	Message       string      `json:"message"`
+}
+
+type RollbackEnvironmentParams struct {
+	EnvironmentID pgtype.UUID `json:"-"`
+	At            time.Time   `json:"at"`
+	AuthorID      pgtype.UUID `json:"-"`
+	Message       string      `json:"message"`
+	// Keys is what the author may do with the variables of the environment.
+	// The rollback fails with ErrRestricted if it changes a key the author may
+	// not change.
+	Keys KeyAccess `json:"-"`
+}
+
+// RollbackResult lists the keys that a rollback restored or deleted.
+type RollbackResult struct {
+	Restored []string `json:"restored"`
+	Deleted  []string `json:"deleted"`
+}
+
+func (s *svc) ListVariableVersions(ctx context.Context, envID pgtype.UUID, key string, reveal bool) ([]VariableVersion, error) {
+	versions, err := s.repo.ListVariableVersions(ctx, repo.ListVariableVersionsParams{
+		EnvironmentID: envID,
+		Key:           key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return nil, ErrVariableNotFound
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, envID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]VariableVersion, 0, len(versions))
+	for _, v := range versions {
+		out := VariableVersion{
+			Key:       v.Key,
+			Version:   v.Version,
+			Action:    v.Action,
+			IsSecret:  v.IsSecret,
+			AuthorID:  v.AuthorID,
+			Message:   v.Message.String,
+			CreatedAt: v.CreatedAt,
+		}
+		if v.IsSecret && !reveal {
+			out.Masked = true
+		} else if out.Value, err = openValue(dataKey, v.EnvironmentID, v.Key, v.Value, v.Encrypted); err != nil {
+			return nil, fmt.Errorf("failed to decrypt version %d of %s: %w", v.Version, v.Key, err)
+		}
+		result = append(result, out)
+	}
+	return result, nil
+}
+
+// stateAt returns the latest version of every key that existed in the
+// environment at the given time.
+func (s *svc) stateAt(ctx context.Context, envID pgtype.UUID, at time.Time) ([]repo.VariableVersion, error) {
+	versions, err := s.repo.ListVariableVersionsAt(ctx, repo.ListVariableVersionsAtParams{
+		EnvironmentID: envID,
+		CreatedAt:     pgtype.Timestamptz{Time: at, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	state := make([]repo.VariableVersion, 0, len(versions))
+	for _, v := range versions {
+		if v.Action != actionDelete {
+			state = append(state, v)
+		}
+	}
+	return state, nil
+}
+
+// ListVariablesAt returns the variables of the environment at the given time.
+// Only the history of the environment's own variables is recorded, not that of
+// its parent or tombstones, so an environment with a parent fails with
+// ErrInheritedHistory rather than returning a state it never had.
+func (s *svc) ListVariablesAt(ctx context.Context, envID pgtype.UUID, at time.Time, reveal bool) ([]Variable, error) {
+	env, err := s.repo.GetEnvironment(ctx, envID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch environment: %w", err)
+	}
+	if env.ParentID.Valid {
+		return nil, ErrInheritedHistory
+	}
+
+	state, err := s.stateAt(ctx, envID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Variable, 0, len(state))
+	if len(state) == 0 {
+		return result, nil
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, envID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range state {
+		out := Variable{
+			Key:       v.Key,
+			IsSecret:  v.IsSecret,
+			UpdatedAt: v.CreatedAt,
+		}
+		if v.IsSecret && !reveal {
+			out.Masked = true
+		} else if out.Value, err = openValue(dataKey, v.EnvironmentID, v.Key, v.Value, v.Encrypted); err != nil {
+			return nil, fmt.Errorf("failed to decrypt variable %s: %w", v.Key, err)
+		}
+		result = append(result, out)
+	}
+	return result, nil
+}
+
+func (s *svc) RollbackVariable(ctx context.Context, params RollbackVariableParams) (RollbackResult, error) {
+	if err := s.checkUnprotected(ctx, params.EnvironmentID); err != nil {
+		return RollbackResult{}, err
+	}
+
+	target, err := s.repo.GetVariableVersion(ctx, repo.GetVariableVersionParams{
+		EnvironmentID: params.EnvironmentID,
+		Key:           params.Key,
+		Version:       params.Version,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return RollbackResult{}, ErrVersionNotFound
+		}
+		return RollbackResult{}, fmt.Errorf("failed to fetch version: %w", err)
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, params.EnvironmentID)
+	if err != nil {
+		return RollbackResult{}, err
+	}
+
+	message := params.Message
+	if message == "" {
+		message = fmt.Sprintf("rollback to version %d", target.Version)
+	}
+	c := change{
+		Key:      target.Key,
+		IsSecret: target.IsSecret,
+		Delete:   target.Action == actionDelete,
+		Action:   actionRollback,
+		AuthorID: params.AuthorID,
+		Message:  message,
+	}
+	if !c.Delete {
+		if c.Value, err = openValue(dataKey, target.EnvironmentID, target.Key, target.Value, target.Encrypted); err != nil {
+			return RollbackResult{}, fmt.Errorf("failed to decrypt version: %w", err)
+		}
+	}
+
+	if err := s.checkChanges(ctx, params.EnvironmentID, []change{c}, nil); err != nil {
+		return RollbackResult{}, err
+	}
+
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, params.EnvironmentID); err != nil {
+			return err
+		}
+		_, err := s.apply(ctx, q, params.EnvironmentID, dataKey, c)
+		return err
+	})
+	if err != nil {
+		return RollbackResult{}, err
+	}
+
+	result := RollbackResult{Restored: []string{}, Deleted: []string{}}
+	if c.Delete {
+		result.Deleted = append(result.Deleted, c.Key)
+	} else {
+		result.Restored = append(result.Restored, c.Key)
+	}
+	return result, nil
+}
+
+// RollbackEnvironment restores every variable of the environment to the value
+// it had at params.At. Keys created afterwards are deleted. Keys whose value
+// did not change are left untouched so that they do not get a new version.
+func (s *svc) RollbackEnvironment(ctx context.Context, params RollbackEnvironmentParams) (RollbackResult, error) {
+	if err := s.checkUnprotected(ctx, params.EnvironmentID); err != nil {
+		return RollbackResult{}, err
+	}
+
+	state, err := s.stateAt(ctx, params.EnvironmentID, params.At)
+	if err != nil {
+		return RollbackResult{}, err
+	}
+	current, err := s.repo.ListVariables(ctx, params.EnvironmentID)
+	if err != nil {
+		return RollbackResult{}, fmt.Errorf("failed to list variables: %w", err)
+	}
+
+	dataKey, err := s.keys.dataKey(ctx, s.repo, params.EnvironmentID)
+	if err != nil {
+		return RollbackResult{}, err
+	}
+
+	message := params.Message
+	if message == "" {
+		message = "rollback to " + params.At.UTC().Format(time.RFC3339)
+	}
+
+	existing := make(map[string]repo.Variable, len(current))
+	for _, v := range current {
+		existing[v.Key] = v
+	}
+	wanted := make(map[string]bool, len(state))
+
+	var changes []change
+	for _, target := range state {
+		wanted[target.Key] = true
+		value, err := openValue(dataKey, target.EnvironmentID, target.Key, target.Value, target.Encrypted)
+		if err != nil {
+			return RollbackResult{}, fmt.Errorf("failed to decrypt version of %s: %w", target.Key, err)
+		}
+
+		if v, ok := existing[target.Key]; ok && v.IsSecret.Bool == target.IsSecret {
+			currentValue, err := openValue(dataKey, v.EnvironmentID, v.Key, v.Value, v.Encrypted)
+			if err != nil {
+				return RollbackResult{}, fmt.Errorf("failed to decrypt variable %s: %w", v.Key, err)
+			}
+			if currentValue == value {
+				continue
+			}
+		}
+		changes = append(changes, change{
+			Key:      target.Key,
+			Value:    value,
+			IsSecret: target.IsSecret,
+			Action:   actionRollback,
+			AuthorID: params.AuthorID,
+			Message:  message,
+		})
+	}
+	for _, v := range current {
+		if !wanted[v.Key] {
+			changes = append(changes, change{
+				Key:      v.Key,
+				Delete:   true,
+				AuthorID: params.AuthorID,
+				Message:  message,
+			})
+		}
+	}
+
+	if err := checkKeys(changedKeys(changes), params.Keys.CanWrite); err != nil {
+		return RollbackResult{}, err
+	}
+	if err := s.checkChanges(ctx, params.EnvironmentID, changes, nil); err != nil {
+		return RollbackResult{}, err
+	}
+
+	err = s.withTx(ctx, func(q *repo.Queries) error {
+		if err := s.checkUnlocked(ctx, q, params.EnvironmentID); err != nil {
+			return err
+		}
+		for _, c := range changes {
+			if _, err := s.apply(ctx, q, params.EnvironmentID, dataKey, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return RollbackResult{}, err
+	}
+
+	result := RollbackResult{Restored: []string{}, Deleted: []string{}}
+	for _, c := range changes {
+		if c.Delete {
+			result.Deleted = append(result.Deleted, c.Key)
+		} else {
+			result.Restored = append(result.Restored, c.Key)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package env
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *handler) ListVariableVersions(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+	reveal, reason, ok := h.revealRequested(w, r, env, userID)
+	if !ok {
+		return
+	}
+
+	key := chi.URLParam(r, "key")
+	if !h.checkKey(w, r, env, userID, key, false) {
+		return
+	}
+	versions, err := h.service.ListVariableVersions(r.Context(), env.ID, key, reveal)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	if reveal && slices.ContainsFunc(versions, func(v VariableVersion) bool { return v.IsSecret }) {
+		if !h.auditReveal(w, r, env, userID, reason, []string{key}) {
+			return
+		}
+	}
+	HTTPwriter.JSON(w, http.StatusOK, versions)
+}
+
+func (h *handler) RollbackVariable(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	var params RollbackVariableParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if params.Version < 1 {
+		http.Error(w, "version must be a positive number", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.Key = chi.URLParam(r, "key")
+	params.AuthorID = userID
+	if !h.checkKey(w, r, env, userID, params.Key, true) {
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.RollbackVariable(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, result)
+}
+
+func (h *handler) RollbackEnvironment(w http.ResponseWriter, r *http.Request) {
+	env, userID, ok := h.authorizeEnv(w, r)
+	if !ok {
+		return
+	}
+
+	var params RollbackEnvironmentParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if params.At.IsZero() {
+		http.Error(w, "at is required", http.StatusBadRequest)
+		return
+	}
+	params.EnvironmentID = env.ID
+	params.AuthorID = userID
+	if params.Keys, ok = h.keyAccess(w, r, env, userID); !ok {
+		return
+	}
+
+	r, ok = h.lockOverride(w, r, env.ID)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.RollbackEnvironment(r.Context(), params)
+	if err != nil {
+		writeVariableError(w, err)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, result)
+}
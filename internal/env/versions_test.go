@@ -0,0 +1,100 @@
+package env
+
+import (
+	"context"
+	"maps"
+	"testing"
+)
+
+func TestRollbackAcrossRollbackDelete(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	f.set(t, f.env.ID, "PORT", "8080")
+	before := f.now(t)
+	f.set(t, f.env.ID, "DEBUG", "true")
+
+	// Rolling back to before DEBUG existed deletes it.
+	result, err := f.s.RollbackEnvironment(ctx, RollbackEnvironmentParams{
+		EnvironmentID: f.env.ID,
+		At:            before,
+		AuthorID:      f.user,
+	})
+	if err != nil {
+		t.Fatalf("RollbackEnvironment() error = %v", err)
+	}
+	if len(result.Deleted) != 1 || result.Deleted[0] != "DEBUG" {
+		t.Fatalf("RollbackEnvironment() = %+v, want DEBUG deleted", result)
+	}
+	deleted := f.now(t)
+
+	versions, err := f.s.ListVariableVersions(ctx, f.env.ID, "DEBUG", true)
+	if err != nil {
+		t.Fatalf("ListVariableVersions() error = %v", err)
+	}
+	if versions[0].Action != actionDelete {
+		t.Errorf("rollback recorded the delete as %q, want %q", versions[0].Action, actionDelete)
+	}
+
+	at, err := f.s.ListVariablesAt(ctx, f.env.ID, deleted, true)
+	if err != nil {
+		t.Fatalf("ListVariablesAt() error = %v", err)
+	}
+	for _, v := range at {
+		if v.Key == "DEBUG" {
+			t.Errorf("ListVariablesAt() returned deleted key DEBUG = %q", v.Value)
+		}
+	}
+
+	// Rolling back to the rollback brings back its state, without DEBUG.
+	f.set(t, f.env.ID, "DEBUG", "false")
+	if _, err := f.s.RollbackEnvironment(ctx, RollbackEnvironmentParams{
+		EnvironmentID: f.env.ID,
+		At:            deleted,
+		AuthorID:      f.user,
+	}); err != nil {
+		t.Fatalf("RollbackEnvironment() error = %v", err)
+	}
+	if got, want := f.values(t, f.env.ID), map[string]string{"PORT": "8080"}; !maps.Equal(got, want) {
+		t.Errorf("variables after rollback = %v, want %v", got, want)
+	}
+
+	// Rolling the key back to the version deleted by the rollback deletes it.
+	f.set(t, f.env.ID, "DEBUG", "false")
+	if _, err := f.s.RollbackVariable(ctx, RollbackVariableParams{
+		EnvironmentID: f.env.ID,
+		Key:           "DEBUG",
+		Version:       versions[0].Version,
+		AuthorID:      f.user,
+	}); err != nil {
+		t.Fatalf("RollbackVariable() error = %v", err)
+	}
+	if _, err := f.s.GetVariable(ctx, f.env.ID, "DEBUG", true); err != ErrVariableNotFound {
+		t.Errorf("GetVariable() after rolling back to a delete error = %v, want %v", err, ErrVariableNotFound)
+	}
+}
+
+func TestListVariablesAtInherited(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	base := f.newEnv(t, "base")
+	f.set(t, base.ID, "PORT", "8080")
+	if _, err := f.s.SetParent(ctx, f.env.ID, base.ID); err != nil {
+		t.Fatalf("SetParent() error = %v", err)
+	}
+	at := f.now(t)
+
+	if _, err := f.s.ListVariablesAt(ctx, f.env.ID, at, true); err != ErrInheritedHistory {
+		t.Errorf("ListVariablesAt() of an inheriting environment error = %v, want %v", err, ErrInheritedHistory)
+	}
+	_, err := f.s.DiffEnvironments(ctx, DiffParams{
+		Left:  DiffSide{EnvironmentID: base.ID, At: &at},
+		Right: DiffSide{EnvironmentID: f.env.ID, At: &at},
+	})
+	if err != ErrInheritedHistory {
+		t.Errorf("DiffEnvironments() at a time error = %v, want %v", err, ErrInheritedHistory)
+	}
+	if _, err := f.s.ListVariablesAt(ctx, base.ID, at, true); err != nil {
+		t.Errorf("ListVariablesAt() of the parent error = %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/envm-org/envm/internal/env"
+)
+
+// HygieneReporter builds secret hygiene reports. It is implemented by the env
+// service, which holds the keys needed to compare values.
+type HygieneReporter interface {
+	HygieneReport(ctx context.Context, orgID pgtype.UUID, policy env.HygienePolicy) (env.HygieneReport, error)
+}
+
+func (s *svc) HygieneReport(ctx context.Context, orgID pgtype.UUID, policy env.HygienePolicy) (env.HygieneReport, error) {
+	return s.hygiene.HygieneReport(ctx, orgID, policy)
+}
+
+// SendHygieneDigests emails the owners and admins of every organization with
+// findings a summary of its hygiene report and returns the number of
+// organizations notified. Like the report, the digest never contains values.
+// An organization whose digest cannot be built or sent does not stop the
+// others; the errors are joined.
+func (s *svc) SendHygieneDigests(ctx context.Context, policy env.HygienePolicy) (int, error) {
+	orgs, err := s.repo.ListOrganizations(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list organizations: %w", err)
+	}
+
+	notified := 0
+	var errs []error
+	for _, org := range orgs {
+		report, err := s.hygiene.HygieneReport(ctx, org.ID, policy)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("organization %s: %w", org.Slug, err))
+			continue
+		}
+		if report.Findings() == 0 {
+			continue
+		}
+		recipients, err := s.repo.ListOrganizationAdminEmails(ctx, org.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("organization %s: failed to list organization admins: %w", org.Slug, err))
+			continue
+		}
+
+		subject := fmt.Sprintf("Secret hygiene report for %s: %d findings", org.Name, report.Findings())
+		body := hygieneDigest(org.Name, report)
+		sent := false
+		for _, to := range recipients {
+			if err := s.mailer.SendEmail(to, subject, body); err != nil {
+				errs = append(errs, fmt.Errorf("organization %s: failed to notify %s: %w", org.Slug, to, err))
+				continue
+			}
+			sent = true
+		}
+		if sent {
+			notified++
+		}
+	}
+	return notified, errors.Join(errs...)
+}
+
+func hygieneDigest(orgName string, report env.HygieneReport) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Secret hygiene report for %s, generated %s.\n", orgName, report.GeneratedAt.UTC().Format("2006-01-02 15:04 MST"))
+
+	if len(report.Reused) > 0 {
+		fmt.Fprintf(&body, "\nSecret values reused across environments:\n")
+		for _, group := range report.Reused {
+			locations := make([]string, 0, len(group.Locations))
+			for _, l := range group.Locations {
+				locations = append(locations, fmt.Sprintf("%s/%s/%s", l.Project, l.Environment, l.Key))
+			}
+			fmt.Fprintf(&body, "  %s\n", strings.Join(locations, ", "))
+		}
+	}
+	if len(report.Weak) > 0 {
+		fmt.Fprintf(&body, "\nWeak secrets:\n")
+		for _, w := range report.Weak {
+			fmt.Fprintf(&body, "  %s/%s/%s  %s\n", w.Project, w.Environment, w.Key, strings.ReplaceAll(w.Reason, "_", " "))
+		}
+	}
+	if len(report.Stale) > 0 {
+		fmt.Fprintf(&body, "\nSecrets not rotated in time (maximum age %s):\n", report.MaxAge)
+		for _, a := range report.Stale {
+			fmt.Fprintf(&body, "  %s/%s/%s  last changed %s\n", a.Project, a.Environment, a.Key, a.UpdatedAt.UTC().Format("2006-01-02"))
+		}
+	}
+	if len(report.ProductionDev) > 0 {
+		fmt.Fprintf(&body, "\nProduction secrets identical to development:\n")
+		for _, m := range report.ProductionDev {
+			fmt.Fprintf(&body, "  %s/%s  %s = %s\n", m.Project, m.Key, m.Production, m.Development)
+		}
+	}
+	return body.String()
+}
@@ -0,0 +1,58 @@
+package org
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/envm-org/envm/internal/auth"
+	"github.com/envm-org/envm/internal/env"
+	"github.com/envm-org/envm/internal/middleware"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	authPkg "github.com/envm-org/envm/pkg/auth"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// HygieneReport returns the secret hygiene report of the organization given by
+// id. The optional max_age, a duration such as 2160h, overrides the default
+// maximum age of secrets without a rotation policy. The report covers every
+// project, so only owners and admins can request it.
+func (h *handler) HygieneReport(w http.ResponseWriter, r *http.Request) {
+	var orgID pgtype.UUID
+	if err := orgID.Scan(r.URL.Query().Get("id")); err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(middleware.UserKey).(*authPkg.Claims)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var userID pgtype.UUID
+	if err := userID.Scan(claims.UserID); err != nil {
+		http.Error(w, "invalid user id in token", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.authorizer.HasRole(r.Context(), userID, orgID, auth.RoleOwner, auth.RoleAdmin); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	var policy env.HygienePolicy
+	if raw := r.URL.Query().Get("max_age"); raw != "" {
+		maxAge, err := time.ParseDuration(raw)
+		if err != nil || maxAge <= 0 {
+			http.Error(w, "max_age must be a positive duration such as 2160h", http.StatusBadRequest)
+			return
+		}
+		policy.MaxAge = maxAge
+	}
+
+	report, err := h.service.HygieneReport(r.Context(), orgID, policy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, report)
+}
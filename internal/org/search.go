@@ -0,0 +1,197 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/internal/env"
+)
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+// Search modes. A glob matches * against any run of characters and ? against
+// a single character.
+const (
+	SearchExact  = "exact"
+	SearchPrefix = "prefix"
+	SearchGlob   = "glob"
+)
+
+// Bounds of a search.
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+	maxQueryLength     = 255
+)
+
+// SearchParams searches the variables of the given projects, which the caller
+// is expected to be allowed to see. Query is matched case-insensitively
+// against keys and against the description, owner and tags of variables.
+type SearchParams struct {
+	Projects []repo.Project
+	// Access, if set, limits the search to the environments of Projects it
+	// allows.
+	Access env.AccessFunc
+	Query  string
+	Mode   string
+	Limit  int
+}
+
+// SearchResult is a variable that matched a search. Values are never part of
+// a result. Matched lists the fields that matched: key, description, owner or
+// tags.
+type SearchResult struct {
+	ProjectID       pgtype.UUID `json:"project_id"`
+	ProjectSlug     string      `json:"project_slug"`
+	EnvironmentID   pgtype.UUID `json:"environment_id"`
+	EnvironmentSlug string      `json:"environment_slug"`
+	Key             string      `json:"key"`
+	IsSecret        bool        `json:"is_secret"`
+	Description     string      `json:"description,omitempty"`
+	Owner           string      `json:"owner,omitempty"`
+	Tags            []string    `json:"tags,omitempty"`
+	Matched         []string    `json:"matched"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// searchPattern is a query compiled for the database, as a LIKE pattern, and
+// for reporting which fields matched, as a regular expression.
+type searchPattern struct {
+	like string
+	re   *regexp.Regexp
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func compileSearch(query, mode string) (searchPattern, error) {
+	if query == "" {
+		return searchPattern{}, fmt.Errorf("%w: query is required", ErrInvalidSearch)
+	}
+	if len(query) > maxQueryLength {
+		return searchPattern{}, fmt.Errorf("%w: query is longer than %d characters", ErrInvalidSearch, maxQueryLength)
+	}
+
+	switch mode {
+	case "", SearchExact:
+		return searchPattern{
+			like: likeEscaper.Replace(query),
+			re:   regexp.MustCompile(`(?is)^` + regexp.QuoteMeta(query) + `$`),
+		}, nil
+	case SearchPrefix:
+		return searchPattern{
+			like: likeEscaper.Replace(query) + "%",
+			re:   regexp.MustCompile(`(?is)^` + regexp.QuoteMeta(query)),
+		}, nil
+	case SearchGlob:
+		var like, re strings.Builder
+		for _, r := range query {
+			switch r {
+			case '*':
+				like.WriteByte('%')
+				re.WriteString(".*")
+			case '?':
+				like.WriteByte('_')
+				re.WriteString(".")
+			default:
+				like.WriteString(likeEscaper.Replace(string(r)))
+				re.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		return searchPattern{
+			like: like.String(),
+			re:   regexp.MustCompile(`(?is)^` + re.String() + `$`),
+		}, nil
+	}
+	return searchPattern{}, fmt.Errorf("%w: mode must be %s, %s or %s", ErrInvalidSearch, SearchExact, SearchPrefix, SearchGlob)
+}
+
+func (p searchPattern) matched(row repo.SearchVariablesRow) []string {
+	var fields []string
+	if p.re.MatchString(row.Key) {
+		fields = append(fields, "key")
+	}
+	if row.Description.Valid && p.re.MatchString(row.Description.String) {
+		fields = append(fields, "description")
+	}
+	if row.Owner.Valid && p.re.MatchString(row.Owner.String) {
+		fields = append(fields, "owner")
+	}
+	for _, tag := range row.Tags {
+		if p.re.MatchString(tag) {
+			fields = append(fields, "tags")
+			break
+		}
+	}
+	return fields
+}
+
+// SearchVariables finds the variables defined in the given projects whose key
+// or metadata matches the query. Inherited variables are reported once, in the
+// environment that defines them.
+func (s *svc) SearchVariables(ctx context.Context, params SearchParams) ([]SearchResult, error) {
+	pattern, err := compileSearch(strings.TrimSpace(params.Query), params.Mode)
+	if err != nil {
+		return nil, err
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit < 0 || limit > maxSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSearch, maxSearchLimit)
+	}
+
+	results := []SearchResult{}
+	if len(params.Projects) == 0 {
+		return results, nil
+	}
+	slugs := make(map[pgtype.UUID]string, len(params.Projects))
+	var ids []pgtype.UUID
+	for _, p := range params.Projects {
+		slugs[p.ID] = p.Slug
+		envs, err := s.repo.ListEnvironments(ctx, p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list environments: %w", err)
+		}
+		for _, e := range envs {
+			if params.Access == nil || params.Access(ctx, e) == nil {
+				ids = append(ids, e.ID)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	rows, err := s.repo.SearchVariables(ctx, repo.SearchVariablesParams{
+		EnvironmentIds: ids,
+		Pattern:        pattern.like,
+		MaxResults:     int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search variables: %w", err)
+	}
+	for _, row := range rows {
+		results = append(results, SearchResult{
+			ProjectID:       row.ProjectID,
+			ProjectSlug:     slugs[row.ProjectID],
+			EnvironmentID:   row.EnvironmentID,
+			EnvironmentSlug: row.EnvironmentSlug,
+			Key:             row.Key,
+			IsSecret:        row.IsSecret.Bool,
+			Description:     row.Description.String,
+			Owner:           row.Owner.String,
+			Tags:            row.Tags,
+			Matched:         pattern.matched(row),
+			UpdatedAt:       row.UpdatedAt.Time,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,95 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/internal/auth"
+	"github.com/envm-org/envm/internal/middleware"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	authPkg "github.com/envm-org/envm/pkg/auth"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// SearchVariables searches the variable keys and metadata of the organization
+// given by id. q is the query and mode one of exact (the default), prefix or
+// glob. Only the environments the caller may read, in projects they have
+// access to, are searched.
+func (h *handler) SearchVariables(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var orgID pgtype.UUID
+	if err := orgID.Scan(query.Get("id")); err != nil {
+		http.Error(w, "invalid id format", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(middleware.UserKey).(*authPkg.Claims)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var userID pgtype.UUID
+	if err := userID.Scan(claims.UserID); err != nil {
+		http.Error(w, "invalid user id in token", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.authorizer.HasRole(r.Context(), userID, orgID, auth.RoleOwner, auth.RoleAdmin, auth.RoleMember); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	params := SearchParams{Query: query.Get("q"), Mode: query.Get("mode")}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		params.Limit = limit
+	}
+
+	projects, err := h.service.ListProjects(r.Context(), orgID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Owners and admins see every project, other members only those they
+	// were added to.
+	if err := h.authorizer.HasRole(r.Context(), userID, orgID, auth.RoleOwner, auth.RoleAdmin); err == nil {
+		params.Projects = projects
+	} else {
+		params.Projects = make([]repo.Project, 0, len(projects))
+		for _, p := range projects {
+			if err := h.authorizer.HasProjectAccess(r.Context(), userID, p.ID); err == nil {
+				params.Projects = append(params.Projects, p)
+			}
+		}
+	}
+
+	params.Access = func(ctx context.Context, e repo.Environment) error {
+		access, err := h.authorizer.EnvironmentAccess(ctx, userID, e)
+		if err != nil {
+			return err
+		}
+		if !access.Allows(auth.AccessRead) {
+			return fmt.Errorf("insufficient permissions: %s access to environment %s required", auth.AccessRead, e.Slug)
+		}
+		return nil
+	}
+
+	results, err := h.service.SearchVariables(r.Context(), params)
+	if errors.Is(err, ErrInvalidSearch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, results)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 
 	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/internal/env"
 	"github.com/envm-org/envm/pkg/email"
 )
 
@@ -23,17 +24,24 @@ type Service interface {
 	
 	InviteMember(ctx context.Context, orgID pgtype.UUID, email, role string, invitedBy pgtype.UUID) error
 	AcceptInvitation(ctx context.Context, token string, userID pgtype.UUID) error
+
+	ListProjects(ctx context.Context, orgID pgtype.UUID) ([]repo.Project, error)
+	SearchVariables(ctx context.Context, params SearchParams) ([]SearchResult, error)
+	HygieneReport(ctx context.Context, orgID pgtype.UUID, policy env.HygienePolicy) (env.HygieneReport, error)
+	SendHygieneDigests(ctx context.Context, policy env.HygienePolicy) (int, error)
 }
 
 type svc struct {
-	repo   *repo.Queries
-	mailer email.Sender
+	repo    *repo.Queries
+	mailer  email.Sender
+	hygiene HygieneReporter
 }
 
-func NewService(repo *repo.Queries, mailer email.Sender) Service {
+func NewService(repo *repo.Queries, mailer email.Sender, hygiene HygieneReporter) Service {
 	return &svc{
-		repo:   repo,
-		mailer: mailer,
+		repo:    repo,
+		mailer:  mailer,
+		hygiene: hygiene,
 	}
 }
 
@@ -57,6 +65,10 @@ func (s *svc) DeleteOrg(ctx context.Context, id pgtype.UUID) error {
 	return s.repo.DeleteOrganization(ctx, id)
 }
 
+func (s *svc) ListProjects(ctx context.Context, orgID pgtype.UUID) ([]repo.Project, error) {
+	return s.repo.ListProjects(ctx, orgID)
+}
+
 func (s *svc) InviteMember(ctx context.Context, orgID pgtype.UUID, emailAddr, role string, invitedBy pgtype.UUID) error {
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -2,7 +2,9 @@ package project
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
 
 	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
 	"github.com/envm-org/envm/internal/auth"
@@ -75,6 +77,13 @@ func (h *handler) AddMember(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
+	if req.Role == "" {
+		req.Role = string(auth.RoleDeveloper)
+	}
+	if !slices.Contains(auth.ProjectRoles, auth.Role(req.Role)) {
+		http.Error(w, fmt.Sprintf("role must be one of %v", auth.ProjectRoles), http.StatusBadRequest)
+		return
+	}
 
 	project, err := h.service.GetProject(r.Context(), req.ProjectID)
 	if err != nil {
@@ -0,0 +1,48 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/pkg/varschema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrSchemaNotFound = errors.New("project has no schema")
+	ErrInvalidSchema  = errors.New("invalid schema")
+)
+
+func (s *svc) GetSchema(ctx context.Context, projectID pgtype.UUID) (*varschema.Schema, error) {
+	stored, err := s.repo.GetProjectSchema(ctx, projectID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrSchemaNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch schema: %w", err)
+	}
+	return varschema.Parse(stored.Definition)
+}
+
+// SetSchema replaces the variable schema of the project. The definition is
+// checked before it is stored.
+func (s *svc) SetSchema(ctx context.Context, projectID pgtype.UUID, definition []byte) (*varschema.Schema, error) {
+	schema, err := varschema.Parse(definition)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if _, err := s.repo.UpsertProjectSchema(ctx, repo.UpsertProjectSchemaParams{
+		ProjectID:  projectID,
+		Definition: definition,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to save schema: %w", err)
+	}
+	return schema, nil
+}
+
+func (s *svc) DeleteSchema(ctx context.Context, projectID pgtype.UUID) error {
+	return s.repo.DeleteProjectSchema(ctx, projectID)
+}
@@ -0,0 +1,100 @@
+package project
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/envm-org/envm/internal/auth"
+	"github.com/envm-org/envm/internal/middleware"
+	HTTPwriter "github.com/envm-org/envm/pkg/HTTPwriter"
+	authPkg "github.com/envm-org/envm/pkg/auth"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const maxSchemaSize = 1 << 20
+
+// schemaProject resolves the project_id query parameter and checks that the
+// caller has one of the given organization roles. On failure the error
+// response has already been written and ok is false.
+func (h *handler) schemaProject(w http.ResponseWriter, r *http.Request, roles ...auth.Role) (projectID pgtype.UUID, ok bool) {
+	if err := projectID.Scan(r.URL.Query().Get("project_id")); err != nil {
+		http.Error(w, "invalid project_id", http.StatusBadRequest)
+		return projectID, false
+	}
+
+	project, err := h.service.GetProject(r.Context(), projectID)
+	if err != nil {
+		http.Error(w, "project not found", http.StatusNotFound)
+		return projectID, false
+	}
+
+	claims, isClaims := r.Context().Value(middleware.UserKey).(*authPkg.Claims)
+	if !isClaims {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return projectID, false
+	}
+	var userID pgtype.UUID
+	userID.Scan(claims.UserID)
+
+	if err := h.authorizer.HasRole(r.Context(), userID, project.OrganizationID, roles...); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return projectID, false
+	}
+	return projectID, true
+}
+
+func (h *handler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.schemaProject(w, r, auth.RoleOwner, auth.RoleAdmin, auth.RoleMember)
+	if !ok {
+		return
+	}
+
+	schema, err := h.service.GetSchema(r.Context(), projectID)
+	if err != nil {
+		if errors.Is(err, ErrSchemaNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, schema)
+}
+
+func (h *handler) SetSchema(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.schemaProject(w, r, auth.RoleOwner, auth.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	definition, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSchemaSize))
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	schema, err := h.service.SetSchema(r.Context(), projectID, definition)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSchema) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, schema)
+}
+
+func (h *handler) DeleteSchema(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.schemaProject(w, r, auth.RoleOwner, auth.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSchema(r.Context(), projectID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	HTTPwriter.JSON(w, http.StatusOK, map[string]string{"message": "schema deleted"})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	repo "github.com/envm-org/envm/internal/adapters/postgresql/sqlc"
+	"github.com/envm-org/envm/pkg/varschema"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -19,6 +20,10 @@ type Service interface {
 	RemoveMember(ctx context.Context, projectID, userID pgtype.UUID) error
 	GetMember(ctx context.Context, projectID, userID pgtype.UUID) (repo.ProjectMember, error)
 	ListMembers(ctx context.Context, projectID pgtype.UUID) ([]repo.ListProjectMembersRow, error)
+
+	GetSchema(ctx context.Context, projectID pgtype.UUID) (*varschema.Schema, error)
+	SetSchema(ctx context.Context, projectID pgtype.UUID, definition []byte) (*varschema.Schema, error)
+	DeleteSchema(ctx context.Context, projectID pgtype.UUID) error
 }
 
 type svc struct {
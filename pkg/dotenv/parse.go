@@ -0,0 +1,219 @@
+package dotenv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Entry is a single KEY=VALUE assignment.
+type Entry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// ParseError reports the line on which the content could not be parsed.
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Parse reads dotenv content. It supports comments, an optional "export"
+// prefix, single quoted literals, double quoted values with escape sequences,
+// and quoted values spanning several lines. When a key is assigned more than
+// once the last value wins and the entry keeps the position of the first one.
+func Parse(content string) ([]Entry, error) {
+	p := parser{src: strings.ReplaceAll(content, "\r\n", "\n"), line: 1}
+
+	var entries []Entry
+	index := make(map[string]int)
+	for {
+		entry, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return entries, nil
+		}
+		if i, seen := index[entry.Key]; seen {
+			entries[i].Value = entry.Value
+			continue
+		}
+		index[entry.Key] = len(entries)
+		entries = append(entries, entry)
+	}
+}
+
+type parser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *parser) advance() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *parser) skipBlank() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.advance()
+	}
+}
+
+func (p *parser) skipLine() {
+	for !p.eof() && p.advance() != '\n' {
+	}
+}
+
+// next returns the next assignment, skipping blank lines and comments.
+func (p *parser) next() (Entry, bool, error) {
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return Entry{}, false, nil
+		}
+		switch p.peek() {
+		case '\n':
+			p.advance()
+			continue
+		case '#':
+			p.skipLine()
+			continue
+		}
+		break
+	}
+
+	line := p.line
+	key := p.readKey()
+	if key == "export" {
+		p.skipBlank()
+		if !p.eof() && p.peek() != '=' {
+			key = p.readKey()
+		}
+	}
+	if key == "" {
+		return Entry{}, false, &ParseError{Line: line, Msg: "expected a key"}
+	}
+
+	p.skipBlank()
+	if p.eof() || p.peek() != '=' {
+		return Entry{}, false, &ParseError{Line: line, Msg: fmt.Sprintf("expected '=' after %s", key)}
+	}
+	p.advance()
+	p.skipBlank()
+
+	value, err := p.readValue(line)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return Entry{Key: key, Value: value, Line: line}, true, nil
+}
+
+func (p *parser) readKey() string {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c == '=' || c == ' ' || c == '\t' || c == '\n' || c == '#' {
+			break
+		}
+		p.advance()
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) readValue(line int) (string, error) {
+	if p.eof() {
+		return "", nil
+	}
+
+	var value string
+	switch quote := p.peek(); quote {
+	case '\'':
+		p.advance()
+		start := p.pos
+		for !p.eof() && p.peek() != '\'' {
+			p.advance()
+		}
+		if p.eof() {
+			return "", &ParseError{Line: line, Msg: "unterminated single quoted value"}
+		}
+		value = p.src[start:p.pos]
+		p.advance()
+	case '"':
+		p.advance()
+		var b strings.Builder
+		for {
+			if p.eof() {
+				return "", &ParseError{Line: line, Msg: "unterminated double quoted value"}
+			}
+			c := p.advance()
+			if c == '"' {
+				break
+			}
+			if c != '\\' || p.eof() {
+				b.WriteByte(c)
+				continue
+			}
+			switch esc := p.advance(); esc {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$', '\'':
+				b.WriteByte(esc)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(esc)
+			}
+		}
+		value = b.String()
+	default:
+		start := p.pos
+		for !p.eof() && p.peek() != '\n' {
+			// an inline comment must be preceded by whitespace
+			if p.peek() == '#' && p.pos > start && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+				break
+			}
+			p.advance()
+		}
+		return strings.TrimSpace(p.src[start:p.pos]), p.endOfLine(line)
+	}
+
+	return value, p.endOfLine(line)
+}
+
+// endOfLine consumes trailing whitespace and comments after a value.
+func (p *parser) endOfLine(line int) error {
+	p.skipBlank()
+	if p.eof() {
+		return nil
+	}
+	switch p.peek() {
+	case '\n':
+		p.advance()
+		return nil
+	case '#':
+		p.skipLine()
+		return nil
+	}
+	return &ParseError{Line: line, Msg: "unexpected characters after quoted value"}
+}
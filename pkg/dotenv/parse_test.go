@@ -0,0 +1,133 @@
+package dotenv
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Entry
+	}{
+		{
+			name:    "empty",
+			content: "",
+			want:    nil,
+		},
+		{
+			name:    "plain values",
+			content: "A=1\nB=two words\n",
+			want:    []Entry{{Key: "A", Value: "1", Line: 1}, {Key: "B", Value: "two words", Line: 2}},
+		},
+		{
+			name:    "blank lines and comments",
+			content: "# header\n\n  A=1\n\t# indented comment\nB=2",
+			want:    []Entry{{Key: "A", Value: "1", Line: 3}, {Key: "B", Value: "2", Line: 5}},
+		},
+		{
+			name:    "export prefix",
+			content: "export A=1\nexport  B = 2\n",
+			want:    []Entry{{Key: "A", Value: "1", Line: 1}, {Key: "B", Value: "2", Line: 2}},
+		},
+		{
+			name:    "key named export",
+			content: "export=1",
+			want:    []Entry{{Key: "export", Value: "1", Line: 1}},
+		},
+		{
+			name:    "empty values",
+			content: "A=\nB=   \nC=\"\"\nD=''",
+			want: []Entry{
+				{Key: "A", Value: "", Line: 1},
+				{Key: "B", Value: "", Line: 2},
+				{Key: "C", Value: "", Line: 3},
+				{Key: "D", Value: "", Line: 4},
+			},
+		},
+		{
+			name:    "inline comments need whitespace",
+			content: "A=value # comment\nB=value#hash\nC=value\t# tab",
+			want: []Entry{
+				{Key: "A", Value: "value", Line: 1},
+				{Key: "B", Value: "value#hash", Line: 2},
+				{Key: "C", Value: "value", Line: 3},
+			},
+		},
+		{
+			name:    "single quotes are literal",
+			content: `A='a\nb $HOME # not a comment'`,
+			want:    []Entry{{Key: "A", Value: `a\nb $HOME # not a comment`, Line: 1}},
+		},
+		{
+			name:    "double quote escapes",
+			content: `A="tab\there\nnew \"quoted\" \\ \$HOME \' \q"`,
+			want:    []Entry{{Key: "A", Value: "tab\there\nnew \"quoted\" \\ $HOME ' \\q", Line: 1}},
+		},
+		{
+			name:    "comment after quoted value",
+			content: `A="x" # comment` + "\nB='y'\t",
+			want:    []Entry{{Key: "A", Value: "x", Line: 1}, {Key: "B", Value: "y", Line: 2}},
+		},
+		{
+			name:    "multi-line values",
+			content: "A=\"first\nsecond\"\nB='one\ntwo'\nC=3",
+			want: []Entry{
+				{Key: "A", Value: "first\nsecond", Line: 1},
+				{Key: "B", Value: "one\ntwo", Line: 3},
+				{Key: "C", Value: "3", Line: 5},
+			},
+		},
+		{
+			name:    "windows line endings",
+			content: "A=1\r\nB=\"x\r\ny\"\r\n",
+			want:    []Entry{{Key: "A", Value: "1", Line: 1}, {Key: "B", Value: "x\ny", Line: 2}},
+		},
+		{
+			name:    "last assignment wins in first position",
+			content: "A=1\nB=2\nA=3",
+			want:    []Entry{{Key: "A", Value: "3", Line: 1}, {Key: "B", Value: "2", Line: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.content)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		msg     string
+	}{
+		{name: "missing key", content: "=1", line: 1, msg: "expected a key"},
+		{name: "missing equals", content: "A=1\nB 2", line: 2, msg: "expected '=' after B"},
+		{name: "key only", content: "\n\nA", line: 3, msg: "expected '=' after A"},
+		{name: "unterminated double quote", content: "A=\"x\ny", line: 1, msg: "unterminated double quoted value"},
+		{name: "unterminated single quote", content: "A=1\nB='x", line: 2, msg: "unterminated single quoted value"},
+		{name: "text after quoted value", content: `A="x" y`, line: 1, msg: "unexpected characters after quoted value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.content)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse() error = %v, want a *ParseError", err)
+			}
+			if parseErr.Line != tt.line || parseErr.Msg != tt.msg {
+				t.Errorf("Parse() error = %v, want line %d: %s", err, tt.line, tt.msg)
+			}
+		})
+	}
+}
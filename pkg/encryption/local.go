@@ -0,0 +1,56 @@
+package encryption
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LocalKeyProvider wraps data keys with a master key held in process memory,
+// loaded from an environment variable or a file.
+type LocalKeyProvider struct {
+	id        string
+	masterKey []byte
+}
+
+// NewLocalKeyProvider creates a provider from a base64 encoded 32 byte key.
+func NewLocalKeyProvider(encodedKey string) (KeyProvider, error) {
+	masterKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: must be base64 encoded: %w", err)
+	}
+	if len(masterKey) != KeySize {
+		return nil, fmt.Errorf("invalid master key size: must be %d bytes", KeySize)
+	}
+
+	fingerprint := sha256.Sum256(masterKey)
+	return &LocalKeyProvider{
+		id:        "local:" + hex.EncodeToString(fingerprint[:4]),
+		masterKey: masterKey,
+	}, nil
+}
+
+// NewLocalKeyProviderFromFile reads a base64 encoded master key from path.
+func NewLocalKeyProviderFromFile(path string) (KeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+	return NewLocalKeyProvider(string(data))
+}
+
+func (p *LocalKeyProvider) ID() string {
+	return p.id
+}
+
+func (p *LocalKeyProvider) WrapKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	return Seal(p.masterKey, dataKey, nil)
+}
+
+func (p *LocalKeyProvider) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	return Open(p.masterKey, wrapped, nil)
+}
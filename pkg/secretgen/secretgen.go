@@ -0,0 +1,213 @@
+package secretgen
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+var ErrInvalidSpec = errors.New("invalid generator spec")
+
+// Type selects the kind of value a Spec generates.
+type Type string
+
+const (
+	TypeBytes    Type = "bytes"
+	TypePassword Type = "password"
+	TypeUUID     Type = "uuid"
+	TypeEd25519  Type = "ed25519"
+	TypeRSA      Type = "rsa"
+)
+
+// Character classes available to passwords.
+const (
+	ClassLower   = "lower"
+	ClassUpper   = "upper"
+	ClassDigits  = "digits"
+	ClassSymbols = "symbols"
+)
+
+var classChars = map[string]string{
+	ClassLower:   "abcdefghijklmnopqrstuvwxyz",
+	ClassUpper:   "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	ClassDigits:  "0123456789",
+	ClassSymbols: "!#%+-.:=?@^_~",
+}
+
+// Spec describes how to generate a value. It is stored with the variable so
+// that the value can be regenerated later.
+type Spec struct {
+	Type Type `json:"type"`
+	// Length is the number of random bytes for bytes and the number of
+	// characters for passwords.
+	Length int `json:"length,omitempty"`
+	// Encoding of random bytes: hex, base64 or base64url.
+	Encoding string `json:"encoding,omitempty"`
+	// Classes of characters a password contains at least once.
+	Classes []string `json:"classes,omitempty"`
+	// Bits is the RSA key size.
+	Bits int `json:"bits,omitempty"`
+}
+
+// Result is a generated value. For key pairs Value holds the private key and
+// PublicKey the matching public key, both PEM encoded.
+type Result struct {
+	Value     string
+	PublicKey string
+}
+
+// Normalize fills in defaults and checks the spec.
+func (s *Spec) Normalize() error {
+	switch s.Type {
+	case TypeBytes:
+		if s.Length == 0 {
+			s.Length = 32
+		}
+		if s.Length < 8 || s.Length > 1024 {
+			return fmt.Errorf("%w: length must be between 8 and 1024 bytes", ErrInvalidSpec)
+		}
+		if s.Encoding == "" {
+			s.Encoding = "hex"
+		}
+		if s.Encoding != "hex" && s.Encoding != "base64" && s.Encoding != "base64url" {
+			return fmt.Errorf("%w: encoding must be hex, base64 or base64url", ErrInvalidSpec)
+		}
+	case TypePassword:
+		if s.Length == 0 {
+			s.Length = 32
+		}
+		if len(s.Classes) == 0 {
+			s.Classes = []string{ClassLower, ClassUpper, ClassDigits, ClassSymbols}
+		}
+		for _, c := range s.Classes {
+			if _, ok := classChars[c]; !ok {
+				return fmt.Errorf("%w: unknown character class %q", ErrInvalidSpec, c)
+			}
+		}
+		slices.Sort(s.Classes)
+		s.Classes = slices.Compact(s.Classes)
+		if s.Length < 8 || s.Length > 256 || s.Length < len(s.Classes) {
+			return fmt.Errorf("%w: length must be between 8 and 256 characters", ErrInvalidSpec)
+		}
+	case TypeUUID, TypeEd25519:
+	case TypeRSA:
+		if s.Bits == 0 {
+			s.Bits = 3072
+		}
+		if s.Bits != 2048 && s.Bits != 3072 && s.Bits != 4096 {
+			return fmt.Errorf("%w: bits must be 2048, 3072 or 4096", ErrInvalidSpec)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidSpec, s.Type)
+	}
+	return nil
+}
+
+// Generate produces a new value from crypto/rand.
+func Generate(spec Spec) (Result, error) {
+	if err := spec.Normalize(); err != nil {
+		return Result{}, err
+	}
+
+	switch spec.Type {
+	case TypeBytes:
+		b := make([]byte, spec.Length)
+		if _, err := rand.Read(b); err != nil {
+			return Result{}, err
+		}
+		switch spec.Encoding {
+		case "base64":
+			return Result{Value: base64.StdEncoding.EncodeToString(b)}, nil
+		case "base64url":
+			return Result{Value: base64.RawURLEncoding.EncodeToString(b)}, nil
+		default:
+			return Result{Value: hex.EncodeToString(b)}, nil
+		}
+	case TypePassword:
+		value, err := password(spec.Length, spec.Classes)
+		return Result{Value: value}, err
+	case TypeUUID:
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return Result{}, err
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return Result{Value: fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])}, nil
+	case TypeEd25519:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return Result{}, err
+		}
+		return encodeKeyPair(priv, pub)
+	default:
+		priv, err := rsa.GenerateKey(rand.Reader, spec.Bits)
+		if err != nil {
+			return Result{}, err
+		}
+		return encodeKeyPair(priv, &priv.PublicKey)
+	}
+}
+
+func encodeKeyPair(priv, pub any) (Result, error) {
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return Result{}, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Value:     string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})),
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})),
+	}, nil
+}
+
+// password picks one character of every class and fills the rest from all
+// classes, then shuffles the result.
+func password(length int, classes []string) (string, error) {
+	var all string
+	out := make([]byte, 0, length)
+	for _, c := range classes {
+		chars := classChars[c]
+		all += chars
+		ch, err := pick(chars)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, ch)
+	}
+	for len(out) < length {
+		ch, err := pick(all)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, ch)
+	}
+
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		out[i], out[j.Int64()] = out[j.Int64()], out[i]
+	}
+	return string(out), nil
+}
+
+func pick(chars string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n.Int64()], nil
+}
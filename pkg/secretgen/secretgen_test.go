@@ -0,0 +1,222 @@
+package secretgen
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		spec Spec
+		want Spec
+	}{
+		{
+			name: "bytes defaults",
+			spec: Spec{Type: TypeBytes},
+			want: Spec{Type: TypeBytes, Length: 32, Encoding: "hex"},
+		},
+		{
+			name: "password defaults",
+			spec: Spec{Type: TypePassword},
+			want: Spec{Type: TypePassword, Length: 32, Classes: []string{ClassDigits, ClassLower, ClassSymbols, ClassUpper}},
+		},
+		{
+			name: "password classes are sorted and deduplicated",
+			spec: Spec{Type: TypePassword, Length: 8, Classes: []string{ClassUpper, ClassLower, ClassUpper}},
+			want: Spec{Type: TypePassword, Length: 8, Classes: []string{ClassLower, ClassUpper}},
+		},
+		{
+			name: "rsa defaults",
+			spec: Spec{Type: TypeRSA},
+			want: Spec{Type: TypeRSA, Bits: 3072},
+		},
+		{
+			name: "uuid",
+			spec: Spec{Type: TypeUUID},
+			want: Spec{Type: TypeUUID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := tt.spec
+			if err := spec.Normalize(); err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if spec.Type != tt.want.Type || spec.Length != tt.want.Length || spec.Encoding != tt.want.Encoding ||
+				spec.Bits != tt.want.Bits || !slices.Equal(spec.Classes, tt.want.Classes) {
+				t.Errorf("Normalize() = %+v, want %+v", spec, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec Spec
+	}{
+		{name: "unknown type", spec: Spec{Type: "otp"}},
+		{name: "missing type", spec: Spec{}},
+		{name: "bytes too short", spec: Spec{Type: TypeBytes, Length: 4}},
+		{name: "bytes too long", spec: Spec{Type: TypeBytes, Length: 2048}},
+		{name: "unknown encoding", spec: Spec{Type: TypeBytes, Encoding: "base32"}},
+		{name: "unknown class", spec: Spec{Type: TypePassword, Classes: []string{"emoji"}}},
+		{name: "password too short", spec: Spec{Type: TypePassword, Length: 7}},
+		{name: "password too long", spec: Spec{Type: TypePassword, Length: 257}},
+		{name: "unsupported rsa size", spec: Spec{Type: TypeRSA, Bits: 1024}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := tt.spec
+			if err := spec.Normalize(); !errors.Is(err, ErrInvalidSpec) {
+				t.Errorf("Normalize() error = %v, want %v", err, ErrInvalidSpec)
+			}
+			if _, err := Generate(tt.spec); !errors.Is(err, ErrInvalidSpec) {
+				t.Errorf("Generate() error = %v, want %v", err, ErrInvalidSpec)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  Spec
+		check func(t *testing.T, r Result)
+	}{
+		{
+			name: "hex bytes",
+			spec: Spec{Type: TypeBytes, Length: 16},
+			check: func(t *testing.T, r Result) {
+				b, err := hex.DecodeString(r.Value)
+				if err != nil || len(b) != 16 {
+					t.Errorf("value %q is not 16 hex encoded bytes", r.Value)
+				}
+			},
+		},
+		{
+			name: "base64 bytes",
+			spec: Spec{Type: TypeBytes, Length: 20, Encoding: "base64"},
+			check: func(t *testing.T, r Result) {
+				b, err := base64.StdEncoding.DecodeString(r.Value)
+				if err != nil || len(b) != 20 {
+					t.Errorf("value %q is not 20 base64 encoded bytes", r.Value)
+				}
+			},
+		},
+		{
+			name: "base64url bytes",
+			spec: Spec{Type: TypeBytes, Length: 20, Encoding: "base64url"},
+			check: func(t *testing.T, r Result) {
+				b, err := base64.RawURLEncoding.DecodeString(r.Value)
+				if err != nil || len(b) != 20 {
+					t.Errorf("value %q is not 20 unpadded base64url encoded bytes", r.Value)
+				}
+			},
+		},
+		{
+			name: "digits only password",
+			spec: Spec{Type: TypePassword, Length: 12, Classes: []string{ClassDigits}},
+			check: func(t *testing.T, r Result) {
+				if !regexp.MustCompile(`^[0-9]{12}$`).MatchString(r.Value) {
+					t.Errorf("value %q is not 12 digits", r.Value)
+				}
+			},
+		},
+		{
+			name: "password uses every class",
+			spec: Spec{Type: TypePassword, Length: 8},
+			check: func(t *testing.T, r Result) {
+				if len(r.Value) != 8 {
+					t.Errorf("value %q has length %d, want 8", r.Value, len(r.Value))
+				}
+				for class, chars := range classChars {
+					if !strings.ContainsAny(r.Value, chars) {
+						t.Errorf("value %q has no %s character", r.Value, class)
+					}
+				}
+			},
+		},
+		{
+			name: "uuid",
+			spec: Spec{Type: TypeUUID},
+			check: func(t *testing.T, r Result) {
+				if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(r.Value) {
+					t.Errorf("value %q is not a version 4 UUID", r.Value)
+				}
+			},
+		},
+		{
+			name: "ed25519 key pair",
+			spec: Spec{Type: TypeEd25519},
+			check: func(t *testing.T, r Result) {
+				priv, pub := parseKeyPair(t, r)
+				if !priv.(ed25519.PrivateKey).Public().(ed25519.PublicKey).Equal(pub) {
+					t.Error("public key does not match the private key")
+				}
+			},
+		},
+		{
+			name: "rsa key pair",
+			spec: Spec{Type: TypeRSA, Bits: 2048},
+			check: func(t *testing.T, r Result) {
+				priv, pub := parseKeyPair(t, r)
+				key := priv.(*rsa.PrivateKey)
+				if key.N.BitLen() != 2048 {
+					t.Errorf("key has %d bits, want 2048", key.N.BitLen())
+				}
+				if !key.PublicKey.Equal(pub) {
+					t.Error("public key does not match the private key")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Generate(tt.spec)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			tt.check(t, r)
+
+			again, err := Generate(tt.spec)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if again.Value == r.Value {
+				t.Errorf("Generate() returned %q twice", r.Value)
+			}
+		})
+	}
+}
+
+func parseKeyPair(t *testing.T, r Result) (priv, pub any) {
+	t.Helper()
+	privBlock, _ := pem.Decode([]byte(r.Value))
+	if privBlock == nil || privBlock.Type != "PRIVATE KEY" {
+		t.Fatalf("value is not a PEM encoded private key: %q", r.Value)
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(privBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse private key: %v", err)
+	}
+	pubBlock, _ := pem.Decode([]byte(r.PublicKey))
+	if pubBlock == nil || pubBlock.Type != "PUBLIC KEY" {
+		t.Fatalf("public key is not PEM encoded: %q", r.PublicKey)
+	}
+	pub, err = x509.ParsePKIXPublicKey(pubBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse public key: %v", err)
+	}
+	return priv, pub
+}
@@ -0,0 +1,214 @@
+package varschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the type a variable value must parse as.
+type Type string
+
+const (
+	TypeString   Type = "string"
+	TypeInt      Type = "int"
+	TypeBool     Type = "bool"
+	TypeURL      Type = "url"
+	TypeJSON     Type = "json"
+	TypeDuration Type = "duration"
+)
+
+// Rule constrains the value of a single key. Pattern is a regular expression
+// the whole value must match: it is anchored at both ends, so \d+ only
+// accepts values made of digits.
+type Rule struct {
+	Required bool     `json:"required,omitempty"`
+	Type     Type     `json:"type,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// KeyRules constrain the names of all keys.
+type KeyRules struct {
+	// ShellIdentifiers requires keys to be valid POSIX shell variable names.
+	ShellIdentifiers bool `json:"shell_identifiers,omitempty"`
+	// CaseInsensitiveUnique rejects keys that differ from another key only in
+	// case, such as Api_Key and API_KEY.
+	CaseInsensitiveUnique bool `json:"case_insensitive_unique,omitempty"`
+}
+
+// Schema describes the variables expected in every environment of a project.
+type Schema struct {
+	KeyRules  KeyRules        `json:"key_rules"`
+	Variables map[string]Rule `json:"variables"`
+}
+
+// Violation describes a key or value that does not satisfy the schema. It
+// never contains the value itself, which may be a secret.
+type Violation struct {
+	Key     string `json:"key"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Names of the rules reported in violations.
+const (
+	RuleRequired  = "required"
+	RuleType      = "type"
+	RulePattern   = "pattern"
+	RuleEnum      = "enum"
+	RuleShellName = "shell_identifier"
+	RuleCollision = "case_insensitive_unique"
+)
+
+var shellIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Parse decodes and checks a schema definition.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if s.Variables == nil {
+		s.Variables = map[string]Rule{}
+	}
+
+	for key, rule := range s.Variables {
+		switch rule.Type {
+		case "", TypeString, TypeInt, TypeBool, TypeURL, TypeJSON, TypeDuration:
+		default:
+			return nil, fmt.Errorf("invalid schema: %s: unknown type %q", key, rule.Type)
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(`^(?:` + rule.Pattern + `)$`)
+			if err != nil {
+				return nil, fmt.Errorf("invalid schema: %s: %w", key, err)
+			}
+			rule.pattern = re
+		}
+		for _, value := range rule.Enum {
+			if err := checkType(rule.Type, value); err != nil {
+				return nil, fmt.Errorf("invalid schema: %s: enum value %q is not a valid %s", key, value, rule.Type)
+			}
+		}
+		s.Variables[key] = rule
+	}
+	return &s, nil
+}
+
+// CheckKey validates a key name against the key rules.
+func (s *Schema) CheckKey(key string) []Violation {
+	if s.KeyRules.ShellIdentifiers && !shellIdentifier.MatchString(key) {
+		return []Violation{{Key: key, Rule: RuleShellName, Message: "key is not a valid shell identifier"}}
+	}
+	return nil
+}
+
+// CheckValue validates the value of key against its rule, if it has one.
+func (s *Schema) CheckValue(key, value string) []Violation {
+	rule, ok := s.Variables[key]
+	if !ok {
+		return nil
+	}
+
+	var out []Violation
+	if err := checkType(rule.Type, value); err != nil {
+		out = append(out, Violation{Key: key, Rule: RuleType, Message: err.Error()})
+	}
+	if rule.pattern != nil && !rule.pattern.MatchString(value) {
+		out = append(out, Violation{Key: key, Rule: RulePattern, Message: "value does not match " + rule.Pattern})
+	}
+	if len(rule.Enum) > 0 && !slices.Contains(rule.Enum, value) {
+		out = append(out, Violation{Key: key, Rule: RuleEnum, Message: "value must be one of " + strings.Join(rule.Enum, ", ")})
+	}
+	return out
+}
+
+// Collisions reports keys that differ from another key only in case. Only
+// keys listed in check are reported, so that existing collisions are not
+// blamed on an unrelated write. A nil check reports every collision.
+func (s *Schema) Collisions(keys []string, check []string) []Violation {
+	if !s.KeyRules.CaseInsensitiveUnique {
+		return nil
+	}
+
+	byFold := make(map[string][]string, len(keys))
+	for _, key := range keys {
+		fold := strings.ToLower(key)
+		if !slices.Contains(byFold[fold], key) {
+			byFold[fold] = append(byFold[fold], key)
+		}
+	}
+
+	var out []Violation
+	for _, key := range keys {
+		if check != nil && !slices.Contains(check, key) {
+			continue
+		}
+		for _, other := range byFold[strings.ToLower(key)] {
+			if other != key {
+				out = append(out, Violation{Key: key, Rule: RuleCollision, Message: "key collides with " + other})
+			}
+		}
+	}
+	return out
+}
+
+// Check validates a complete set of variables and returns the violations
+// sorted by key.
+func (s *Schema) Check(values map[string]string) []Violation {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out []Violation
+	for _, key := range keys {
+		out = append(out, s.CheckKey(key)...)
+		out = append(out, s.CheckValue(key, values[key])...)
+	}
+	out = append(out, s.Collisions(keys, nil)...)
+	for key, rule := range s.Variables {
+		if _, ok := values[key]; rule.Required && !ok {
+			out = append(out, Violation{Key: key, Rule: RuleRequired, Message: "required key is missing"})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func checkType(t Type, value string) error {
+	switch t {
+	case TypeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("value is not an integer")
+		}
+	case TypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("value is not a boolean")
+		}
+	case TypeURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "" && u.Path == "") {
+			return fmt.Errorf("value is not an absolute URL")
+		}
+	case TypeJSON:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("value is not valid JSON")
+		}
+	case TypeDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("value is not a duration such as 30s or 1h30m")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package varschema
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "malformed json", data: `{"variables":`, want: "invalid schema"},
+		{name: "unknown type", data: `{"variables":{"PORT":{"type":"float"}}}`, want: `PORT: unknown type "float"`},
+		{name: "bad pattern", data: `{"variables":{"PORT":{"pattern":"("}}}`, want: "PORT: error parsing regexp"},
+		{name: "enum of wrong type", data: `{"variables":{"PORT":{"type":"int","enum":["80","http"]}}}`, want: `PORT: enum value "http" is not a valid int`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckValue(t *testing.T) {
+	schema, err := Parse([]byte(`{"variables":{
+		"PORT":     {"type":"int"},
+		"DEBUG":    {"type":"bool"},
+		"API_URL":  {"type":"url"},
+		"CONFIG":   {"type":"json"},
+		"TIMEOUT":  {"type":"duration"},
+		"CODE":     {"pattern":"\\d+"},
+		"REGION":   {"pattern":"eu|us"},
+		"LEVEL":    {"enum":["debug","info"]},
+		"REPLICAS": {"type":"int","pattern":"[1-9]"}
+	}}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		key   string
+		value string
+		want  []string
+	}{
+		{key: "PORT", value: "8080"},
+		{key: "PORT", value: "80a", want: []string{RuleType}},
+		{key: "DEBUG", value: "true"},
+		{key: "DEBUG", value: "yes", want: []string{RuleType}},
+		{key: "API_URL", value: "https://api.example.com"},
+		{key: "API_URL", value: "api.example.com", want: []string{RuleType}},
+		{key: "CONFIG", value: `{"a":1}`},
+		{key: "CONFIG", value: `{a:1}`, want: []string{RuleType}},
+		{key: "TIMEOUT", value: "1h30m"},
+		{key: "TIMEOUT", value: "90", want: []string{RuleType}},
+		{key: "CODE", value: "123"},
+		{key: "CODE", value: "12a", want: []string{RulePattern}},
+		{key: "CODE", value: "a12", want: []string{RulePattern}},
+		{key: "REGION", value: "eu"},
+		{key: "REGION", value: "europe", want: []string{RulePattern}},
+		{key: "LEVEL", value: "info"},
+		{key: "LEVEL", value: "warn", want: []string{RuleEnum}},
+		{key: "REPLICAS", value: "3"},
+		{key: "REPLICAS", value: "x", want: []string{RuleType, RulePattern}},
+		{key: "UNKNOWN", value: "anything"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			var got []string
+			for _, v := range schema.CheckValue(tt.key, tt.value) {
+				if v.Key != tt.key {
+					t.Errorf("violation for key %q, want %q", v.Key, tt.key)
+				}
+				if strings.Contains(v.Message, tt.value) && tt.value != "" {
+					t.Errorf("violation message %q contains the value", v.Message)
+				}
+				got = append(got, v.Rule)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("CheckValue() rules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	schema, err := Parse([]byte(`{
+		"key_rules": {"shell_identifiers": true, "case_insensitive_unique": true},
+		"variables": {
+			"DATABASE_URL": {"required": true, "type": "url"},
+			"PORT":         {"required": true, "type": "int"}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   []Violation
+	}{
+		{
+			name:   "valid",
+			values: map[string]string{"DATABASE_URL": "postgres://db/app", "PORT": "5432"},
+		},
+		{
+			name:   "missing required key",
+			values: map[string]string{"PORT": "5432"},
+			want:   []Violation{{Key: "DATABASE_URL", Rule: RuleRequired}},
+		},
+		{
+			name:   "invalid key and value",
+			values: map[string]string{"DATABASE_URL": "postgres://db/app", "PORT": "x", "2FA": "on"},
+			want:   []Violation{{Key: "2FA", Rule: RuleShellName}, {Key: "PORT", Rule: RuleType}},
+		},
+		{
+			name:   "keys differing in case",
+			values: map[string]string{"DATABASE_URL": "postgres://db/app", "PORT": "1", "Api_Key": "a", "API_KEY": "b"},
+			want:   []Violation{{Key: "API_KEY", Rule: RuleCollision}, {Key: "Api_Key", Rule: RuleCollision}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schema.Check(tt.values)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Check() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Key != tt.want[i].Key || got[i].Rule != tt.want[i].Rule {
+					t.Errorf("Check()[%d] = %s %s, want %s %s", i, got[i].Key, got[i].Rule, tt.want[i].Key, tt.want[i].Rule)
+				}
+			}
+		})
+	}
+}
+
+func TestCollisions(t *testing.T) {
+	schema := &Schema{KeyRules: KeyRules{CaseInsensitiveUnique: true}}
+	keys := []string{"API_KEY", "Api_Key", "OTHER", "other", "PORT"}
+
+	tests := []struct {
+		name  string
+		check []string
+		want  []string
+	}{
+		{name: "all keys", check: nil, want: []string{"API_KEY", "Api_Key", "OTHER", "other"}},
+		{name: "only checked keys", check: []string{"Api_Key", "PORT"}, want: []string{"Api_Key"}},
+		{name: "no colliding key checked", check: []string{"PORT"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range schema.Collisions(keys, tt.check) {
+				got = append(got, v.Key)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Collisions() keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := (&Schema{}).Collisions(keys, nil); got != nil {
+		t.Errorf("Collisions() without the rule = %v, want none", got)
+	}
+}